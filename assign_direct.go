@@ -5,8 +5,10 @@
 package rdb
 
 import (
+	"bytes"
 	"io"
 	"math/big"
+	"strings"
 	"time"
 )
 
@@ -73,6 +75,8 @@ func assignViaDirect(prep interface{}, value interface{}) (handled bool, err err
 		return DirectAssignDuration(prep, v, false, nil)
 	case *big.Rat:
 		return DirectAssignRat(prep, v, false, nil)
+	case Decimal:
+		return DirectAssignDecimal(prep, v, false, nil)
 	default:
 		return false, nil
 	}
@@ -85,6 +89,7 @@ func setFlag(flag *bool, null bool) {
 }
 
 // DirectAssignInt writes v into prep without an intermediate interface box.
+// A ColumnScanner or registered type prep is passed the value.
 // Returns handled=false if prep is not a known integer destination (caller should fall back).
 func DirectAssignInt(prep interface{}, v int64, null bool, defaultNull interface{}) (handled bool, err error) {
 	prep, flag := unwrapFlag(prep)
@@ -137,7 +142,7 @@ func DirectAssignInt(prep interface{}, v int64, null bool, defaultNull interface
 		p.Null = false
 		p.Value = v
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: v}, prep)
 	}
 	return true, nil
 }
@@ -158,7 +163,7 @@ func DirectAssignBool(prep interface{}, v bool, null bool, defaultNull interface
 		p.Null = false
 		p.Value = v
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: v}, prep)
 	}
 	return true, nil
 }
@@ -183,15 +188,15 @@ func DirectAssignFloat(prep interface{}, v float64, null bool, defaultNull inter
 		p.Null = false
 		p.Value = v
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: v}, prep)
 	}
 	return true, nil
 }
 
 // DirectAssignBytes writes bb into prep. If mustCopy is true and prep retains
-// the bytes (*[]byte, *Nullable, *Opt[[]byte]), a copy is made. For *string /
-// *Opt[string], string(bb) copies. For io.Writer, bb is written as-is (caller
-// must not mutate until Write returns).
+// the bytes (*[]byte, *Nullable, *Opt[[]byte], *io.Reader), a copy is made.
+// For *string / *Opt[string], string(bb) copies. For io.Writer, bb is written
+// as-is (caller must not mutate until Write returns).
 func DirectAssignBytes(prep interface{}, bb []byte, null bool, mustCopy bool, defaultNull interface{}) (handled bool, err error) {
 	prep, flag := unwrapFlag(prep)
 	if null {
@@ -221,6 +226,13 @@ func DirectAssignBytes(prep interface{}, bb []byte, null bool, mustCopy bool, de
 		}
 	case io.Writer:
 		_, err = p.Write(bb)
+	case *io.Reader:
+		if mustCopy {
+			cp := make([]byte, len(bb))
+			copy(cp, bb)
+			bb = cp
+		}
+		*p = bytes.NewReader(bb)
 	case *Nullable:
 		p.Null = false
 		if mustCopy {
@@ -231,7 +243,7 @@ func DirectAssignBytes(prep interface{}, bb []byte, null bool, mustCopy bool, de
 			p.Value = bb
 		}
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: bb}, prep)
 	}
 	return true, err
 }
@@ -254,11 +266,13 @@ func DirectAssignString(prep interface{}, s string, null bool, defaultNull inter
 		p.Set([]byte(s))
 	case io.Writer:
 		_, err = p.Write([]byte(s))
+	case *io.Reader:
+		*p = strings.NewReader(s)
 	case *Nullable:
 		p.Null = false
 		p.Value = s
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: s}, prep)
 	}
 	return true, err
 }
@@ -279,7 +293,7 @@ func DirectAssignTime(prep interface{}, t time.Time, null bool, defaultNull inte
 		p.Null = false
 		p.Value = t
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: t}, prep)
 	}
 	return true, nil
 }
@@ -302,7 +316,41 @@ func DirectAssignRat(prep interface{}, r *big.Rat, null bool, defaultNull interf
 		p.Null = false
 		p.Value = r
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: r}, prep)
+	}
+	return true, nil
+}
+
+// DirectAssignDecimal writes d into prep. *big.Rat, *float64 and *string
+// destinations are converted from d.
+func DirectAssignDecimal(prep interface{}, d Decimal, null bool, defaultNull interface{}) (handled bool, err error) {
+	prep, flag := unwrapFlag(prep)
+	if null {
+		return true, assignNullWithFlag(prep, flag, defaultNull)
+	}
+	setFlag(flag, false)
+	switch p := prep.(type) {
+	case *Decimal:
+		*p = d
+	case *Opt[Decimal]:
+		p.Set(d)
+	case **big.Rat:
+		*p = d.Rat(nil)
+	case *big.Rat:
+		d.Rat(p)
+	case *Opt[*big.Rat]:
+		p.Set(d.Rat(nil))
+	case *float64:
+		*p = d.Float64()
+	case *float32:
+		*p = float32(d.Float64())
+	case *string:
+		*p = d.String()
+	case *Nullable:
+		p.Null = false
+		p.Value = d
+	default:
+		return assignCustom(nil, Nullable{Value: d}, prep)
 	}
 	return true, nil
 }
@@ -323,7 +371,7 @@ func DirectAssignDuration(prep interface{}, d time.Duration, null bool, defaultN
 		p.Null = false
 		p.Value = d
 	default:
-		return false, nil
+		return assignCustom(nil, Nullable{Value: d}, prep)
 	}
 	return true, nil
 }
@@ -362,6 +410,9 @@ func assignNullPrep(prep interface{}) error {
 	case *Nullable:
 		*p = Nullable{Null: true}
 		return nil
+	case *io.Reader:
+		*p = nil
+		return nil
 	case *Opt[int8]:
 		p.SetNull()
 		return nil
@@ -416,6 +467,12 @@ func assignNullPrep(prep interface{}) error {
 	case *Opt[*big.Rat]:
 		p.SetNull()
 		return nil
+	case *Opt[Decimal]:
+		p.SetNull()
+		return nil
+	}
+	if handled, err := assignCustom(nil, Nullable{Null: true}, prep); handled {
+		return err
 	}
 	return ErrScanNull
 }
@@ -459,6 +516,8 @@ func zeroPayload(prep interface{}) error {
 		*p = time.Time{}
 	case *time.Duration:
 		*p = 0
+	case *Decimal:
+		*p = Decimal{}
 	default:
 		// Leave as-is for unknown payload types; flag already marks null.
 	}
@@ -182,3 +182,20 @@ func TestAssignValueOptAndNullFlag(t *testing.T) {
 		t.Fatalf("null into *int32: err=%v", err)
 	}
 }
+
+// intScanner assigns its value to an int32, as a ColumnScanner.
+type intScanner struct {
+	v int32
+}
+
+func (s *intScanner) ScanColumn(c *Column, value Nullable) error {
+	return AssignValue(c, value, &s.v, nil)
+}
+
+func TestDirectAssignScannerMismatch(t *testing.T) {
+	var s intScanner
+	handled, err := DirectAssignString(&s, "abc", false, nil)
+	if !handled || err == nil {
+		t.Fatalf("got handled %t, err %v; want a type error", handled, err)
+	}
+}
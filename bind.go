@@ -0,0 +1,339 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// sqlTypeNames maps SQL type names used in the "sqltype" tag to a Type.
+var sqlTypeNames = map[string]Type{
+	"nvarchar":         TypeVarChar,
+	"varchar":          TypeAnsiVarChar,
+	"nchar":            TypeChar,
+	"char":             TypeAnsiChar,
+	"ntext":            TypeText,
+	"text":             TypeAnsiText,
+	"varbinary":        TypeBinary,
+	"binary":           TypeBinary,
+	"bit":              TypeBool,
+	"bool":             TypeBool,
+	"boolean":          TypeBool,
+	"tinyint":          TypeInt8,
+	"smallint":         TypeInt16,
+	"int":              TypeInt32,
+	"integer":          TypeInt32,
+	"bigint":           TypeInt64,
+	"real":             TypeFloat32,
+	"float":            TypeFloat64,
+	"decimal":          TypeDecimal,
+	"numeric":          TypeDecimal,
+	"money":            TypeMoney,
+	"date":             TypeDate,
+	"time":             TypeTime,
+	"datetime2":        TypeTimestamp,
+	"timestamp":        TypeTimestamp,
+	"datetimeoffset":   TypeTimestampz,
+	"timestamptz":      TypeTimestampz,
+	"uniqueidentifier": TypeUUID,
+	"uuid":             TypeUUID,
+	"xml":              TypeXML,
+	"json":             TypeJSON,
+}
+
+// parseSQLType parses a type such as "varchar(50)", "nvarchar(max)",
+// or "decimal(18,4)" into the Type, Length, Precision and Scale of p.
+// A max length is a Length of zero.
+func parseSQLType(s string, p *Param) error {
+	s = strings.ToLower(strings.TrimSpace(s))
+	name, args, hasArgs := strings.Cut(s, "(")
+	name = strings.TrimSpace(name)
+	typ, ok := sqlTypeNames[name]
+	if !ok {
+		return fmt.Errorf("unknown SQL type %q", name)
+	}
+	p.Type = typ
+	if !hasArgs {
+		return nil
+	}
+	args, ok = strings.CutSuffix(strings.TrimSpace(args), ")")
+	if !ok {
+		return fmt.Errorf("SQL type %q: missing )", s)
+	}
+	list := strings.Split(args, ",")
+	nums := make([]int, len(list))
+	for i, a := range list {
+		a = strings.TrimSpace(a)
+		if a == "max" && len(list) == 1 {
+			continue
+		}
+		n, err := strconv.Atoi(a)
+		if err != nil || n < 0 {
+			return fmt.Errorf("SQL type %q: invalid size %q", s, a)
+		}
+		nums[i] = n
+	}
+	switch {
+	case typ == TypeDecimal:
+		if len(nums) > 2 {
+			return fmt.Errorf("SQL type %q: too many arguments", s)
+		}
+		p.Precision = nums[0]
+		if len(nums) == 2 {
+			p.Scale = nums[1]
+		}
+	case len(nums) != 1:
+		return fmt.Errorf("SQL type %q: too many arguments", s)
+	case typ == TypeTime || typ == TypeTimestamp || typ == TypeTimestampz:
+		p.Scale = nums[0]
+	default:
+		p.Length = nums[0]
+	}
+	return nil
+}
+
+// paramField reads one struct field into a Param.
+type paramField struct {
+	param   Param // Name and any type from the sqltype tag.
+	get     func(base unsafe.Pointer) interface{}
+	json    bool
+	hasNull bool
+	nullOff uintptr      // Offset of the null:"…" flag if hasNull.
+	typ     reflect.Type // Field type, to infer the type of a null value.
+}
+
+type paramPlan struct {
+	fields []paramField
+}
+
+var paramPlans sync.Map // map[reflect.Type]*paramPlan
+
+// ParamsFromStruct returns a parameter for each exported field of the struct
+// v, or a pointer to one. Fields use the same "db" tags as table.Query:
+// the tag sets the parameter name, "-" skips the field, and the "json"
+// option sends the field as JSON. A bool field with a null:"name" tag sends
+// the named parameter as NULL when true, typed from the named field. An optional "sqltype" tag sets the
+// type, length, precision and scale:
+//
+//	type Account struct {
+//	    ID      int64           `db:"id"`
+//	    Name    string          `db:"name" sqltype:"varchar(50)"`
+//	    Balance *big.Rat        `db:"balance" sqltype:"decimal(18,4)"`
+//	    Note    rdb.Opt[string] `db:"note"`
+//	}
+//
+// The fields of each struct type are planned once. Passing a pointer avoids
+// copying the struct.
+func ParamsFromStruct(v any) ([]Param, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ParamsFromStruct: %T is not a struct or pointer to a struct", v)
+	}
+	if !rv.CanAddr() {
+		cp := reflect.New(rv.Type())
+		cp.Elem().Set(rv)
+		rv = cp.Elem()
+	}
+	plan, err := getParamPlan(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	base := rv.Addr().UnsafePointer()
+
+	params := make([]Param, len(plan.fields))
+	for i := range plan.fields {
+		f := &plan.fields[i]
+		p := &params[i]
+		*p = f.param
+		if f.hasNull && *(*bool)(unsafe.Add(base, f.nullOff)) {
+			if p.Type == TypeUnknown {
+				if err := inferNull(p, f.typ); err != nil {
+					return nil, fmt.Errorf("ParamsFromStruct: %w", err)
+				}
+			}
+			p.Null = true
+			continue
+		}
+		p.Value = f.get(base)
+		if f.json {
+			bb, err := json.Marshal(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf("ParamsFromStruct: param %q: %w", p.Name, err)
+			}
+			p.Value = json.RawMessage(bb)
+		}
+	}
+	return params, nil
+}
+
+// ParamsFromMap returns a parameter for each map entry, sorted by name.
+func ParamsFromMap(m map[string]any) []Param {
+	params := make([]Param, 0, len(m))
+	for name, value := range m {
+		params = append(params, Param{Name: name, Value: value})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+	return params
+}
+
+func getParamPlan(t reflect.Type) (*paramPlan, error) {
+	if plan, ok := paramPlans.Load(t); ok {
+		return plan.(*paramPlan), nil
+	}
+	plan, err := newParamPlan(t)
+	if err != nil {
+		return nil, err
+	}
+	paramPlans.Store(t, plan)
+	return plan, nil
+}
+
+func newParamPlan(t reflect.Type) (*paramPlan, error) {
+	type field struct {
+		index int
+		name  string
+		json  bool
+	}
+	var fields []field
+	nullFlags := make(map[string]int)
+	// Field and parameter names to the struct field index, for null:"…" targets.
+	fieldByName := make(map[string]int)
+	fieldByParam := make(map[string]int)
+	// Struct field index to the plan field index.
+	planIndex := make(map[int]int)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fieldByName[f.Name] = i
+		if target := f.Tag.Get("null"); len(target) > 0 {
+			if f.Type.Kind() != reflect.Bool {
+				return nil, fmt.Errorf("ParamsFromStruct: field %s has null:%q but is not bool", f.Name, target)
+			}
+			if _, dup := nullFlags[target]; dup {
+				return nil, fmt.Errorf("ParamsFromStruct: duplicate null:%q", target)
+			}
+			nullFlags[target] = i
+			continue
+		}
+		name := f.Name
+		isJSON := false
+		if tag := f.Tag.Get("db"); len(tag) > 0 {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if len(parts[0]) > 0 {
+				name = parts[0]
+			}
+			for _, part := range parts[1:] {
+				if part == "json" {
+					isJSON = true
+				}
+			}
+		}
+		fieldByParam[name] = i
+		planIndex[i] = len(fields)
+		fields = append(fields, field{index: i, name: name, json: isJSON})
+	}
+
+	plan := &paramPlan{fields: make([]paramField, len(fields))}
+	for i, fd := range fields {
+		f := t.Field(fd.index)
+		pf := &plan.fields[i]
+		pf.param.Name = fd.name
+		pf.json = fd.json
+		pf.get = fieldGetter(f.Type, f.Offset)
+		pf.typ = f.Type
+		if fd.json {
+			pf.typ = typeRawMessage
+		}
+		if st := f.Tag.Get("sqltype"); len(st) > 0 {
+			if err := parseSQLType(st, &pf.param); err != nil {
+				return nil, fmt.Errorf("ParamsFromStruct: field %s: %w", f.Name, err)
+			}
+		}
+	}
+	// Resolve null flag targets the same way table.Query does.
+	for target, flagIndex := range nullFlags {
+		targetIndex, ok := fieldByParam[target]
+		if !ok {
+			targetIndex, ok = fieldByName[target]
+		}
+		if !ok {
+			return nil, fmt.Errorf("ParamsFromStruct: null:%q does not match any field", target)
+		}
+		if targetIndex == flagIndex {
+			return nil, fmt.Errorf("ParamsFromStruct: null:%q cannot refer to itself", target)
+		}
+		tf := t.Field(targetIndex)
+		if tf.Type.Kind() == reflect.Pointer && isOpt(tf.Type.Elem()) {
+			return nil, fmt.Errorf("ParamsFromStruct: field %s is *Opt[T]; use Opt[T] by value (nil *Opt is not supported)", tf.Name)
+		}
+		if isOpt(tf.Type) {
+			return nil, fmt.Errorf("ParamsFromStruct: null:%q target field %s is Opt[T]; use one null mechanism", target, tf.Name)
+		}
+		if tf.Tag.Get("null") != "" {
+			return nil, fmt.Errorf("ParamsFromStruct: null:%q target %s is itself a null flag", target, tf.Name)
+		}
+		i, ok := planIndex[targetIndex]
+		if !ok {
+			return nil, fmt.Errorf("ParamsFromStruct: null:%q does not match any field", target)
+		}
+		plan.fields[i].hasNull = true
+		plan.fields[i].nullOff = t.Field(flagIndex).Offset
+	}
+	return plan, nil
+}
+
+var (
+	typeString  = reflect.TypeFor[string]()
+	typeBytes   = reflect.TypeFor[[]byte]()
+	typeBool    = reflect.TypeFor[bool]()
+	typeInt     = reflect.TypeFor[int]()
+	typeInt32   = reflect.TypeFor[int32]()
+	typeInt64   = reflect.TypeFor[int64]()
+	typeFloat64 = reflect.TypeFor[float64]()
+)
+
+// fieldGetter returns a func that reads the field of type ft at off.
+// Common types are read without reflection.
+func fieldGetter(ft reflect.Type, off uintptr) func(unsafe.Pointer) interface{} {
+	switch ft {
+	case typeString:
+		return func(base unsafe.Pointer) interface{} { return *(*string)(unsafe.Add(base, off)) }
+	case typeBytes:
+		return func(base unsafe.Pointer) interface{} { return *(*[]byte)(unsafe.Add(base, off)) }
+	case typeBool:
+		return func(base unsafe.Pointer) interface{} { return *(*bool)(unsafe.Add(base, off)) }
+	case typeInt:
+		return func(base unsafe.Pointer) interface{} { return *(*int)(unsafe.Add(base, off)) }
+	case typeInt32:
+		return func(base unsafe.Pointer) interface{} { return *(*int32)(unsafe.Add(base, off)) }
+	case typeInt64:
+		return func(base unsafe.Pointer) interface{} { return *(*int64)(unsafe.Add(base, off)) }
+	case typeFloat64:
+		return func(base unsafe.Pointer) interface{} { return *(*float64)(unsafe.Add(base, off)) }
+	case typeTime:
+		return func(base unsafe.Pointer) interface{} { return *(*time.Time)(unsafe.Add(base, off)) }
+	}
+	return func(base unsafe.Pointer) interface{} {
+		return reflect.NewAt(ft, unsafe.Add(base, off)).Elem().Interface()
+	}
+}
@@ -0,0 +1,166 @@
+package rdb
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSQLType(t *testing.T) {
+	list := []struct {
+		SQL  string
+		Want Param
+	}{
+		{"int", Param{Type: TypeInt32}},
+		{"varchar(50)", Param{Type: TypeAnsiVarChar, Length: 50}},
+		{"NVARCHAR(MAX)", Param{Type: TypeVarChar}},
+		{"decimal(18, 4)", Param{Type: TypeDecimal, Precision: 18, Scale: 4}},
+		{"numeric(10)", Param{Type: TypeDecimal, Precision: 10}},
+		{"datetime2(3)", Param{Type: TypeTimestamp, Scale: 3}},
+		{"uniqueidentifier", Param{Type: TypeUUID}},
+	}
+	for _, item := range list {
+		var p Param
+		if err := parseSQLType(item.SQL, &p); err != nil {
+			t.Errorf("%s: %v", item.SQL, err)
+			continue
+		}
+		if p != item.Want {
+			t.Errorf("%s: got %+v, want %+v", item.SQL, p, item.Want)
+		}
+	}
+	for _, bad := range []string{"blob", "varchar(50", "varchar(a)", "varchar(1,2)", "decimal(1,2,3)", "int(max, 2)"} {
+		var p Param
+		if err := parseSQLType(bad, &p); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestParamsFromStruct(t *testing.T) {
+	type Row struct {
+		ID       int64       `db:"id"`
+		Name     string      `db:"name" sqltype:"varchar(50)"`
+		Balance  *big.Rat    `db:"balance" sqltype:"decimal(18,4)"`
+		Note     Opt[string] `db:"note"`
+		Region   string      `db:"region"`
+		RegNull  bool        `null:"region"`
+		Tags     []string    `db:"tags,json"`
+		Created  time.Time   `db:"created"`
+		Updated  time.Time   `db:"updated"`
+		UpdNull  bool        `null:"updated"`
+		Skip     string      `db:"-"`
+		Untagged int32
+		private  int
+	}
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	row := Row{
+		ID:       7,
+		Name:     "alice",
+		Balance:  big.NewRat(5, 2),
+		Note:     Opt[string]{V: "n", Valid: true},
+		Region:   "ignored",
+		RegNull:  true,
+		Tags:     []string{"a", "b"},
+		Created:  created,
+		Updated:  created,
+		UpdNull:  true,
+		Skip:     "skip",
+		Untagged: 3,
+		private:  1,
+	}
+
+	want := []Param{
+		{Name: "id", Value: int64(7)},
+		{Name: "name", Type: TypeAnsiVarChar, Length: 50, Value: "alice"},
+		{Name: "balance", Type: TypeDecimal, Precision: 18, Scale: 4, Value: big.NewRat(5, 2)},
+		{Name: "note", Value: Opt[string]{V: "n", Valid: true}},
+		{Name: "region", Type: TypeVarChar, Length: InferTextLength, Null: true},
+		{Name: "tags", Value: json.RawMessage(`["a","b"]`)},
+		{Name: "created", Value: created},
+		{Name: "updated", Type: TypeTimestampz, Null: true},
+		{Name: "Untagged", Value: int32(3)},
+	}
+	for _, v := range []any{row, &row} {
+		params, err := ParamsFromStruct(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("%T:\ngot  %+v\nwant %+v", v, params, want)
+		}
+	}
+
+	// The plan is cached.
+	if _, ok := paramPlans.Load(reflect.TypeFor[Row]()); !ok {
+		t.Error("plan not cached")
+	}
+
+	if _, err := ParamsFromStruct(3); err == nil {
+		t.Error("expected error for non-struct")
+	}
+	type Bad struct {
+		Name string `sqltype:"blob"`
+	}
+	if _, err := ParamsFromStruct(Bad{}); err == nil {
+		t.Error("expected error for bad sqltype")
+	}
+}
+
+func TestParamsFromStructNullErrors(t *testing.T) {
+	type OptNull struct {
+		Note     Opt[string] `db:"note"`
+		NoteNull bool        `null:"note"`
+	}
+	type Self struct {
+		Flag bool `null:"Flag"`
+	}
+	type Nested struct {
+		Name     string `db:"name"`
+		NameNull bool   `null:"name"`
+		NullNull bool   `null:"NameNull"`
+	}
+	type Missing struct {
+		Name     string `db:"name"`
+		NameNull bool   `null:"other"`
+	}
+	for _, item := range []struct {
+		v    any
+		want string
+	}{
+		{OptNull{}, `ParamsFromStruct: null:"note" target field Note is Opt[T]; use one null mechanism`},
+		{Self{}, `ParamsFromStruct: null:"Flag" cannot refer to itself`},
+		{Nested{}, `ParamsFromStruct: null:"NameNull" target NameNull is itself a null flag`},
+		{Missing{}, `ParamsFromStruct: null:"other" does not match any field`},
+	} {
+		_, err := ParamsFromStruct(item.v)
+		if err == nil || err.Error() != item.want {
+			t.Errorf("%T: got %v, want %s", item.v, err, item.want)
+		}
+	}
+}
+
+func TestParamsFromMap(t *testing.T) {
+	params := ParamsFromMap(map[string]any{"b": 2, "a": "x"})
+	want := []Param{{Name: "a", Value: "x"}, {Name: "b", Value: 2}}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("got %+v, want %+v", params, want)
+	}
+}
+
+func BenchmarkParamsFromStruct(b *testing.B) {
+	type Row struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name" sqltype:"varchar(50)"`
+		OK   bool   `db:"ok"`
+	}
+	row := &Row{ID: 1, Name: "a", OK: true}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParamsFromStruct(row); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned instead of opening a new connection after
+	// Config.CircuitThreshold consecutive connection failures.
+	ErrCircuitOpen = errors.New("circuit open: database connections are failing")
+
+	// ErrPoolBusy is returned when more then Config.PoolMaxWaiters requests
+	// are already waiting for a connection.
+	ErrPoolBusy = errors.New("connection pool busy: too many waiting requests")
+)
+
+// breaker stops opening connections after repeated failures.
+// After the cool-down a single probe connection is attempted; if it
+// succeeds the breaker closes, otherwise it opens again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(conf *Config) *breaker {
+	if conf.CircuitThreshold <= 0 {
+		return nil
+	}
+	b := &breaker{
+		threshold: conf.CircuitThreshold,
+		cooldown:  conf.CircuitCooldown,
+	}
+	if b.cooldown <= 0 {
+		b.cooldown = 5 * time.Second
+	}
+	return b
+}
+
+// allow returns ErrCircuitOpen if a connection should not be attempted.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// done records the result of a connection attempt made with ctx.
+// A dial timeout matches context.DeadlineExceeded, so only the caller ctx
+// decides if the attempt was abandoned rather than failed.
+func (b *breaker) done(ctx context.Context, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	switch {
+	case err == nil:
+		b.failures = 0
+	case ctx.Err() != nil:
+		// The caller gave up, the server did not fail.
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openUntil = time.Now().Add(b.cooldown)
+		}
+	}
+}
+
+// isOpen returns true if connections are currently refused.
+func (b *breaker) isOpen() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold && time.Now().Before(b.openUntil)
+}
+
+// wait registers a request that may wait for a connection.
+// The returned func must be called when the wait ends.
+func (cp *ConnPool) wait() (func(), error) {
+	max := int64(cp.conf.PoolMaxWaiters)
+	if max <= 0 || cp.pool.Available() > 0 {
+		return func() {}, nil
+	}
+	if cp.waiters.Add(1) > max {
+		cp.waiters.Add(-1)
+		cp.stats.busy.Add(1)
+		return nil, ErrPoolBusy
+	}
+	return func() { cp.waiters.Add(-1) }, nil
+}
@@ -0,0 +1,170 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	d := &hostDriver{down: map[string]error{}}
+	Register("circuit", d)
+	d.set("db", errors.New("connection refused"))
+
+	pool, err := Open(&Config{
+		DriverName:       "circuit",
+		Hostname:         "db",
+		CircuitThreshold: 2,
+		CircuitCooldown:  30 * time.Millisecond,
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+	cmd := &Command{Arity: Zero}
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Query(ctx, cmd); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("query %d: got %v, want the connection error", i, err)
+		}
+	}
+	if _, err := pool.Query(ctx, cmd); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if got := len(d.takeOpened()); got != 2 {
+		t.Fatalf("opened %d connections, want 2", got)
+	}
+	st := pool.Stats()
+	if !st.CircuitOpen || st.CircuitRejected != 1 {
+		t.Fatalf("circuit open %t, rejected %d", st.CircuitOpen, st.CircuitRejected)
+	}
+
+	// A failed probe opens the circuit again.
+	time.Sleep(40 * time.Millisecond)
+	if _, err := pool.Query(ctx, cmd); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe: got %v, want the connection error", err)
+	}
+	if _, err := pool.Query(ctx, cmd); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after probe: got %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful probe closes the circuit.
+	d.set("db", nil)
+	time.Sleep(40 * time.Millisecond)
+	if _, err := pool.Query(ctx, cmd); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if pool.Stats().CircuitOpen {
+		t.Fatal("circuit still open after a successful probe")
+	}
+}
+
+// dialDriver dials the configured host and closes the network
+// connection again on success.
+type dialDriver struct {
+	dummyDriver
+}
+
+func (d *dialDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	dialer := net.Dialer{Timeout: c.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(c.Hostname, strconv.Itoa(c.Port)))
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return &dummyConn{opened: time.Now(), status: StatusReady}, nil
+}
+
+func TestCircuitBreakerDialTimeout(t *testing.T) {
+	addr, _ := blackholeAddr(t)
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, _ := strconv.Atoi(port)
+	Register("circuitdialtimeout", &dialDriver{})
+	pool, err := Open(&Config{
+		DriverName:       "circuitdialtimeout",
+		Hostname:         host,
+		Port:             portNum,
+		DialTimeout:      20 * time.Millisecond,
+		CircuitThreshold: 2,
+		CircuitCooldown:  time.Minute,
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+	cmd := &Command{Arity: Zero}
+
+	// Timing out dials count as failures and open the circuit.
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Query(ctx, cmd); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("query %d: got %v, want the dial timeout", i, err)
+		}
+	}
+	if _, err := pool.Query(ctx, cmd); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestPoolMaxWaiters(t *testing.T) {
+	d := &signalDriver{}
+	Register("max_waiters", d)
+	pool, err := Open(&Config{
+		DriverName:       "max_waiters",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		PoolMaxWaiters:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Query(ctx, &Command{Arity: Zero})
+		firstDone <- err
+	}()
+	held := waitQuery(t, d, 1)[0]
+
+	secondDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Query(ctx, &Command{Arity: Zero})
+		secondDone <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Stats().Waiting != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("second query is not waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := pool.Query(ctx, &Command{Arity: Zero}); !errors.Is(err, ErrPoolBusy) {
+		t.Fatalf("got %v, want ErrPoolBusy", err)
+	}
+	if got := pool.Stats().Busy; got != 1 {
+		t.Fatalf("busy %d, want 1", got)
+	}
+
+	held.signal()
+	if err := <-firstDone; err != nil {
+		t.Fatal(err)
+	}
+	waitQuery(t, d, 1)[0].signal()
+	if err := <-secondDone; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Balance selects how a Cluster spreads reads over the replicas.
+type Balance byte
+
+const (
+	RoundRobin Balance = iota // Use each replica in turn.
+	LeastInUse                // Use the replica with the fewest checked out connections.
+)
+
+type readOnlyKey struct{}
+
+// WithReadOnly returns a context that marks queries and transactions run
+// through a Cluster as read-only, allowing them to be sent to a replica.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly returns true if ctx was returned from WithReadOnly.
+func IsReadOnly(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	ro, _ := ctx.Value(readOnlyKey{}).(bool)
+	return ro
+}
+
+type replica struct {
+	pool      *ConnPool
+	downUntil atomic.Int64 // Unix nano time the replica may be used again.
+}
+
+// Cluster routes commands over a primary and zero or more readable replicas.
+// Commands with Command.ReadOnly set, or run with a WithReadOnly context, are
+// sent to a replica. All other commands and transactions are sent to
+// the primary. When no replica is healthy reads are sent to the primary.
+type Cluster struct {
+	Primary *ConnPool
+
+	// Balance sets how reads are spread over the replicas.
+	Balance Balance
+
+	// DownTime is how long a replica is skipped after it fails to run
+	// a command. Defaults to 5 seconds.
+	DownTime time.Duration
+
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+var _ Queryer = &Cluster{}
+
+// NewCluster returns a Cluster that writes to primary and reads from replicas.
+func NewCluster(primary *ConnPool, replicas ...*ConnPool) *Cluster {
+	c := &Cluster{
+		Primary:  primary,
+		replicas: make([]*replica, len(replicas)),
+	}
+	for i, cp := range replicas {
+		c.replicas[i] = &replica{pool: cp}
+	}
+	return c
+}
+
+// Replicas returns the replica pools.
+func (c *Cluster) Replicas() []*ConnPool {
+	list := make([]*ConnPool, len(c.replicas))
+	for i, r := range c.replicas {
+		list[i] = r.pool
+	}
+	return list
+}
+
+// Close closes the primary and replica pools.
+func (c *Cluster) Close() {
+	c.Primary.Close()
+	for _, r := range c.replicas {
+		r.pool.Close()
+	}
+}
+
+func (c *Cluster) healthy(r *replica, now int64) bool {
+	return !r.pool.closing.Load() && now >= r.downUntil.Load()
+}
+
+// replica returns a healthy replica, or nil if none are healthy.
+func (c *Cluster) replica() *replica {
+	n := len(c.replicas)
+	if n == 0 {
+		return nil
+	}
+	now := time.Now().UnixNano()
+	switch c.Balance {
+	default:
+		start := int(c.next.Add(1) % uint64(n))
+		for i := 0; i < n; i++ {
+			r := c.replicas[(start+i)%n]
+			if c.healthy(r, now) {
+				return r
+			}
+		}
+		return nil
+	case LeastInUse:
+		var best *replica
+		bestInUse := int64(0)
+		for _, r := range c.replicas {
+			if !c.healthy(r, now) {
+				continue
+			}
+			inUse := r.pool.pool.InUse()
+			if best == nil || inUse < bestInUse {
+				best, bestInUse = r, inUse
+			}
+		}
+		return best
+	}
+}
+
+// failed marks the replica as down if err means the replica cannot be
+// reached or cannot take connections. Errors in the request, such as a bad
+// parameter or a full pool, leave the replica in use.
+// Returns true if the command should be sent to the primary instead.
+func (c *Cluster) failed(ctx context.Context, r *replica, err error) bool {
+	if err == nil || ctx.Err() != nil || !unavailable(err) {
+		return false
+	}
+	down := c.DownTime
+	if down <= 0 {
+		down = 5 * time.Second
+	}
+	r.downUntil.Store(time.Now().Add(down).UnixNano())
+	return true
+}
+
+// unavailable returns true if err is a failure to connect to the server,
+// a network error, or a server message that the database is not available.
+func unavailable(err error) bool {
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, ErrLoginFailed) {
+		return true
+	}
+	var ce *connectError
+	if errors.As(err, &ce) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+func readOnly(ctx context.Context, cmd *Command) bool {
+	return (cmd != nil && cmd.ReadOnly) || IsReadOnly(ctx)
+}
+
+// Query runs cmd on a replica if it is read-only, otherwise on the primary.
+func (c *Cluster) Query(ctx context.Context, cmd *Command, params ...Param) (*Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if readOnly(ctx, cmd) {
+		if r := c.replica(); r != nil {
+			res, err := r.pool.Query(ctx, cmd, params...)
+			if !c.failed(ctx, r, err) {
+				return res, err
+			}
+		}
+	}
+	return c.Primary.Query(ctx, cmd, params...)
+}
+
+// Begin starts a Transaction with the default isolation level.
+func (c *Cluster) Begin(ctx context.Context) (*Transaction, error) {
+	return c.BeginLevel(ctx, LevelDefault)
+}
+
+// BeginLevel starts a Transaction on the primary. If ctx is marked with
+// WithReadOnly the transaction is started on a replica.
+func (c *Cluster) BeginLevel(ctx context.Context, level IsolationLevel) (*Transaction, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if IsReadOnly(ctx) {
+		if r := c.replica(); r != nil {
+			tran, err := r.pool.BeginLevel(ctx, level)
+			if !c.failed(ctx, r, err) {
+				return tran, err
+			}
+		}
+	}
+	return c.Primary.BeginLevel(ctx, level)
+}
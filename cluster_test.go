@@ -0,0 +1,222 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clusterDriver counts queries and fails to open connections when down.
+type clusterDriver struct {
+	dummyDriver
+	queries atomic.Int64
+	down    atomic.Bool
+	fail    atomic.Pointer[Errors] // Returned from each query if set.
+}
+
+func (d *clusterDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	if d.down.Load() {
+		return nil, errors.New("server down")
+	}
+	return &clusterConn{dummyConn: dummyConn{opened: time.Now(), status: StatusReady}, d: d}, nil
+}
+
+type clusterConn struct {
+	dummyConn
+	d *clusterDriver
+}
+
+func (c *clusterConn) Query(ctx context.Context, cmd *Command, params []Param, preparedToken any, val DriverValuer) error {
+	c.d.queries.Add(1)
+	if errs := c.d.fail.Load(); errs != nil {
+		return *errs
+	}
+	return nil
+}
+
+// badParam fails to convert every parameter.
+type badParam struct{}
+
+func (badParam) ColumnConverter(column *Column) ColumnConverter { return nil }
+func (badParam) ConvertParam(param *Param) error {
+	return errors.New("bad parameter")
+}
+
+func openCluster(t *testing.T, name string, replicas int) (*Cluster, []*clusterDriver) {
+	t.Helper()
+	var pools []*ConnPool
+	var drivers []*clusterDriver
+	for i := 0; i <= replicas; i++ {
+		d := &clusterDriver{}
+		dn := name + "_" + string(rune('a'+i))
+		Register(dn, d)
+		pool, err := Open(&Config{DriverName: dn, PoolInitCapacity: 1, PoolMaxCapacity: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pools = append(pools, pool)
+		drivers = append(drivers, d)
+	}
+	c := NewCluster(pools[0], pools[1:]...)
+	t.Cleanup(c.Close)
+	return c, drivers
+}
+
+func queryCounts(drivers []*clusterDriver) []int64 {
+	list := make([]int64, len(drivers))
+	for i, d := range drivers {
+		list[i] = d.queries.Load()
+	}
+	return list
+}
+
+func equalCounts(got, want []int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestClusterRouting(t *testing.T) {
+	c, drivers := openCluster(t, "cluster_route", 2)
+	ctx := context.Background()
+
+	if _, err := c.Query(ctx, &Command{Arity: Zero}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := c.Query(ctx, &Command{Arity: Zero, ReadOnly: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Query(WithReadOnly(ctx), &Command{Arity: Zero}); err != nil {
+		t.Fatal(err)
+	}
+	got := queryCounts(drivers)
+	if want := []int64{1, 3, 2}; !equalCounts(got, want) && !equalCounts(got, []int64{1, 2, 3}) {
+		t.Fatalf("query counts %v, want writes on primary and reads round-robin", got)
+	}
+
+	tran, err := c.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tran.cp != c.Primary {
+		t.Fatal("transaction not started on the primary")
+	}
+	tran.Rollback()
+
+	tran, err = c.Begin(WithReadOnly(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tran.cp == c.Primary {
+		t.Fatal("read-only transaction started on the primary")
+	}
+	tran.Rollback()
+}
+
+func TestClusterFallback(t *testing.T) {
+	c, drivers := openCluster(t, "cluster_fallback", 1)
+	c.DownTime = time.Hour
+	ctx := context.Background()
+
+	drivers[1].down.Store(true)
+	for i := 0; i < 3; i++ {
+		if _, err := c.Query(ctx, &Command{Arity: Zero, ReadOnly: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := queryCounts(drivers), []int64{3, 0}; !equalCounts(got, want) {
+		t.Fatalf("query counts %v, want %v", got, want)
+	}
+	if c.replica() != nil {
+		t.Fatal("failed replica not marked down")
+	}
+}
+
+func TestClusterLeastInUse(t *testing.T) {
+	c, drivers := openCluster(t, "cluster_least", 2)
+	c.Balance = LeastInUse
+	ctx := context.Background()
+
+	// Hold a connection on the first replica.
+	conn, err := c.Replicas()[0].Connection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Query(ctx, &Command{Arity: Zero, ReadOnly: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := queryCounts(drivers), []int64{0, 0, 3}; !equalCounts(got, want) {
+		t.Fatalf("query counts %v, want %v", got, want)
+	}
+}
+
+func TestClusterRequestErrorNoFailover(t *testing.T) {
+	c, drivers := openCluster(t, "clusterreqerr", 1)
+	c.DownTime = time.Hour
+	ctx := context.Background()
+
+	_, err := c.Query(ctx, &Command{Arity: Zero, ReadOnly: true, Converter: badParam{}}, Param{Name: "id", Value: 1})
+	if err == nil {
+		t.Fatal("expected parameter error")
+	}
+	constraint := Errors{{Type: SqlError, Number: 547, Err: &ServerError{Kind: ErrForeignKey}}}
+	drivers[1].fail.Store(&constraint)
+	if _, err := c.Query(ctx, &Command{Arity: Zero, ReadOnly: true}); !errors.Is(err, ErrForeignKey) {
+		t.Fatalf("got %v, want the server error", err)
+	}
+	if got, want := queryCounts(drivers), []int64{0, 1}; !equalCounts(got, want) {
+		t.Fatalf("query counts %v, want %v", got, want)
+	}
+	if c.replica() == nil {
+		t.Fatal("replica marked down after a request error")
+	}
+
+	// A database that is not available fails over to the primary.
+	offline := Errors{{Type: SqlError, Number: 954, Err: &ServerError{Kind: ErrUnavailable}}}
+	drivers[1].fail.Store(&offline)
+	if _, err := c.Query(ctx, &Command{Arity: Zero, ReadOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := queryCounts(drivers), []int64{1, 2}; !equalCounts(got, want) {
+		t.Fatalf("query counts %v, want %v", got, want)
+	}
+	if c.replica() != nil {
+		t.Fatal("unavailable replica not marked down")
+	}
+}
+
+func TestClusterBeginScope(t *testing.T) {
+	c, drivers := openCluster(t, "clusterscope", 1)
+	ctx := context.Background()
+
+	tran, err := BeginScope(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tran.cp != c.Primary {
+		t.Fatal("scope not started on the primary")
+	}
+	if _, err := tran.Query(ctx, &Command{Arity: Zero}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tran.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := queryCounts(drivers), []int64{1, 0}; !equalCounts(got, want) {
+		t.Fatalf("queries %v, want %v", got, want)
+	}
+}
@@ -5,6 +5,7 @@
 package rdb
 
 import (
+	"context"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -28,6 +29,31 @@ type Config struct {
 	Instance string
 	Database string // Initial database to connect to.
 
+	// Credentials, if set, is called for each new connection to get the
+	// user name and password, replacing Username and Password.
+	// Connections already open are not affected.
+	// If the server refuses the login it is called once more, with
+	// CredentialsRefresh(ctx) returning true, and the login is retried.
+	Credentials func(ctx context.Context) (user, password string, err error) `json:"-"`
+
+	// AccessToken authenticates with a token, such as an OAuth access token,
+	// instead of a user name and password, if the driver supports it.
+	AccessToken string
+
+	// TokenCredentials, if set, is called for each new connection to get
+	// the AccessToken. It is refreshed the same way as Credentials.
+	TokenCredentials func(ctx context.Context) (token string, err error) `json:"-"`
+
+	// FailoverPartners are servers tried in order when a connection to
+	// Hostname cannot be opened, or the server refuses the login or reports
+	// the database is not available, such as a mirror or restoring database.
+	// Each partner is formatted as "host[:port][/instance]".
+	FailoverPartners []string
+
+	// After failing over, the first server is tried again at this interval.
+	// If zero, defaults to 30s.
+	FailbackInterval time.Duration
+
 	// Timeout time for connection dial.
 	// Zero for no timeout.
 	DialTimeout time.Duration
@@ -62,6 +88,37 @@ type Config struct {
 	// If zero, defaults to 6.
 	ExpandPoolBy int
 
+	// PriorityMaxCapacity optionally limits how many connections requests
+	// of a priority may hold at once. See WithPriority.
+	PriorityMaxCapacity map[Priority]int
+
+	// If non-zero, at most this many requests wait for a connection.
+	// Additional requests fail with ErrPoolBusy.
+	PoolMaxWaiters int
+
+	// If non-zero, after this many consecutive failures to open a connection
+	// new connections fail with ErrCircuitOpen for CircuitCooldown.
+	// After the cool-down a single connection is attempted before
+	// allowing others.
+	CircuitThreshold int
+
+	// Time to refuse new connections after CircuitThreshold failures.
+	// If zero, defaults to 5s.
+	CircuitCooldown time.Duration
+
+	// Validate sets when an idle connection is checked before it is handed out.
+	// A connection that fails the check is replaced with a new connection.
+	Validate ValidateMode
+
+	// Idle time after which a connection is checked if Validate is ValidateIdle.
+	// If zero, defaults to 1s.
+	ValidateAfter time.Duration
+
+	// If non-zero, idle connections are checked at this interval and broken
+	// connections are replaced. At least PoolInitCapacity idle connections
+	// are kept open.
+	HealthCheckInterval time.Duration
+
 	// Require the driver to establish a secure connection.
 	Secure bool
 
@@ -78,6 +135,36 @@ type Config struct {
 	// ResetQuery is executed after the connection is reset.
 	ResetQuery string
 
+	// LeakTimeout enables leak detection if non-zero. A Result, Transaction,
+	// or Connection that holds a pooled connection without being used for
+	// longer than LeakTimeout is reported.
+	LeakTimeout time.Duration
+
+	// LeakReport is called for each leak found. If nil, leaks are logged.
+	LeakReport func(leak *Leak) `json:"-"`
+
+	// LeakClose closes a leaked resource and returns the connection to the pool.
+	LeakClose bool
+
+	// Slice parameters, such as a []int64 used in "in (@ids)", with up to
+	// ListParamMax values are sent as one parameter per value. Longer lists
+	// are sent as a single JSON array if the driver supports it.
+	// If zero, defaults to 200.
+	ListParamMax int
+
+	// Location is used for date and time values that carry no time zone,
+	// such as datetime, datetime2 and date. Values read are returned as the
+	// wall clock time in Location and time parameters are converted to
+	// Location before being sent. If nil, values read are returned in UTC and
+	// parameters are sent with their own wall clock time.
+	// Command.Location overrides it for a single command.
+	Location *time.Location
+
+	// If set, values with a time zone offset, such as datetimeoffset, are
+	// converted to OffsetLocation when read. If nil, the offset sent by the
+	// server is kept.
+	OffsetLocation *time.Location
+
 	KV map[string]interface{}
 }
 
@@ -100,10 +187,24 @@ const optPrefix = "opt_"
 //	   init_cap=<int>:                   Pool Init Capacity
 //	   max_cap=<int>:                    Pool Max Capacity
 //	   expand_by=<int>:                  Number of connections to add when expanding pool (default 6)
+//	   max_cap_<low|normal|high>=<int>:  Max connections held by requests of a priority.
+//	   max_waiters=<int>:                Max requests waiting for a connection before failing with ErrPoolBusy.
+//	   circuit_threshold=<int>:          Consecutive connection failures before failing fast with ErrCircuitOpen.
+//	   circuit_cooldown=<time.Duration>: Time to fail fast before trying to connect again. Default 5s.
 //	   idle_timeout=<time.Duration>:     Pool Idle Timeout
 //	   reset_timeout=<time.Duration>:    Reset Connection Timeout
 //	   soft_wait=<time.Duration>:        Time to wait for connection in pool before expanding pool. Default 20ms.
 //	   rollback_timeout=<time.Duration>: Rollback or cancel connection Timeout.
+//	   validate=<never|always|idle>:     Check idle connections on checkout.
+//	   validate_after=<time.Duration>:   Idle time before checking a connection with validate=idle. Default 1s.
+//	   health_check=<time.Duration>:     Interval to check idle connections and keep init_cap connections open.
+//	   leak_timeout=<time.Duration>:     Report resources unused for longer then this.
+//	   leak_close=<bool>:                Close leaked resources.
+//	   list_param_max=<int>:             Max values of a slice parameter to send as separate parameters. Default 200.
+//	   location=<string>:                Time zone name of date and time values without a zone, such as America/Chicago.
+//	   offset_location=<string>:         Time zone name to convert date and time values with an offset to.
+//	   failover_partner=<host[:port][/instance]>: Server to use if the primary is not available, repeatable or comma separated.
+//	   failback_interval=<time.Duration>: Interval to try the primary server after failing over. Default 30s.
 //	   require_encryption=<bool>:        Require Connection Encryption
 //	   disable_encryption=<bool>:        Disable Connection Encryption
 //	   cert=<string>:                    Load the cert file as root CA, repeatable.
@@ -194,6 +295,61 @@ func ParseConfigURL(connectionString string) (*Config, error) {
 			if err != nil {
 				return nil, fmt.Errorf("DSN property %q: %w", key, err)
 			}
+		case "validate":
+			conf.Validate, err = parseValidateMode(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "validate_after":
+			conf.ValidateAfter, err = time.ParseDuration(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "health_check":
+			conf.HealthCheckInterval, err = time.ParseDuration(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "leak_timeout":
+			conf.LeakTimeout, err = time.ParseDuration(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "leak_close":
+			conf.LeakClose, err = strconv.ParseBool(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "list_param_max":
+			conf.ListParamMax, err = strconv.Atoi(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "location":
+			conf.Location, err = time.LoadLocation(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "offset_location":
+			conf.OffsetLocation, err = time.LoadLocation(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "failover_partner":
+			allowMultiple = true
+			for _, v := range vv {
+				for _, p := range strings.Split(v, ",") {
+					if _, err := parseHostAddr(p); err != nil {
+						return nil, fmt.Errorf("DSN property %q: %w", key, err)
+					}
+					conf.FailoverPartners = append(conf.FailoverPartners, strings.TrimSpace(p))
+				}
+			}
+		case "failback_interval":
+			conf.FailbackInterval, err = time.ParseDuration(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
 		case "query_timeout":
 			// Ignore this.
 			// All query timeouts controlled from context.
@@ -213,6 +369,37 @@ func ParseConfigURL(connectionString string) (*Config, error) {
 			if err != nil {
 				return nil, fmt.Errorf("DSN property %q: %w", key, err)
 			}
+		case "max_cap_low", "max_cap_normal", "max_cap_high":
+			n, err := strconv.Atoi(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+			if conf.PriorityMaxCapacity == nil {
+				conf.PriorityMaxCapacity = make(map[Priority]int)
+			}
+			switch key {
+			case "max_cap_low":
+				conf.PriorityMaxCapacity[Low] = n
+			case "max_cap_normal":
+				conf.PriorityMaxCapacity[Normal] = n
+			case "max_cap_high":
+				conf.PriorityMaxCapacity[High] = n
+			}
+		case "max_waiters":
+			conf.PoolMaxWaiters, err = strconv.Atoi(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "circuit_threshold":
+			conf.CircuitThreshold, err = strconv.Atoi(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
+		case "circuit_cooldown":
+			conf.CircuitCooldown, err = time.ParseDuration(v0)
+			if err != nil {
+				return nil, fmt.Errorf("DSN property %q: %w", key, err)
+			}
 		case "insecure_skip_verify":
 			conf.InsecureSkipVerify, err = strconv.ParseBool(v0)
 			if err != nil {
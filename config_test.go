@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 var configTestPass = map[string]*Config{
@@ -54,6 +55,21 @@ var configTestPass = map[string]*Config{
 		Database:   "mydatabase",
 		KV:         make(map[string]interface{}),
 	},
+	"driver://localUrl?db=mydatabase&failover_partner=mirror:1433,dr/SqlExpress&failover_partner=other&failback_interval=1m": {
+		DriverName:       "driver",
+		Hostname:         "localUrl",
+		Database:         "mydatabase",
+		FailoverPartners: []string{"mirror:1433", "dr/SqlExpress", "other"},
+		FailbackInterval: time.Minute,
+		KV:               make(map[string]interface{}),
+	},
+	"driver://localUrl?db=mydatabase&list_param_max=50": {
+		DriverName:   "driver",
+		Hostname:     "localUrl",
+		Database:     "mydatabase",
+		ListParamMax: 50,
+		KV:           make(map[string]interface{}),
+	},
 	"sqlite:///C:/folder/file.sqlite3?opt_1=valA&opt_2=valB": {
 		DriverName: "sqlite",
 		Username:   "",
@@ -94,3 +110,21 @@ func TestConfigURL(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigURLLocation(t *testing.T) {
+	conf, err := ParseConfigURL("driver://localUrl?location=America/Chicago&offset_location=UTC")
+	if err != nil {
+		if _, is := err.(DriverNotFound); !is {
+			t.Fatal(err)
+		}
+	}
+	if conf.Location == nil || conf.Location.String() != "America/Chicago" {
+		t.Errorf("location: %v", conf.Location)
+	}
+	if conf.OffsetLocation != time.UTC {
+		t.Errorf("offset location: %v", conf.OffsetLocation)
+	}
+	if _, err := ParseConfigURL("driver://localUrl?location=Nowhere/Town"); err == nil {
+		t.Error("expected error for unknown location")
+	}
+}
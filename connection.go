@@ -7,22 +7,28 @@ package rdb
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 )
 
 type Connection struct {
 	cp   *ConnPool
 	conn DriverConn
-	done bool
+	done atomic.Bool
 
 	// Used, if an option, to renew a connection if required on close.
 	ctx context.Context
+
+	// Set if leak detection is enabled.
+	leak *leakEntry
 }
 
 var errConnectionClosed = errors.New("connection already closed")
 
 // Query executes a Command on the connection.
 func (c *Connection) Query(ctx context.Context, cmd *Command, params ...Param) (*Result, error) {
-	if c.done {
+	c.leak.lock()
+	defer c.leak.unlock()
+	if c.done.Load() {
 		return nil, errConnectionClosed
 	}
 	return c.cp.query(ctx, true, c.conn, cmd, nil, params...)
@@ -30,15 +36,28 @@ func (c *Connection) Query(ctx context.Context, cmd *Command, params ...Param) (
 
 // Close returns the underlying connection to the Connection Pool.
 func (c *Connection) Close() error {
-	if c.done {
-		return errTransactionClosed
+	c.leak.lock()
+	defer c.leak.unlock()
+	return c.close(false)
+}
+
+// close releases the connection to the connection pool.
+// If kill is set the connection is closed first.
+func (c *Connection) close(kill bool) error {
+	if !c.done.CompareAndSwap(false, true) {
+		return errConnectionClosed
 	}
-	c.done = true
-	c.cp.releaseConn(c.ctx, c.conn, c.conn.Status() != StatusReady)
+	c.cp.releaseConn(c.ctx, c.conn, kill || c.conn.Status() != StatusReady)
+	return nil
+}
+
+// forceClose closes a leaked connection and releases it to the connection pool.
+func (c *Connection) forceClose() func() {
+	c.close(true)
 	return nil
 }
 
 // Return true if the connection has not been closed.
 func (c *Connection) Active() bool {
-	return !c.done
+	return !c.done.Load()
 }
@@ -0,0 +1,406 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Driver option keys set from a connection string.
+const (
+	OptApplicationIntent = "application_intent" // "ReadOnly" or "ReadWrite".
+	OptApplicationName   = "app_name"
+	OptTDS8              = "tds8" // "only" to require TDS 8.0 or "disable".
+)
+
+// Connection string key synonyms, mapped to the canonical key.
+var connStrKeys = map[string]string{
+	"server":                   "server",
+	"data source":              "server",
+	"address":                  "server",
+	"addr":                     "server",
+	"network address":          "server",
+	"database":                 "database",
+	"initial catalog":          "database",
+	"user id":                  "user id",
+	"uid":                      "user id",
+	"user":                     "user id",
+	"password":                 "password",
+	"pwd":                      "password",
+	"encrypt":                  "encrypt",
+	"trustservercertificate":   "trustservercertificate",
+	"trust server certificate": "trustservercertificate",
+	"connect timeout":          "connect timeout",
+	"connection timeout":       "connect timeout",
+	"timeout":                  "connect timeout",
+	"application intent":       "application intent",
+	"applicationintent":        "application intent",
+	"application name":         "application name",
+	"app":                      "application name",
+	"failover partner":         "failover partner",
+	"max pool size":            "max pool size",
+	"min pool size":            "min pool size",
+	"connection lifetime":      "connection lifetime",
+	"load balance timeout":     "connection lifetime",
+	"pooling":                  "pooling",
+	"integrated security":      "integrated security",
+	"trusted_connection":       "integrated security",
+}
+
+// splitConnectionString splits an ADO.NET or ODBC style connection string
+// into key value pairs. Values may be quoted with single or double quotes,
+// where a doubled quote is a literal quote, or with braces, where "}}"
+// is a literal "}".
+func splitConnectionString(s string) ([][2]string, error) {
+	var list [][2]string
+	for i := 0; i < len(s); {
+		// Key.
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			if rest := strings.TrimSpace(strings.Trim(s[i:], ";")); len(rest) > 0 {
+				return nil, fmt.Errorf("missing value for %q", rest)
+			}
+			break
+		}
+		key := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if strings.ContainsRune(key, ';') {
+			return nil, fmt.Errorf("missing value for %q", key[:strings.IndexByte(key, ';')])
+		}
+
+		// Value.
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		var value string
+		if i < len(s) && (s[i] == '\'' || s[i] == '"' || s[i] == '{') {
+			open := s[i]
+			end := open
+			if open == '{' {
+				end = '}'
+			}
+			i++
+			sb := &strings.Builder{}
+			closed := false
+			for i < len(s) {
+				c := s[i]
+				i++
+				if c != end {
+					sb.WriteByte(c)
+					continue
+				}
+				if i < len(s) && s[i] == end {
+					sb.WriteByte(c)
+					i++
+					continue
+				}
+				closed = true
+				break
+			}
+			if !closed {
+				return nil, fmt.Errorf("key %q: unterminated %c", key, open)
+			}
+			value = sb.String()
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			if i < len(s) && s[i] != ';' {
+				return nil, fmt.Errorf("key %q: unexpected text after quoted value", key)
+			}
+		} else {
+			semi := strings.IndexByte(s[i:], ';')
+			if semi < 0 {
+				semi = len(s) - i
+			}
+			value = strings.TrimSpace(s[i : i+semi])
+			i += semi
+		}
+		i++ // Skip the ';'.
+		if len(key) == 0 {
+			return nil, fmt.Errorf("missing key for value %q", value)
+		}
+		list = append(list, [2]string{key, value})
+	}
+	return list, nil
+}
+
+// parseConnBool parses boolean connection string values.
+func parseConnBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "true", "yes", "1":
+		return true, nil
+	case "false", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", v)
+}
+
+// parseServer parses "[tcp:]host[\instance][,port]".
+func parseServer(conf *Config, v string) error {
+	v = strings.TrimPrefix(v, "tcp:")
+	if i := strings.LastIndexByte(v, ','); i >= 0 {
+		port, err := strconv.ParseUint(strings.TrimSpace(v[i+1:]), 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid port: %w", err)
+		}
+		conf.Port = int(port)
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '\\'); i >= 0 {
+		conf.Instance = v[i+1:]
+		v = v[:i]
+	}
+	switch strings.ToLower(v) {
+	case "(local)", ".":
+		v = "localhost"
+	}
+	conf.Hostname = v
+	return nil
+}
+
+// ParseConnectionString parses an ADO.NET or ODBC style connection string
+// for driverName into a Config:
+//
+//	Server=tcp:host,1433;Database=app;User ID=u;Password=p;Encrypt=true
+//
+// Keys are case insensitive and common synonyms are accepted, such as
+// "Data Source" for "Server" and "Initial Catalog" for "Database".
+// Keys that are not known are matched to the driver's options.
+// Keys prefixed with "opt_" are stored in Config.KV without the prefix.
+// "Encrypt=Strict" requires TDS 8.0 with OptTDS8. Connections are always
+// pooled, so "Pooling=false" is an error.
+func ParseConnectionString(driverName, s string) (*Config, error) {
+	pairs, err := splitConnectionString(s)
+	if err != nil {
+		return nil, err
+	}
+	conf := &Config{
+		DriverName: driverName,
+		KV:         make(map[string]interface{}),
+	}
+	var options []*DriverOption
+	if dr, err := getDriver(driverName); err == nil {
+		options = dr.DriverInfo().Options
+	}
+
+	for _, kv := range pairs {
+		key, v := kv[0], kv[1]
+		canon, ok := connStrKeys[strings.ToLower(key)]
+		if !ok {
+			if strings.HasPrefix(key, optPrefix) {
+				conf.KV[strings.TrimPrefix(key, optPrefix)] = v
+				continue
+			}
+			op := findOption(options, key)
+			if op == nil {
+				return nil, fmt.Errorf("unknown connection string key %q", key)
+			}
+			var value interface{} = v
+			if op.Parse != nil {
+				value, err = op.Parse(v)
+				if err != nil {
+					return nil, fmt.Errorf("connection string key %q: %w", key, err)
+				}
+			}
+			conf.KV[op.Name] = value
+			continue
+		}
+		switch canon {
+		case "server":
+			err = parseServer(conf, v)
+		case "database":
+			conf.Database = v
+		case "user id":
+			conf.Username = v
+		case "password":
+			conf.Password = v
+		case "encrypt":
+			switch strings.ToLower(v) {
+			case "mandatory":
+				conf.Secure = true
+			case "strict":
+				conf.Secure = true
+				conf.KV[OptTDS8] = "only"
+			case "optional":
+				conf.Secure = false
+			default:
+				conf.Secure, err = parseConnBool(v)
+			}
+		case "trustservercertificate":
+			conf.InsecureSkipVerify, err = parseConnBool(v)
+		case "connect timeout":
+			var sec int
+			sec, err = strconv.Atoi(v)
+			conf.DialTimeout = time.Duration(sec) * time.Second
+		case "application intent":
+			switch strings.ToLower(v) {
+			case "readonly":
+				conf.KV[OptApplicationIntent] = "ReadOnly"
+			case "readwrite":
+				conf.KV[OptApplicationIntent] = "ReadWrite"
+			default:
+				err = fmt.Errorf("must be ReadOnly or ReadWrite")
+			}
+		case "application name":
+			conf.KV[OptApplicationName] = v
+		case "failover partner":
+			partner := &Config{}
+			err = parseServer(partner, v)
+			if err == nil {
+				h := hostAddr{Hostname: partner.Hostname, Port: partner.Port, Instance: partner.Instance}
+				conf.FailoverPartners = append(conf.FailoverPartners, h.String())
+			}
+		case "max pool size":
+			conf.PoolMaxCapacity, err = strconv.Atoi(v)
+		case "min pool size":
+			conf.PoolInitCapacity, err = strconv.Atoi(v)
+		case "connection lifetime":
+			var sec int
+			sec, err = strconv.Atoi(v)
+			conf.ConnectionMaxLifetime = time.Duration(sec) * time.Second
+		case "pooling":
+			var on bool
+			on, err = parseConnBool(v)
+			if err == nil && !on {
+				err = fmt.Errorf("pooling cannot be disabled")
+			}
+		case "integrated security":
+			on := strings.EqualFold(v, "sspi")
+			if !on {
+				on, err = parseConnBool(v)
+			}
+			if err == nil && on {
+				err = fmt.Errorf("integrated security is not supported")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("connection string key %q: %w", key, err)
+		}
+	}
+	return conf, nil
+}
+
+// formatServer returns h as "host[\instance][,port]".
+func formatServer(h hostAddr) string {
+	server := h.Hostname
+	if len(h.Instance) > 0 {
+		server += `\` + h.Instance
+	}
+	if h.Port != 0 {
+		server += "," + strconv.Itoa(h.Port)
+	}
+	return server
+}
+
+// findOption returns the driver option matching key, ignoring case
+// and treating spaces as underscores.
+func findOption(options []*DriverOption, key string) *DriverOption {
+	key = strings.ReplaceAll(key, " ", "_")
+	for _, op := range options {
+		if strings.EqualFold(op.Name, key) {
+			return op
+		}
+	}
+	return nil
+}
+
+// quoteConnValue quotes a connection string value if needed.
+func quoteConnValue(v string) string {
+	if !strings.ContainsAny(v, ";'\"{}=") && strings.TrimSpace(v) == v {
+		return v
+	}
+	return "{" + strings.ReplaceAll(v, "}", "}}") + "}"
+}
+
+// ConnectionString returns the Config as an ADO.NET style connection string
+// that can be read by ParseConnectionString. The password is redacted.
+func (c *Config) ConnectionString() string {
+	var pairs [][2]string
+	add := func(key, value string) {
+		pairs = append(pairs, [2]string{key, value})
+	}
+	if len(c.Hostname) > 0 || len(c.Instance) > 0 || c.Port != 0 {
+		add("Server", formatServer(hostAddr{Hostname: c.Hostname, Port: c.Port, Instance: c.Instance}))
+	}
+	if len(c.Database) > 0 {
+		add("Database", c.Database)
+	}
+	if len(c.Username) > 0 {
+		add("User ID", c.Username)
+	}
+	if len(c.Password) > 0 {
+		add("Password", "*****")
+	}
+	strict := c.Secure && c.KV[OptTDS8] == "only"
+	switch {
+	case strict:
+		add("Encrypt", "strict")
+	case c.Secure:
+		add("Encrypt", "true")
+	}
+	if c.InsecureSkipVerify {
+		add("TrustServerCertificate", "true")
+	}
+	if c.DialTimeout > 0 {
+		add("Connect Timeout", strconv.Itoa(int(c.DialTimeout/time.Second)))
+	}
+	for _, p := range c.FailoverPartners {
+		if h, err := parseHostAddr(p); err == nil {
+			add("Failover Partner", formatServer(h))
+		}
+	}
+	if c.PoolInitCapacity > 0 {
+		add("Min Pool Size", strconv.Itoa(c.PoolInitCapacity))
+	}
+	if c.PoolMaxCapacity > 0 {
+		add("Max Pool Size", strconv.Itoa(c.PoolMaxCapacity))
+	}
+	if c.ConnectionMaxLifetime > 0 {
+		add("Connection Lifetime", strconv.Itoa(int(c.ConnectionMaxLifetime/time.Second)))
+	}
+	var options []*DriverOption
+	if dr, err := getDriver(c.DriverName); err == nil {
+		options = dr.DriverInfo().Options
+	}
+	keys := make([]string, 0, len(c.KV))
+	for k := range c.KV {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch k {
+		case OptApplicationIntent:
+			add("Application Intent", fmt.Sprint(c.KV[k]))
+		case OptApplicationName:
+			add("Application Name", fmt.Sprint(c.KV[k]))
+		case OptTDS8:
+			if strict {
+				continue
+			}
+			fallthrough
+		default:
+			if findOption(options, k) != nil {
+				add(k, fmt.Sprint(c.KV[k]))
+			} else {
+				add(optPrefix+k, fmt.Sprint(c.KV[k]))
+			}
+		}
+	}
+
+	sb := &strings.Builder{}
+	for i, kv := range pairs {
+		if i > 0 {
+			sb.WriteByte(';')
+		}
+		sb.WriteString(kv[0])
+		sb.WriteByte('=')
+		sb.WriteString(quoteConnValue(kv[1]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+var connStrTestPass = map[string]*Config{
+	"Server=tcp:db.example.com,1433;Database=app;User ID=u;Password=p;Encrypt=true;TrustServerCertificate=true;Application Intent=ReadOnly": {
+		DriverName:         "ms",
+		Hostname:           "db.example.com",
+		Port:               1433,
+		Database:           "app",
+		Username:           "u",
+		Password:           "p",
+		Secure:             true,
+		InsecureSkipVerify: true,
+		KV: map[string]interface{}{
+			OptApplicationIntent: "ReadOnly",
+		},
+	},
+	`data source=(local)\SQLEXPRESS; initial catalog = app ;uid=u;pwd='it''s;secret';Connect Timeout=15`: {
+		DriverName:  "ms",
+		Hostname:    "localhost",
+		Instance:    "SQLEXPRESS",
+		Database:    "app",
+		Username:    "u",
+		Password:    "it's;secret",
+		DialTimeout: 15 * time.Second,
+		KV:          map[string]interface{}{},
+	},
+	`Server=a;Password={p}}w;d};App=tool;Failover Partner=b\MIRROR;Max Pool Size=20;Min Pool Size=2;opt_tds8=only;`: {
+		DriverName:       "ms",
+		Hostname:         "a",
+		Password:         "p}w;d",
+		FailoverPartners: []string{"b/MIRROR"},
+		PoolMaxCapacity:  20,
+		PoolInitCapacity: 2,
+		KV: map[string]interface{}{
+			OptApplicationName: "tool",
+			"tds8":             "only",
+		},
+	},
+	"Server=a;Encrypt=Strict;Pooling=true;Integrated Security=false": {
+		DriverName: "ms",
+		Hostname:   "a",
+		Secure:     true,
+		KV: map[string]interface{}{
+			OptTDS8: "only",
+		},
+	},
+}
+
+var connStrTestFail = []string{
+	"Server",
+	"Server=a;Database",
+	"Bogus Key=1",
+	"Password='unterminated",
+	"Password='a'b",
+	"Encrypt=perhaps",
+	"Application Intent=Sometimes",
+	"Server=a,port",
+	"Integrated Security=SSPI",
+	"Encrypt=SSPI",
+	"Pooling=false",
+}
+
+func TestConnectionString(t *testing.T) {
+	for s, confExpect := range connStrTestPass {
+		conf, err := ParseConnectionString("ms", s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		got, _ := json.MarshalIndent(conf, "", "\t")
+		want, _ := json.MarshalIndent(confExpect, "", "\t")
+		if !bytes.Equal(got, want) {
+			t.Errorf("Not as expected:\nconn: %s\ngot: %s\nwant: %s", s, got, want)
+		}
+
+		// Round trip, with the password redacted.
+		out := conf.ConnectionString()
+		again, err := ParseConnectionString("ms", out)
+		if err != nil {
+			t.Errorf("round trip %s: %v", out, err)
+			continue
+		}
+		if len(conf.Password) > 0 && again.Password != "*****" {
+			t.Errorf("round trip %s: password not redacted", out)
+		}
+		again.Password = conf.Password
+		got, _ = json.MarshalIndent(again, "", "\t")
+		if !bytes.Equal(got, want) {
+			t.Errorf("Round trip not as expected:\nconn: %s\ngot: %s\nwant: %s", out, got, want)
+		}
+	}
+	for _, s := range connStrTestFail {
+		if _, err := ParseConnectionString("ms", s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type credentialsRefreshKey struct{}
+
+// CredentialsRefresh returns true if a credential provider is called again
+// after the server refused the login. Providers that cache secrets should
+// fetch new secrets when it returns true.
+func CredentialsRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(credentialsRefreshKey{}).(bool)
+	return refresh
+}
+
+// loginConfig returns the configuration used to open a new connection,
+// with the credentials from Config.Credentials or Config.TokenCredentials.
+func (cp *ConnPool) loginConfig(ctx context.Context, refresh bool) (*Config, error) {
+	if cp.conf.Credentials == nil && cp.conf.TokenCredentials == nil {
+		return cp.conf, nil
+	}
+	if refresh {
+		ctx = context.WithValue(ctx, credentialsRefreshKey{}, true)
+	}
+	conf := *cp.conf
+	if cp.conf.Credentials != nil {
+		user, password, err := cp.conf.Credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("credentials: %w", err)
+		}
+		conf.Username, conf.Password = user, password
+	}
+	if cp.conf.TokenCredentials != nil {
+		token, err := cp.conf.TokenCredentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("token credentials: %w", err)
+		}
+		conf.AccessToken = token
+	}
+	return &conf, nil
+}
+
+// open opens a new driver connection. If the login fails with credentials
+// from a provider, the credentials are refreshed and the login is tried once more.
+func (cp *ConnPool) open(ctx context.Context) (DriverConn, error) {
+	conf, err := cp.loginConfig(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := cp.openHost(ctx, conf)
+	if err != nil && conf != cp.conf && errors.Is(err, ErrLoginFailed) {
+		conf, err = cp.loginConfig(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		conn, err = cp.openHost(ctx, conf)
+	}
+	if err != nil {
+		return conn, &connectError{err: err}
+	}
+	return conn, nil
+}
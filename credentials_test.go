@@ -0,0 +1,103 @@
+package rdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// credDriver accepts logins with the current password.
+type credDriver struct {
+	dummyDriver
+
+	mu       sync.Mutex
+	password string
+	logins   []string
+}
+
+func (d *credDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logins = append(d.logins, c.Username+":"+c.Password)
+	if c.Password != d.password {
+		return nil, Errors{{Type: SqlError, Number: 18456, Err: &ServerError{Kind: ErrLoginFailed}}}
+	}
+	return &dummyConn{opened: time.Now(), status: StatusReady}, nil
+}
+
+func TestCredentialsRefresh(t *testing.T) {
+	d := &credDriver{password: "one"}
+	Register("credentials", d)
+
+	var refreshed []bool
+	secret := "one"
+	pool, err := Open(&Config{
+		DriverName:       "credentials",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		Credentials: func(ctx context.Context) (string, string, error) {
+			refresh := CredentialsRefresh(ctx)
+			refreshed = append(refreshed, refresh)
+			if refresh {
+				secret = d.password
+			}
+			return "app", secret, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	if _, err := pool.Query(ctx, &Command{Arity: Zero}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the password; the cached secret is refused once then refreshed.
+	d.mu.Lock()
+	d.password = "two"
+	d.mu.Unlock()
+	pool.Drain()
+
+	if _, err := pool.Query(ctx, &Command{Arity: Zero}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"app:one", "app:one", "app:two"}
+	if !equalCalls(d.logins, want) {
+		t.Fatalf("logins %q, want %q", d.logins, want)
+	}
+	if len(refreshed) != 3 || refreshed[0] || refreshed[1] || !refreshed[2] {
+		t.Fatalf("refresh flags %v", refreshed)
+	}
+}
+
+func TestTokenCredentials(t *testing.T) {
+	d := &hostDriver{down: map[string]error{}}
+	Register("token_credentials", d)
+
+	pool, err := Open(&Config{
+		DriverName:       "token_credentials",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		TokenCredentials: func(ctx context.Context) (string, error) {
+			return "token", nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	conf, err := pool.loginConfig(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.AccessToken != "token" {
+		t.Fatalf("got token %q, want token", conf.AccessToken)
+	}
+	if pool.conf.AccessToken != "" {
+		t.Fatal("provider token stored in the pool configuration")
+	}
+}
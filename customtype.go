@@ -0,0 +1,135 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ParamValuer is implemented by Go types that convert themselves to a
+// parameter value, such as decimal, UUID, or enum types. ParamValue is
+// called before the parameter is sent and should set param.Value to a value
+// the driver understands. It may also set the Type, Length, Precision,
+// and Scale if they are not already set.
+type ParamValuer interface {
+	ParamValue(param *Param) error
+}
+
+// ColumnScanner is implemented by pointers to Go types that read themselves
+// from a column. The value is the value decoded by the driver and may be null.
+// The column may be nil when the driver assigns the value directly.
+type ColumnScanner interface {
+	ScanColumn(column *Column, value Nullable) error
+}
+
+type typeCodec struct {
+	encode func(param *Param) error
+	decode func(column *Column, value Nullable, dest interface{}) error
+}
+
+var (
+	customTypesMu sync.Mutex
+	customTypes   atomic.Pointer[map[reflect.Type]typeCodec]
+)
+
+// RegisterType registers how values of T are sent as parameters and read
+// from columns. Use it for types that cannot implement ParamValuer and
+// ColumnScanner, such as types from another package. The encode func sets
+// param.Value from v, as ParamValuer does. The decode func sets dest from
+// the column value. Either func may be nil. Registering T again replaces
+// the previous funcs.
+//
+// Types are usually registered in an init function. Registered types are
+// used by all connection pools.
+func RegisterType[T any](encode func(param *Param, v T) error, decode func(column *Column, value Nullable, dest *T) error) {
+	var codec typeCodec
+	if encode != nil {
+		codec.encode = func(param *Param) error {
+			return encode(param, param.Value.(T))
+		}
+	}
+	if decode != nil {
+		codec.decode = func(column *Column, value Nullable, dest interface{}) error {
+			return decode(column, value, dest.(*T))
+		}
+	}
+
+	customTypesMu.Lock()
+	defer customTypesMu.Unlock()
+
+	next := make(map[reflect.Type]typeCodec)
+	if m := customTypes.Load(); m != nil {
+		for t, c := range *m {
+			next[t] = c
+		}
+	}
+	next[reflect.TypeFor[T]()] = codec
+	customTypes.Store(&next)
+}
+
+func lookupType(t reflect.Type) (typeCodec, bool) {
+	m := customTypes.Load()
+	if m == nil {
+		return typeCodec{}, false
+	}
+	codec, ok := (*m)[t]
+	return codec, ok
+}
+
+var columnScannerType = reflect.TypeFor[ColumnScanner]()
+
+// IsScanType returns true if values of t can be read from a column with a
+// ColumnScanner or a decode func registered with RegisterType.
+func IsScanType(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(columnScannerType) {
+		return true
+	}
+	codec, ok := lookupType(t)
+	return ok && codec.decode != nil
+}
+
+// encodeParam converts a ParamValuer or registered type to a driver value.
+// It returns false if the value is not a custom type.
+func encodeParam(param *Param) (handled bool, err error) {
+	switch param.Value.(type) {
+	case nil, string, []byte, int64, int32, int, bool, float64:
+		return false, nil
+	}
+	if pv, ok := param.Value.(ParamValuer); ok {
+		if rv := reflect.ValueOf(pv); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			param.Value = nil
+			param.Null = true
+			return true, nil
+		}
+		return true, pv.ParamValue(param)
+	}
+	codec, ok := lookupType(reflect.TypeOf(param.Value))
+	if !ok || codec.encode == nil {
+		return false, nil
+	}
+	return true, codec.encode(param)
+}
+
+// assignCustom assigns value to prep if prep is a ColumnScanner or a pointer
+// to a registered type. It returns false if prep is neither.
+func assignCustom(c *Column, value Nullable, prep interface{}) (handled bool, err error) {
+	if s, ok := prep.(ColumnScanner); ok {
+		return true, s.ScanColumn(c, value)
+	}
+	if customTypes.Load() == nil {
+		return false, nil
+	}
+	t := reflect.TypeOf(prep)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return false, nil
+	}
+	codec, ok := lookupType(t.Elem())
+	if !ok || codec.decode == nil {
+		return false, nil
+	}
+	return true, codec.decode(c, value, prep)
+}
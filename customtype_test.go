@@ -0,0 +1,132 @@
+package rdb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// cents implements ParamValuer and ColumnScanner.
+type cents struct {
+	N    int64
+	Null bool
+}
+
+func (c cents) ParamValue(param *Param) error {
+	param.Value = fmt.Sprintf("%d.%02d", c.N/100, c.N%100)
+	if param.Type == TypeUnknown {
+		param.Type = TypeDecimal
+		param.Precision, param.Scale = 19, 2
+	}
+	return nil
+}
+
+func (c *cents) ScanColumn(column *Column, value Nullable) error {
+	if value.Null {
+		*c = cents{Null: true}
+		return nil
+	}
+	s, ok := value.Value.(string)
+	if !ok {
+		return fmt.Errorf("cents: unexpected %T", value.Value)
+	}
+	n, err := strconv.ParseInt(strings.Replace(s, ".", "", 1), 10, 64)
+	if err != nil {
+		return err
+	}
+	*c = cents{N: n}
+	return nil
+}
+
+// upper is registered with RegisterType.
+type upper string
+
+func init() {
+	RegisterType(func(param *Param, v upper) error {
+		param.Value = strings.ToUpper(string(v))
+		return nil
+	}, func(column *Column, value Nullable, dest *upper) error {
+		if value.Null {
+			*dest = "NULL"
+			return nil
+		}
+		switch v := value.Value.(type) {
+		case []byte:
+			*dest = upper(strings.ToUpper(string(v)))
+		default:
+			*dest = upper(strings.ToUpper(fmt.Sprint(v)))
+		}
+		return nil
+	})
+}
+
+func TestColumnScanner(t *testing.T) {
+	var c cents
+	if err := AssignValue(&Column{Name: "c"}, Nullable{Value: "12.34"}, &c, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.N != 1234 {
+		t.Fatalf("got %d, want 1234", c.N)
+	}
+	if handled, err := DirectAssignString(&c, "0.05", false, nil); !handled || err != nil || c.N != 5 {
+		t.Fatalf("direct: handled=%t err=%v got %d", handled, err, c.N)
+	}
+	if handled, err := DirectAssignString(&c, "", true, nil); !handled || err != nil || !c.Null {
+		t.Fatalf("direct null: handled=%t err=%v got %+v", handled, err, c)
+	}
+	if err := AssignValue(nil, Nullable{Null: true}, &c, nil); err != nil || !c.Null {
+		t.Fatalf("null: err=%v got %+v", err, c)
+	}
+	if err := AssignValue(nil, Nullable{Value: int64(1)}, &c, nil); err == nil {
+		t.Fatal("expected scanner error")
+	}
+
+	var u upper
+	if handled, err := DirectAssignBytes(&u, []byte("abc"), false, true, nil); !handled || err != nil || u != "ABC" {
+		t.Fatalf("registered: handled=%t err=%v got %q", handled, err, u)
+	}
+	if err := AssignValue(nil, Nullable{Value: int32(7)}, &u, nil); err != nil || u != "7" {
+		t.Fatalf("registered: err=%v got %q", err, u)
+	}
+	if handled, err := DirectAssignInt(&u, 0, true, nil); !handled || err != nil || u != "NULL" {
+		t.Fatalf("registered null: handled=%t err=%v got %q", handled, err, u)
+	}
+}
+
+func TestParamValuer(t *testing.T) {
+	d := &inferDriver{params: make(chan []Param, 1)}
+	Register("custom_param", d)
+	pool, err := Open(&Config{DriverName: "custom_param"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var nilCents *cents
+	res, err := pool.Query(context.Background(), &Command{SQL: "select"},
+		Param{Name: "c", Value: cents{N: 250}},
+		Param{Name: "p", Value: &cents{N: 1}, Type: TypeVarChar},
+		Param{Name: "n", Value: nilCents},
+		Param{Name: "u", Value: upper("abc")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+
+	got := <-d.params
+	if p := got[0]; p.Value != "2.50" || p.Type != TypeDecimal || p.Scale != 2 {
+		t.Errorf("c: got %#v", p)
+	}
+	if p := got[1]; p.Value != "0.01" || p.Type != TypeVarChar {
+		t.Errorf("p: got %#v", p)
+	}
+	if p := got[2]; !p.Null || p.Value != nil {
+		t.Errorf("n: got %#v", p)
+	}
+	if p := got[3]; p.Value != "ABC" || p.Type != TypeVarChar {
+		t.Errorf("u: got %#v", p)
+	}
+}
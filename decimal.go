@@ -0,0 +1,743 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+)
+
+// MaxDecimalScale is the largest Decimal scale and the largest number of
+// digits in a Decimal coefficient, the same as SQL decimal(38, s).
+const MaxDecimalScale = 38
+
+// ErrDecimalOverflow is returned when a Decimal result does not fit in
+// 38 digits.
+var ErrDecimalOverflow = errors.New("decimal overflow")
+
+// ErrDecimalDivideByZero is returned when dividing a Decimal by zero.
+var ErrDecimalDivideByZero = errors.New("decimal division by zero")
+
+var errDecimalSyntax = errors.New("invalid syntax")
+
+// RoundingMode selects how a Decimal is rounded when digits are dropped.
+type RoundingMode uint8
+
+const (
+	RoundHalfUp   RoundingMode = iota // Nearest, ties away from zero.
+	RoundHalfEven                     // Nearest, ties to even (banker's rounding).
+	RoundHalfDown                     // Nearest, ties toward zero.
+	RoundUp                           // Away from zero.
+	RoundDown                         // Toward zero (truncate).
+	RoundCeiling                      // Toward positive infinity.
+	RoundFloor                        // Toward negative infinity.
+)
+
+// Decimal is an exact decimal number: a coefficient of up to 38 digits and
+// a scale of 0 to 38 fractional digits, the same range as SQL decimal(38, s).
+// The zero value is 0. A Decimal is a small value type; decoding, arithmetic
+// and formatting do not allocate.
+//
+// Drivers decode decimal, numeric and money columns to Decimal. Scan into a
+// *Decimal or *Opt[Decimal] for the allocation free path; *big.Rat, *float64
+// and *string destinations are converted.
+//
+// The scale is part of the value: 1.5 and 1.50 are equal by Cmp, but print
+// differently and are not ==.
+type Decimal struct {
+	hi, lo uint64 // Coefficient magnitude.
+	scale  uint8
+	neg    bool
+}
+
+// NewDecimal returns coef × 10^-scale, so NewDecimal(1250, 2) is 12.50.
+// It panics if scale is not between 0 and MaxDecimalScale.
+func NewDecimal(coef int64, scale int) Decimal {
+	if err := checkScale(scale); err != nil {
+		panic(err)
+	}
+	mag := uint64(coef)
+	if coef < 0 {
+		mag = -mag
+	}
+	return newDecimal(wide{mag}, scale, coef < 0)
+}
+
+// DecimalFromUint128 returns ±(hi<<64 | lo) × 10^-scale. Drivers use it to
+// decode wire values. It returns ErrDecimalOverflow if the coefficient has
+// more than 38 digits.
+func DecimalFromUint128(neg bool, hi, lo uint64, scale int) (Decimal, error) {
+	if err := checkScale(scale); err != nil {
+		return Decimal{}, err
+	}
+	c := wide{lo, hi}
+	if !fits(c) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return newDecimal(c, scale, neg), nil
+}
+
+// ParseDecimal parses s, such as "-12.50" or "1.25e3". The scale is the
+// number of fractional digits in s less any exponent, so "1.25e3" is 1250
+// with a scale of 0. Trailing zeros beyond a scale of 38 are dropped; other
+// values that need more than 38 digits return an error.
+func ParseDecimal(s string) (Decimal, error) {
+	return parseDecimal(s)
+}
+
+func parseDecimal[S string | []byte](s S) (Decimal, error) {
+	c, scale, neg, err := parseCoef(s)
+	if err == nil {
+		var d Decimal
+		d, err = exactDecimal(c, scale, neg)
+		if err == nil {
+			return d, nil
+		}
+	}
+	return Decimal{}, fmt.Errorf("parse decimal %q: %w", s, err)
+}
+
+// DecimalFromFloat64 returns the shortest decimal that converts back to f.
+// Values that need a scale over 38 are rounded half to even at scale 38.
+func DecimalFromFloat64(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("decimal from float %v: not a finite number", f)
+	}
+	var buf [32]byte
+	c, scale, neg, err := parseCoef(strconv.AppendFloat(buf[:0], f, 'e', -1, 64))
+	if err != nil {
+		return Decimal{}, err
+	}
+	switch {
+	case scale > 2*MaxDecimalScale:
+		// At most 17 digits, so the value is below half of 10^-38.
+		return Decimal{scale: MaxDecimalScale}, nil
+	case scale > MaxDecimalScale:
+		return reduce(c, scale, neg, RoundHalfEven)
+	}
+	return exactDecimal(c, scale, neg)
+}
+
+// DecimalFromRat returns r rounded to scale fractional digits with mode.
+func DecimalFromRat(r *big.Rat, scale int, mode RoundingMode) (Decimal, error) {
+	if err := checkScale(scale); err != nil {
+		return Decimal{}, err
+	}
+	var num, den, rem big.Int
+	num.Abs(r.Num())
+	num.Mul(&num, decPow10[scale].bigInt(&den))
+	den.Set(r.Denom())
+	num.QuoRem(&num, &den, &rem)
+	if num.BitLen() > 128 {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	c := wideFromBig(&num)
+	neg := r.Sign() < 0
+	if rem.Sign() != 0 {
+		half := rem.Cmp(den.Sub(&den, &rem))
+		if roundAway(mode, neg, half, c[0]&1 == 1) {
+			c = c.add(wide{1})
+		}
+	}
+	if !fits(c) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return newDecimal(c, scale, neg), nil
+}
+
+// Uint128 returns the sign and coefficient of d, which is
+// ±(hi<<64 | lo) × 10^-d.Scale().
+func (d Decimal) Uint128() (neg bool, hi, lo uint64) {
+	return d.neg, d.hi, d.lo
+}
+
+// Scale returns the number of fractional digits of d.
+func (d Decimal) Scale() int {
+	return int(d.scale)
+}
+
+// Sign returns -1, 0 or 1 for a negative, zero or positive d.
+func (d Decimal) Sign() int {
+	switch {
+	case d.IsZero():
+		return 0
+	case d.neg:
+		return -1
+	}
+	return 1
+}
+
+// IsZero reports whether d is zero at any scale.
+func (d Decimal) IsZero() bool {
+	return d.hi|d.lo == 0
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	if !d.IsZero() {
+		d.neg = !d.neg
+	}
+	return d
+}
+
+// Abs returns |d|.
+func (d Decimal) Abs() Decimal {
+	d.neg = false
+	return d
+}
+
+// Cmp returns -1, 0 or 1 if d is less than, equal to or greater than e.
+func (d Decimal) Cmp(e Decimal) int {
+	ds, es := d.Sign(), e.Sign()
+	switch {
+	case ds < es:
+		return -1
+	case ds > es:
+		return 1
+	case ds == 0:
+		return 0
+	}
+	x, y, _ := align(d, e)
+	if d.neg {
+		return y.cmp(x)
+	}
+	return x.cmp(y)
+}
+
+// Add returns d+e at the larger of the two scales. If the sum needs more
+// than 38 digits the scale is reduced, rounding half to even; the result
+// is ErrDecimalOverflow only if the integer part does not fit.
+func (d Decimal) Add(e Decimal) (Decimal, error) {
+	x, y, scale := align(d, e)
+	var c wide
+	neg := d.neg
+	switch {
+	case d.neg == e.neg:
+		c = x.add(y)
+	case x.cmp(y) >= 0:
+		c = x.sub(y)
+	default:
+		c = y.sub(x)
+		neg = e.neg
+	}
+	return reduce(c, scale, neg, RoundHalfEven)
+}
+
+// Sub returns d-e with the same scale rules as Add.
+func (d Decimal) Sub(e Decimal) (Decimal, error) {
+	return d.Add(e.Neg())
+}
+
+// Mul returns d×e at the sum of the two scales. If the product needs more
+// than 38 digits the scale is reduced, rounding half to even; the result
+// is ErrDecimalOverflow only if the integer part does not fit.
+func (d Decimal) Mul(e Decimal) (Decimal, error) {
+	c := d.coef().mul(e.coef())
+	return reduce(c, int(d.scale)+int(e.scale), d.neg != e.neg, RoundHalfEven)
+}
+
+// Div returns d/e rounded to scale fractional digits with mode.
+func (d Decimal) Div(e Decimal, scale int, mode RoundingMode) (Decimal, error) {
+	if err := checkScale(scale); err != nil {
+		return Decimal{}, err
+	}
+	if e.IsZero() {
+		return Decimal{}, ErrDecimalDivideByZero
+	}
+	neg := d.neg != e.neg
+	n, den := d.coef(), e.coef()
+
+	// d/e × 10^scale = n × 10^k / den.
+	k := int(e.scale) + scale - int(d.scale)
+	var q, r wide
+	switch {
+	case k < 0:
+		den = den.mul(decPow10[-k])
+		q, r = quoRem(n, den)
+	case k <= MaxDecimalScale:
+		q, r = quoRem(n.mul(decPow10[k]), den)
+	default:
+		// n × 10^k may not fit in 256 bits; long divide the last digits.
+		q, r = quoRem(n.mul(decPow10[MaxDecimalScale]), den)
+		for k -= MaxDecimalScale; k > 0; k-- {
+			if !fits(q) {
+				return Decimal{}, ErrDecimalOverflow
+			}
+			q, _ = q.mul64(10)
+			r, _ = r.mul64(10)
+			var digit uint64
+			for r.cmp(den) >= 0 {
+				r = r.sub(den)
+				digit++
+			}
+			q = q.add(wide{digit})
+		}
+	}
+	q = roundRem(q, r, den, neg, mode)
+	if !fits(q) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return newDecimal(q, scale, neg), nil
+}
+
+// Round returns d with exactly scale fractional digits, rounding with mode
+// when digits are dropped. Raising the scale returns ErrDecimalOverflow if
+// the coefficient no longer fits.
+func (d Decimal) Round(scale int, mode RoundingMode) (Decimal, error) {
+	if err := checkScale(scale); err != nil {
+		return Decimal{}, err
+	}
+	c := d.coef()
+	switch k := scale - int(d.scale); {
+	case k > 0:
+		c = c.mul(decPow10[k])
+	case k < 0:
+		c = roundQuo(c, decPow10[-k], d.neg, mode)
+	}
+	if !fits(c) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return newDecimal(c, scale, d.neg), nil
+}
+
+// Rat sets z to d and returns z. If z is nil a new big.Rat is allocated.
+func (d Decimal) Rat(z *big.Rat) *big.Rat {
+	if z == nil {
+		z = new(big.Rat)
+	}
+	var num, den big.Int
+	d.coef().bigInt(&num)
+	if d.neg {
+		num.Neg(&num)
+	}
+	return z.SetFrac(&num, decPow10[d.scale].bigInt(&den))
+}
+
+// float64Pow10 holds the powers of ten exactly representable as float64.
+var float64Pow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11,
+	1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
+}
+
+// Float64 returns the float64 nearest to d.
+func (d Decimal) Float64() float64 {
+	if d.hi != 0 || d.lo >= 1<<53 || int(d.scale) >= len(float64Pow10) {
+		var buf [48]byte
+		f, _ := strconv.ParseFloat(string(d.append(buf[:0])), 64)
+		return f
+	}
+	// Both operands are exact, so the quotient is correctly rounded.
+	f := float64(d.lo) / float64Pow10[d.scale]
+	if d.neg {
+		f = -f
+	}
+	return f
+}
+
+// String returns d with exactly Scale fractional digits, such as "-12.50".
+func (d Decimal) String() string {
+	var buf [48]byte
+	return string(d.append(buf[:0]))
+}
+
+// AppendText implements encoding.TextAppender.
+func (d Decimal) AppendText(b []byte) ([]byte, error) {
+	return d.append(b), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return d.append(nil), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseDecimal rules.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := parseDecimal(text)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON encodes d as a JSON number with Scale fractional digits.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return d.append(nil), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a number.
+// A JSON null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if n := len(data); n >= 2 && data[0] == '"' && data[n-1] == '"' {
+		data = data[1 : n-1]
+	}
+	return d.UnmarshalText(data)
+}
+
+func (d Decimal) append(b []byte) []byte {
+	var buf [MaxDecimalScale]byte
+	i := len(buf)
+	for c := d.coef(); !c.isZero(); {
+		var r uint64
+		c, r = c.quo64(1e19)
+		for j := 0; j < 19 && (r != 0 || !c.isZero()); j++ {
+			i--
+			buf[i] = byte('0' + r%10)
+			r /= 10
+		}
+	}
+	digits := buf[i:]
+	scale := int(d.scale)
+
+	if d.neg {
+		b = append(b, '-')
+	}
+	switch {
+	case scale == 0 && len(digits) == 0:
+		return append(b, '0')
+	case len(digits) <= scale:
+		b = append(b, '0', '.')
+		for range scale - len(digits) {
+			b = append(b, '0')
+		}
+		return append(b, digits...)
+	}
+	n := len(digits) - scale
+	b = append(b, digits[:n]...)
+	if scale > 0 {
+		b = append(b, '.')
+		b = append(b, digits[n:]...)
+	}
+	return b
+}
+
+func checkScale(scale int) error {
+	if scale < 0 || scale > MaxDecimalScale {
+		return fmt.Errorf("decimal scale %d not between 0 and %d", scale, MaxDecimalScale)
+	}
+	return nil
+}
+
+func newDecimal(c wide, scale int, neg bool) Decimal {
+	return Decimal{hi: c[1], lo: c[0], scale: uint8(scale), neg: neg && !c.isZero()}
+}
+
+func (d Decimal) coef() wide {
+	return wide{d.lo, d.hi}
+}
+
+// fits reports whether c has at most 38 digits.
+func fits(c wide) bool {
+	return c.cmp(decPow10[MaxDecimalScale]) < 0
+}
+
+// align returns the coefficients of d and e at the larger of their scales.
+func align(d, e Decimal) (x, y wide, scale int) {
+	x, y = d.coef(), e.coef()
+	scale = int(max(d.scale, e.scale))
+	if k := scale - int(d.scale); k > 0 {
+		x = x.mul(decPow10[k])
+	}
+	if k := scale - int(e.scale); k > 0 {
+		y = y.mul(decPow10[k])
+	}
+	return x, y, scale
+}
+
+// reduce returns c × 10^-scale, dropping the fewest digits needed to fit in
+// 38 digits and scale 38. Scale must be at most 76.
+func reduce(c wide, scale int, neg bool, mode RoundingMode) (Decimal, error) {
+	drop := max(scale-MaxDecimalScale, 0)
+	for n := len(decPow10) - 1; n > MaxDecimalScale; n-- {
+		if c.cmp(decPow10[n-1]) >= 0 {
+			drop = max(drop, n-MaxDecimalScale)
+			break
+		}
+	}
+	// Rounding up may carry into one more digit.
+	for ; drop <= scale; drop++ {
+		q := c
+		if drop > 0 {
+			q = roundQuo(c, decPow10[drop], neg, mode)
+		}
+		if fits(q) {
+			return newDecimal(q, scale-drop, neg), nil
+		}
+	}
+	return Decimal{}, ErrDecimalOverflow
+}
+
+// exactDecimal returns c × 10^-scale without rounding.
+func exactDecimal(c wide, scale int, neg bool) (Decimal, error) {
+	switch {
+	case c.isZero():
+		scale = min(max(scale, 0), MaxDecimalScale)
+	case scale < 0:
+		if scale < -MaxDecimalScale || !fits(c) {
+			return Decimal{}, ErrDecimalOverflow
+		}
+		c = c.mul(decPow10[-scale])
+		scale = 0
+	case scale > MaxDecimalScale:
+		drop := scale - MaxDecimalScale
+		if drop >= len(decPow10) {
+			return Decimal{}, checkScale(scale)
+		}
+		q, r := quoRem(c, decPow10[drop])
+		if !r.isZero() {
+			return Decimal{}, checkScale(scale)
+		}
+		c, scale = q, MaxDecimalScale
+	}
+	if !fits(c) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return newDecimal(c, scale, neg), nil
+}
+
+// parseCoef parses a decimal number into its coefficient and scale. The
+// scale may be negative or over 38; the coefficient has at most 76 digits.
+func parseCoef[S string | []byte](s S) (c wide, scale int, neg bool, err error) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		neg = s[i] == '-'
+		i++
+	}
+	var digits, sig int
+	dot := false
+loop:
+	for ; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= '0' && ch <= '9':
+			digits++
+			if dot {
+				scale++
+			}
+			if sig == 0 && ch == '0' {
+				continue
+			}
+			sig++
+			if sig >= len(decPow10) {
+				return c, 0, false, ErrDecimalOverflow
+			}
+			c, _ = c.mul64(10)
+			c = c.add(wide{uint64(ch - '0')})
+		case ch == '.' && !dot:
+			dot = true
+		default:
+			break loop
+		}
+	}
+	if digits == 0 {
+		return c, 0, false, errDecimalSyntax
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		expNeg := false
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			expNeg = s[i] == '-'
+			i++
+		}
+		exp, expDigits := 0, 0
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			expDigits++
+			if exp < 10000 {
+				exp = exp*10 + int(s[i]-'0')
+			}
+		}
+		if expDigits == 0 {
+			return c, 0, false, errDecimalSyntax
+		}
+		if expNeg {
+			exp = -exp
+		}
+		scale -= exp
+	}
+	if i != len(s) {
+		return c, 0, false, errDecimalSyntax
+	}
+	return c, scale, neg, nil
+}
+
+// roundQuo returns n/d rounded with mode; neg is the sign of the result.
+func roundQuo(n, d wide, neg bool, mode RoundingMode) wide {
+	q, r := quoRem(n, d)
+	return roundRem(q, r, d, neg, mode)
+}
+
+// roundRem rounds the quotient q with remainder r of a division by d.
+func roundRem(q, r, d wide, neg bool, mode RoundingMode) wide {
+	if r.isZero() {
+		return q
+	}
+	// Compare r with d/2 as r against d-r, which cannot overflow.
+	if roundAway(mode, neg, r.cmp(d.sub(r)), q[0]&1 == 1) {
+		q = q.add(wide{1})
+	}
+	return q
+}
+
+// roundAway reports whether an inexact quotient is rounded away from zero.
+// half compares the remainder with half the divisor.
+func roundAway(mode RoundingMode, neg bool, half int, odd bool) bool {
+	switch mode {
+	case RoundHalfEven:
+		return half > 0 || half == 0 && odd
+	case RoundHalfDown:
+		return half > 0
+	case RoundUp:
+		return true
+	case RoundDown:
+		return false
+	case RoundCeiling:
+		return !neg
+	case RoundFloor:
+		return neg
+	}
+	return half >= 0
+}
+
+// wide is an unsigned 256-bit integer, least significant word first. It
+// holds Decimal coefficients and the intermediate results of arithmetic.
+type wide [4]uint64
+
+// decPow10 holds 10^0 through 10^76, the largest power of ten below 2^256.
+var decPow10 = func() (t [2*MaxDecimalScale + 1]wide) {
+	t[0] = wide{1}
+	for i := 1; i < len(t); i++ {
+		t[i], _ = t[i-1].mul64(10)
+	}
+	return t
+}()
+
+func (x wide) isZero() bool {
+	return x[0]|x[1]|x[2]|x[3] == 0
+}
+
+func (x wide) cmp(y wide) int {
+	for i := len(x) - 1; i >= 0; i-- {
+		switch {
+		case x[i] < y[i]:
+			return -1
+		case x[i] > y[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (x wide) add(y wide) (z wide) {
+	var carry uint64
+	for i := range z {
+		z[i], carry = bits.Add64(x[i], y[i], carry)
+	}
+	return z
+}
+
+// sub returns x-y; x must not be less than y.
+func (x wide) sub(y wide) (z wide) {
+	var borrow uint64
+	for i := range z {
+		z[i], borrow = bits.Sub64(x[i], y[i], borrow)
+	}
+	return z
+}
+
+// mul64 returns x×m and whether the product overflowed.
+func (x wide) mul64(m uint64) (z wide, overflow bool) {
+	var carry uint64
+	for i := range z {
+		hi, lo := bits.Mul64(x[i], m)
+		var c uint64
+		z[i], c = bits.Add64(lo, carry, 0)
+		carry = hi + c
+	}
+	return z, carry != 0
+}
+
+// mul returns x×y; both must be below 2^128.
+func (x wide) mul(y wide) (z wide) {
+	for i := range 2 {
+		var carry uint64
+		for j := range 2 {
+			hi, lo := bits.Mul64(x[i], y[j])
+			var c uint64
+			lo, c = bits.Add64(lo, z[i+j], 0)
+			hi += c
+			z[i+j], c = bits.Add64(lo, carry, 0)
+			carry = hi + c
+		}
+		z[i+2] = carry
+	}
+	return z
+}
+
+// quo64 returns x/d and x%d.
+func (x wide) quo64(d uint64) (q wide, r uint64) {
+	for i := len(x) - 1; i >= 0; i-- {
+		q[i], r = bits.Div64(r, x[i], d)
+	}
+	return q, r
+}
+
+func (x wide) bitLen() int {
+	for i := len(x) - 1; i >= 0; i-- {
+		if x[i] != 0 {
+			return i*64 + bits.Len64(x[i])
+		}
+	}
+	return 0
+}
+
+// quoRem returns n/d and n%d; d must be non-zero and below 2^255.
+func quoRem(n, d wide) (q, r wide) {
+	if d[1]|d[2]|d[3] == 0 {
+		q, r[0] = n.quo64(d[0])
+		return q, r
+	}
+	if n.cmp(d) < 0 {
+		return q, n
+	}
+	for i := n.bitLen() - 1; i >= 0; i-- {
+		r = wide{r[0] << 1, r[1]<<1 | r[0]>>63, r[2]<<1 | r[1]>>63, r[3]<<1 | r[2]>>63}
+		r[0] |= n[i/64] >> (i % 64) & 1
+		if r.cmp(d) >= 0 {
+			r = r.sub(d)
+			q[i/64] |= 1 << (i % 64)
+		}
+	}
+	return q, r
+}
+
+// bigInt sets z to x and returns z.
+func (x wide) bigInt(z *big.Int) *big.Int {
+	var b [32]byte
+	for i, w := range x {
+		for j := range 8 {
+			b[31-i*8-j] = byte(w >> (8 * j))
+		}
+	}
+	return z.SetBytes(b[:])
+}
+
+// wideFromBig returns |n|, which must fit in 256 bits.
+func wideFromBig(n *big.Int) (x wide) {
+	var b [32]byte
+	n.FillBytes(b[:])
+	for i := range x {
+		for j := range 8 {
+			x[i] |= uint64(b[31-i*8-j]) << (8 * j)
+		}
+	}
+	return x
+}
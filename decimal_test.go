@@ -0,0 +1,397 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func mustDecimal(t *testing.T, s string) Decimal {
+	t.Helper()
+	d, err := ParseDecimal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	list := []struct {
+		In    string
+		Want  string
+		Scale int
+	}{
+		{"0", "0", 0},
+		{"-0.00", "0.00", 2},
+		{"12.50", "12.50", 2},
+		{"-12.5", "-12.5", 1},
+		{"+.5", "0.5", 1},
+		{"7.", "7", 0},
+		{"000123", "123", 0},
+		{"1.25e3", "1250", 0},
+		{"1.25e1", "12.5", 1},
+		{"-125E-4", "-0.0125", 4},
+		{"99999999999999999999999999999999999999", "99999999999999999999999999999999999999", 0},
+		{"0.00000000000000000000000000000000000001", "0.00000000000000000000000000000000000001", 38},
+		{"0.100000000000000000000000000000000000000000", "0.10000000000000000000000000000000000000", 38},
+		{"0e-50", "0.00000000000000000000000000000000000000", 38},
+		{"0e50", "0", 0},
+	}
+	for _, item := range list {
+		d, err := ParseDecimal(item.In)
+		if err != nil {
+			t.Errorf("%s: %v", item.In, err)
+			continue
+		}
+		if got := d.String(); got != item.Want || d.Scale() != item.Scale {
+			t.Errorf("%s: got %s scale %d, want %s scale %d", item.In, got, d.Scale(), item.Want, item.Scale)
+		}
+	}
+
+	for _, bad := range []string{"", "-", ".", "1..2", "1.2.3", "abc", "1e", "1e+", "1 ", "0x10"} {
+		if _, err := ParseDecimal(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+	for _, over := range []string{"100000000000000000000000000000000000000", "1e38", "1e100", "1.00000000000000000000000000000000000000"} {
+		if _, err := ParseDecimal(over); !errors.Is(err, ErrDecimalOverflow) {
+			t.Errorf("%s: got %v, want ErrDecimalOverflow", over, err)
+		}
+	}
+	if _, err := ParseDecimal("1.000000000000000000000000000000000000001"); err == nil {
+		t.Error("expected error for scale over 38")
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	list := []struct {
+		Op   string
+		A, B string
+		Want string
+	}{
+		{"+", "1.5", "2.25", "3.75"},
+		{"+", "-1.5", "2.25", "0.75"},
+		{"+", "1.5", "-2.25", "-0.75"},
+		{"+", "-1.50", "1.5", "0.00"},
+		{"-", "1", "0.001", "0.999"},
+		{"-", "-1", "1", "-2"},
+		{"*", "1.5", "-2.25", "-3.375"},
+		{"*", "0.1", "0.1", "0.01"},
+		{"*", "-0", "5", "0"},
+		// The exact results need more than 38 digits, so the scale drops.
+		{"+", "9999999999999999999999999999999999999.9", "0.05", "10000000000000000000000000000000000000"},
+		{"+", "1234567890123456789012345678901234567.8", "0.05", "1234567890123456789012345678901234567.8"},
+		{"*", "12345678901234567890.123456789", "1.0000000000000000001", "12345678901234567891.358024679123456789"},
+		{"*", "0.00000000000000000001", "0.00000000000000000001", "0.00000000000000000000000000000000000000"},
+	}
+	for _, item := range list {
+		a, b := mustDecimal(t, item.A), mustDecimal(t, item.B)
+		var got Decimal
+		var err error
+		switch item.Op {
+		case "+":
+			got, err = a.Add(b)
+		case "-":
+			got, err = a.Sub(b)
+		case "*":
+			got, err = a.Mul(b)
+		}
+		if err != nil {
+			t.Errorf("%s %s %s: %v", item.A, item.Op, item.B, err)
+			continue
+		}
+		if got.String() != item.Want {
+			t.Errorf("%s %s %s: got %s, want %s", item.A, item.Op, item.B, got, item.Want)
+		}
+	}
+
+	max := mustDecimal(t, "99999999999999999999999999999999999999")
+	if _, err := max.Add(NewDecimal(1, 0)); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("add: got %v, want ErrDecimalOverflow", err)
+	}
+	if _, err := max.Mul(NewDecimal(2, 0)); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("mul: got %v, want ErrDecimalOverflow", err)
+	}
+}
+
+func TestDecimalDiv(t *testing.T) {
+	list := []struct {
+		A, B  string
+		Scale int
+		Mode  RoundingMode
+		Want  string
+	}{
+		{"10", "4", 2, RoundHalfUp, "2.50"},
+		{"1", "3", 4, RoundHalfUp, "0.3333"},
+		{"2", "3", 4, RoundHalfUp, "0.6667"},
+		{"2", "3", 4, RoundDown, "0.6666"},
+		{"-2", "3", 4, RoundFloor, "-0.6667"},
+		{"-2", "3", 4, RoundCeiling, "-0.6666"},
+		{"1", "8", 2, RoundHalfEven, "0.12"},
+		{"3", "8", 2, RoundHalfEven, "0.38"},
+		{"1.000", "0.5", 0, RoundHalfUp, "2"},
+		{"123.456", "1000", 1, RoundHalfUp, "0.1"},
+		{"1", "0.00000000000000000000000000000000000003", 0, RoundHalfUp, "33333333333333333333333333333333333333"},
+		{"0.00000000000000000000000000000000000001", "3", 38, RoundUp, "0.00000000000000000000000000000000000001"},
+		{"1", "7", 38, RoundHalfUp, "0.14285714285714285714285714285714285714"},
+		{"-1", "0.00000000000000000000000000000000000007", 38, RoundHalfUp, ""},
+	}
+	for _, item := range list {
+		got, err := mustDecimal(t, item.A).Div(mustDecimal(t, item.B), item.Scale, item.Mode)
+		if item.Want == "" {
+			if !errors.Is(err, ErrDecimalOverflow) {
+				t.Errorf("%s / %s: got %v, want ErrDecimalOverflow", item.A, item.B, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s / %s: %v", item.A, item.B, err)
+			continue
+		}
+		if got.String() != item.Want {
+			t.Errorf("%s / %s: got %s, want %s", item.A, item.B, got, item.Want)
+		}
+	}
+	if _, err := NewDecimal(1, 0).Div(Decimal{}, 2, RoundHalfUp); err != ErrDecimalDivideByZero {
+		t.Errorf("got %v, want ErrDecimalDivideByZero", err)
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	modes := []RoundingMode{RoundHalfUp, RoundHalfEven, RoundHalfDown, RoundUp, RoundDown, RoundCeiling, RoundFloor}
+	list := []struct {
+		In   string
+		Want [7]string // Indexed like modes.
+	}{
+		{"2.5", [7]string{"3", "2", "2", "3", "2", "3", "2"}},
+		{"3.5", [7]string{"4", "4", "3", "4", "3", "4", "3"}},
+		{"-2.5", [7]string{"-3", "-2", "-2", "-3", "-2", "-2", "-3"}},
+		{"2.51", [7]string{"3", "3", "3", "3", "2", "3", "2"}},
+		{"-2.49", [7]string{"-2", "-2", "-2", "-3", "-2", "-2", "-3"}},
+		{"2.0", [7]string{"2", "2", "2", "2", "2", "2", "2"}},
+		{"-0.4", [7]string{"0", "0", "0", "-1", "0", "0", "-1"}},
+	}
+	for _, item := range list {
+		d := mustDecimal(t, item.In)
+		for i, mode := range modes {
+			got, err := d.Round(0, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != item.Want[i] {
+				t.Errorf("%s mode %d: got %s, want %s", item.In, mode, got, item.Want[i])
+			}
+		}
+	}
+
+	got, err := NewDecimal(5, 1).Round(3, RoundHalfUp)
+	if err != nil || got.String() != "0.500" {
+		t.Errorf("raise scale: got %s, %v", got, err)
+	}
+	if _, err := mustDecimal(t, "1234567890123456789012345678901234567").Round(2, RoundHalfUp); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("got %v, want ErrDecimalOverflow", err)
+	}
+	if _, err := got.Round(39, RoundHalfUp); err == nil {
+		t.Error("expected error for scale 39")
+	}
+}
+
+func TestDecimalCmp(t *testing.T) {
+	ordered := []string{"-100", "-1.5", "-1.49", "-0.001", "0", "0.0001", "1.5", "1.50001", "12", "99999999999999999999999999999999999999"}
+	for i, a := range ordered {
+		for j, b := range ordered {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got := mustDecimal(t, a).Cmp(mustDecimal(t, b)); got != want {
+				t.Errorf("Cmp(%s, %s) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+	if mustDecimal(t, "1.5").Cmp(mustDecimal(t, "1.50")) != 0 {
+		t.Error("1.5 and 1.50 should compare equal")
+	}
+	if d := NewDecimal(-3, 1); d.Sign() != -1 || d.Neg().Sign() != 1 || d.Abs().String() != "0.3" || (Decimal{}).Sign() != 0 {
+		t.Errorf("sign of %s", d)
+	}
+}
+
+func TestDecimalConvert(t *testing.T) {
+	d := NewDecimal(math.MinInt64, 4)
+	if got, want := d.String(), "-922337203685477.5808"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	neg, hi, lo := d.Uint128()
+	back, err := DecimalFromUint128(neg, hi, lo, d.Scale())
+	if err != nil || back != d {
+		t.Errorf("Uint128 round trip: got %s, %v", back, err)
+	}
+	if _, err := DecimalFromUint128(false, math.MaxUint64, math.MaxUint64, 0); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("got %v, want ErrDecimalOverflow", err)
+	}
+
+	r := mustDecimal(t, "-1234.5678").Rat(nil)
+	if r.Cmp(big.NewRat(-12345678, 10000)) != 0 {
+		t.Errorf("Rat: got %s", r)
+	}
+	for _, item := range []struct {
+		R     *big.Rat
+		Scale int
+		Mode  RoundingMode
+		Want  string
+	}{
+		{big.NewRat(1, 3), 4, RoundHalfUp, "0.3333"},
+		{big.NewRat(-5, 8), 2, RoundHalfEven, "-0.62"},
+		{big.NewRat(-5, 8), 2, RoundHalfUp, "-0.63"},
+		{big.NewRat(7, 1), 2, RoundHalfUp, "7.00"},
+	} {
+		got, err := DecimalFromRat(item.R, item.Scale, item.Mode)
+		if err != nil || got.String() != item.Want {
+			t.Errorf("DecimalFromRat(%s): got %s, %v, want %s", item.R, got, err, item.Want)
+		}
+	}
+	if _, err := DecimalFromRat(big.NewRat(1e18, 1), 30, RoundHalfUp); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("got %v, want ErrDecimalOverflow", err)
+	}
+
+	for _, item := range []struct {
+		F    float64
+		Want string
+	}{
+		{0, "0"},
+		{0.1, "0.1"},
+		{-2.5, "-2.5"},
+		{1e20, "100000000000000000000"},
+		{123.456, "123.456"},
+		{1e-40, "0.00000000000000000000000000000000000000"},
+		{6e-39, "0.00000000000000000000000000000000000001"},
+		{5e-324, "0.00000000000000000000000000000000000000"},
+	} {
+		got, err := DecimalFromFloat64(item.F)
+		if err != nil || got.String() != item.Want {
+			t.Errorf("DecimalFromFloat64(%g): got %s, %v, want %s", item.F, got, err, item.Want)
+		}
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), 1e40} {
+		if _, err := DecimalFromFloat64(f); err == nil {
+			t.Errorf("DecimalFromFloat64(%g): expected error", f)
+		}
+	}
+
+	for _, item := range []struct {
+		In   string
+		Want float64
+	}{
+		{"12.50", 12.5},
+		{"-0.1", -0.1},
+		{"0.30000000000000000000000000000000000000", 0.3},
+		{"123456789012345678901234567890", 123456789012345678901234567890},
+	} {
+		if got := mustDecimal(t, item.In).Float64(); got != item.Want {
+			t.Errorf("Float64(%s) = %v, want %v", item.In, got, item.Want)
+		}
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	type row struct {
+		Amount Decimal
+		Fee    *Decimal
+		Tax    Opt[Decimal]
+	}
+	in := row{Amount: NewDecimal(-1250, 2)}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"Amount":-12.50,"Fee":null,"Tax":{"V":0,"Valid":false}}`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	var out row
+	err = json.Unmarshal([]byte(`{"Amount":"3.10","Fee":1e2,"Tax":{"V":0.07,"Valid":true}}`), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Amount.String() != "3.10" || out.Fee == nil || out.Fee.String() != "100" || out.Tax.V.String() != "0.07" {
+		t.Errorf("got %+v", out)
+	}
+	if err := json.Unmarshal([]byte(`{"Amount":"x"}`), &out); err == nil {
+		t.Error("expected error")
+	}
+
+	text, err := NewDecimal(5, 3).MarshalText()
+	if err != nil || string(text) != "0.005" {
+		t.Errorf("text: %s, %v", text, err)
+	}
+}
+
+func TestDecimalAllocs(t *testing.T) {
+	a, b := mustDecimal(t, "12345.6789"), mustDecimal(t, "-0.0001")
+	buf := make([]byte, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		c, _ := a.Mul(b)
+		c, _ = c.Add(a)
+		c, _ = c.Div(b, 10, RoundHalfEven)
+		c, _ = c.Round(2, RoundHalfUp)
+		buf, _ = c.AppendText(buf[:0])
+		_ = c.Float64()
+		_, _ = DecimalFromUint128(false, 0, 42, 2)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs, want 0", allocs)
+	}
+}
+
+func TestDirectAssignDecimal(t *testing.T) {
+	d := NewDecimal(-1250, 2)
+
+	var dec Decimal
+	if handled, err := DirectAssignDecimal(&dec, d, false, nil); !handled || err != nil || dec != d {
+		t.Fatalf("Decimal: handled=%v err=%v val=%s", handled, err, dec)
+	}
+	var opt Opt[Decimal]
+	if handled, err := DirectAssignDecimal(&opt, d, false, nil); !handled || err != nil || !opt.Valid || opt.V != d {
+		t.Fatalf("Opt: handled=%v err=%v val=%+v", handled, err, opt)
+	}
+	if handled, err := DirectAssignDecimal(&opt, Decimal{}, true, nil); !handled || err != nil || opt.Valid {
+		t.Fatalf("Opt null: handled=%v err=%v val=%+v", handled, err, opt)
+	}
+	var r *big.Rat
+	if handled, err := DirectAssignDecimal(&r, d, false, nil); !handled || err != nil || r.FloatString(2) != "-12.50" {
+		t.Fatalf("*big.Rat: handled=%v err=%v val=%v", handled, err, r)
+	}
+	var f float64
+	if handled, err := DirectAssignDecimal(&f, d, false, nil); !handled || err != nil || f != -12.5 {
+		t.Fatalf("float64: handled=%v err=%v val=%v", handled, err, f)
+	}
+	var s string
+	if handled, err := DirectAssignDecimal(&s, d, false, nil); !handled || err != nil || s != "-12.50" {
+		t.Fatalf("string: handled=%v err=%v val=%q", handled, err, s)
+	}
+	if handled, err := DirectAssignDecimal(&dec, Decimal{}, true, nil); !handled || err != ErrScanNull {
+		t.Fatalf("null into *Decimal: handled=%v err=%v", handled, err)
+	}
+
+	var n Nullable
+	if err := AssignValue(nil, Nullable{Value: d}, &n, nil); err != nil || n.Value != d {
+		t.Fatalf("Nullable: err=%v val=%#v", err, n)
+	}
+	var rat big.Rat
+	if err := AssignValue(nil, Nullable{Value: d}, &rat, nil); err != nil || rat.FloatString(2) != "-12.50" {
+		t.Fatalf("big.Rat: err=%v val=%v", err, rat.FloatString(2))
+	}
+	if err := AssignValue(&Column{Name: "c"}, Nullable{Value: d}, new(int), nil); err == nil {
+		t.Fatal("expected error assigning Decimal to *int")
+	}
+}
@@ -46,6 +46,15 @@ type DriverSupport struct {
 type DriverInfo struct {
 	Options []*DriverOption
 	DriverSupport
+
+	// InferParamType, if set, replaces InferParamType to set the type of
+	// parameters sent without one.
+	InferParamType func(param *Param) error
+
+	// ListSQL, if set, returns SQL that selects the values of a list
+	// parameter named name, sent as a JSON array. It is used for lists longer
+	// than Config.ListParamMax. The elem has the type of each value.
+	ListSQL func(name string, elem *Param) string
 }
 
 type ConnectionInfo struct {
@@ -77,6 +86,11 @@ type DriverValue struct {
 	MustCopy bool // If the Value is a common driver buffer, set to true.
 	More     bool // True if more data is expected for the field.
 	Chunked  bool // True if data is sent in chunks.
+
+	// Stream is set if Value is an io.Reader of the field value for a
+	// *io.Reader Prep destination. The rest of the row is not read until
+	// DriverRowFinisher.FinishRow is called.
+	Stream bool
 }
 
 // Conn represents a database driver connection.
@@ -35,6 +35,11 @@ type Errors []*Message
 
 var ErrClosed = errors.New("closed result")
 
+// ErrStreamClosed is returned when reading a column stream after the result
+// moved past the column. Streamed columns must be read before any later
+// column in the row.
+var ErrStreamClosed = errors.New("column stream closed, columns must be read in order")
+
 func (errs Errors) Error() string {
 	bb := &bytes.Buffer{}
 	if errs == nil {
@@ -49,6 +54,26 @@ func (errs Errors) Error() string {
 	return bb.String()
 }
 
+// Unwrap returns the classified error of each message, if any.
+// This allows using errors.Is and errors.As on Errors:
+//
+//	if errors.Is(err, rdb.ErrUniqueViolation) { ... }
+//
+//	var se *rdb.ServerError
+//	if errors.As(err, &se) {
+//		fmt.Println(se.Constraint)
+//	}
+func (errs Errors) Unwrap() []error {
+	var list []error
+	for _, msg := range errs {
+		if msg == nil || msg.Err == nil {
+			continue
+		}
+		list = append(list, msg.Err)
+	}
+	return list
+}
+
 type MessageType byte
 
 const (
@@ -81,6 +106,10 @@ type Message struct {
 	Number     int32
 	State      byte
 	Class      byte
+
+	// Err is the classified error, set by the driver if the message
+	// number is recognized. Usually a *ServerError.
+	Err error
 }
 
 func (err *Message) String() string {
@@ -96,6 +125,62 @@ func (dr DriverNotFound) Error() string {
 	return fmt.Sprintf("Driver name not found: %s", dr.name)
 }
 
+// Classes of common server errors. Drivers map server messages to these
+// through a *ServerError set on Message.Err.
+var (
+	ErrUniqueViolation = errors.New("unique constraint violation")
+	ErrForeignKey      = errors.New("foreign key constraint violation")
+	ErrDeadlock        = errors.New("deadlock")
+	ErrLoginFailed     = errors.New("login failed")
+	ErrPermission      = errors.New("permission denied")
+	ErrUnavailable     = errors.New("database not available")
+)
+
+// ServerError is a classified server message.
+// Use errors.As on Errors to retrieve it.
+type ServerError struct {
+	// Kind is one of the error classes, such as ErrUniqueViolation.
+	Kind error
+
+	// Constraint or index name, if reported.
+	Constraint string
+
+	// Object, table, database or login name, if reported.
+	Object string
+
+	Msg *Message
+}
+
+func (err *ServerError) Error() string {
+	switch {
+	case len(err.Constraint) > 0 && len(err.Object) > 0:
+		return fmt.Sprintf("%v: %s on %s", err.Kind, err.Constraint, err.Object)
+	case len(err.Constraint) > 0:
+		return fmt.Sprintf("%v: %s", err.Kind, err.Constraint)
+	case len(err.Object) > 0:
+		return fmt.Sprintf("%v: %s", err.Kind, err.Object)
+	}
+	return err.Kind.Error()
+}
+
+func (err *ServerError) Unwrap() error {
+	return err.Kind
+}
+
+// connectError wraps an error opening a connection to the server,
+// such as a dial, TLS, or login failure.
+type connectError struct {
+	err error
+}
+
+func (err *connectError) Error() string {
+	return err.err.Error()
+}
+
+func (err *connectError) Unwrap() error {
+	return err.err
+}
+
 var ErrArity = errors.New("result row count does not match desired arity")
 
 var ErrCancel = errors.New("Query Cancelled")
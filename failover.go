@@ -0,0 +1,175 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostAddr is a server a pool may connect to.
+type hostAddr struct {
+	Hostname string
+	Port     int
+	Instance string
+}
+
+func (h hostAddr) String() string {
+	s := h.Hostname
+	if h.Port != 0 {
+		s = net.JoinHostPort(s, strconv.Itoa(h.Port))
+	}
+	if len(h.Instance) > 0 {
+		s += "/" + h.Instance
+	}
+	return s
+}
+
+// parseHostAddr parses "host[:port][/instance]".
+func parseHostAddr(s string) (hostAddr, error) {
+	var h hostAddr
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		h.Instance = s[i+1:]
+		s = s[:i]
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		// No port.
+		h.Hostname = s
+	} else {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return h, fmt.Errorf("invalid port in %q: %w", s, err)
+		}
+		h.Hostname = host
+		h.Port = int(p)
+	}
+	if len(h.Hostname) == 0 {
+		return h, fmt.Errorf("missing host name")
+	}
+	return h, nil
+}
+
+// failover tracks which of the configured servers new connections are
+// opened to. The first server is Config.Hostname, followed by
+// Config.FailoverPartners.
+type failover struct {
+	hosts    []hostAddr
+	interval time.Duration
+
+	mu        sync.Mutex
+	active    int
+	nextProbe time.Time
+}
+
+func newFailover(conf *Config) (*failover, error) {
+	if len(conf.FailoverPartners) == 0 {
+		return nil, nil
+	}
+	f := &failover{
+		hosts:    []hostAddr{{Hostname: conf.Hostname, Port: conf.Port, Instance: conf.Instance}},
+		interval: conf.FailbackInterval,
+	}
+	if f.interval <= 0 {
+		f.interval = 30 * time.Second
+	}
+	for _, p := range conf.FailoverPartners {
+		h, err := parseHostAddr(p)
+		if err != nil {
+			return nil, fmt.Errorf("failover partner %q: %w", p, err)
+		}
+		f.hosts = append(f.hosts, h)
+	}
+	return f, nil
+}
+
+// order returns the index of the servers to try, in order.
+// Once failed over, the primary server is probed again each interval.
+func (f *failover) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	list := make([]int, 0, len(f.hosts))
+	if f.active != 0 && !time.Now().Before(f.nextProbe) {
+		list = append(list, 0)
+		f.nextProbe = time.Now().Add(f.interval)
+	}
+	for i := range f.hosts {
+		index := (f.active + i) % len(f.hosts)
+		if index == 0 && len(list) > 0 && list[0] == 0 {
+			continue
+		}
+		list = append(list, index)
+	}
+	return list
+}
+
+func (f *failover) setActive(index int) {
+	f.mu.Lock()
+	if f.active != index {
+		f.active = index
+		f.nextProbe = time.Now().Add(f.interval)
+	}
+	f.mu.Unlock()
+}
+
+// shouldFailover returns true if err means the server cannot take
+// connections, rather than the request itself being refused.
+// A dial timeout matches context.DeadlineExceeded, so whether the caller
+// gave up is decided by the caller ctx, not by err.
+func shouldFailover(err error) bool {
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, ErrLoginFailed) {
+		return true
+	}
+	var errs Errors
+	return !errors.As(err, &errs)
+}
+
+// openHost opens a new driver connection with config, trying each failover
+// partner in turn.
+func (cp *ConnPool) openHost(ctx context.Context, config *Config) (DriverConn, error) {
+	f := cp.failover
+	if f == nil {
+		return cp.dr.Open(ctx, config)
+	}
+	var firstErr error
+	for _, index := range f.order() {
+		h := f.hosts[index]
+		conf := *config
+		conf.Hostname, conf.Port, conf.Instance = h.Hostname, h.Port, h.Instance
+
+		conn, err := cp.dr.Open(ctx, &conf)
+		if err == nil {
+			f.setActive(index)
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", h, err)
+		}
+		if !shouldFailover(err) || ctx.Err() != nil {
+			return conn, err
+		}
+	}
+	return nil, firstErr
+}
+
+// ActiveHost returns the server new connections are opened to.
+// It differs from Config.Hostname after a failover to a partner.
+func (cp *ConnPool) ActiveHost() string {
+	f := cp.failover
+	if f == nil {
+		return hostAddr{Hostname: cp.conf.Hostname, Port: cp.conf.Port, Instance: cp.conf.Instance}.String()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hosts[f.active].String()
+}
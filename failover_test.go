@@ -0,0 +1,195 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hostDriver fails to open connections to the hosts in down.
+type hostDriver struct {
+	dummyDriver
+
+	mu     sync.Mutex
+	down   map[string]error
+	opened []string
+}
+
+func (d *hostDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opened = append(d.opened, c.Hostname)
+	if err := d.down[c.Hostname]; err != nil {
+		return nil, err
+	}
+	return &dummyConn{opened: time.Now(), status: StatusReady}, nil
+}
+
+func (d *hostDriver) set(host string, err error) {
+	d.mu.Lock()
+	d.down[host] = err
+	d.mu.Unlock()
+}
+
+func (d *hostDriver) takeOpened() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	list := d.opened
+	d.opened = nil
+	return list
+}
+
+func TestParseHostAddr(t *testing.T) {
+	list := []struct {
+		In   string
+		Want hostAddr
+	}{
+		{"mirror", hostAddr{Hostname: "mirror"}},
+		{"mirror:1433", hostAddr{Hostname: "mirror", Port: 1433}},
+		{"mirror/SqlExpress", hostAddr{Hostname: "mirror", Instance: "SqlExpress"}},
+		{" mirror:1500/SqlExpress ", hostAddr{Hostname: "mirror", Port: 1500, Instance: "SqlExpress"}},
+	}
+	for _, item := range list {
+		got, err := parseHostAddr(item.In)
+		if err != nil {
+			t.Errorf("%q: %v", item.In, err)
+			continue
+		}
+		if got != item.Want {
+			t.Errorf("%q: got %+v, want %+v", item.In, got, item.Want)
+		}
+	}
+	for _, in := range []string{"", ":1433", "mirror:port"} {
+		if _, err := parseHostAddr(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	d := &hostDriver{down: map[string]error{}}
+	Register("failover", d)
+	mirrorErr := Errors{{Type: SqlError, Number: 954, Err: &ServerError{Kind: ErrUnavailable}}}
+	d.set("primary", mirrorErr)
+
+	pool, err := Open(&Config{
+		DriverName:       "failover",
+		Hostname:         "primary",
+		FailoverPartners: []string{"partner"},
+		FailbackInterval: 20 * time.Millisecond,
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	if _, err := pool.Query(ctx, &Command{Arity: Zero}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pool.ActiveHost(); got != "partner" {
+		t.Fatalf("active host %q, want partner", got)
+	}
+	if got := d.takeOpened(); !equalCalls(got, []string{"primary", "partner"}) {
+		t.Fatalf("opened %q", got)
+	}
+
+	// New connections go straight to the partner until the probe interval.
+	pool.Drain()
+	if got := d.takeOpened(); !equalCalls(got, []string{"partner"}) {
+		t.Fatalf("opened %q, want the partner only", got)
+	}
+
+	// Fail back once the primary is available.
+	d.set("primary", nil)
+	time.Sleep(30 * time.Millisecond)
+	pool.Drain()
+	if got := pool.ActiveHost(); got != "primary" {
+		t.Fatalf("active host %q, want primary", got)
+	}
+	if got := d.takeOpened(); !equalCalls(got, []string{"primary"}) {
+		t.Fatalf("opened %q, want the primary", got)
+	}
+}
+
+func TestFailoverServerError(t *testing.T) {
+	d := &hostDriver{down: map[string]error{}}
+	Register("failover_server_error", d)
+	d.set("primary", Errors{{Type: SqlError, Number: 208}})
+
+	pool, err := Open(&Config{
+		DriverName:       "failover_server_error",
+		Hostname:         "primary",
+		FailoverPartners: []string{"partner"},
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	_, err = pool.Query(context.Background(), &Command{Arity: Zero})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want the server error", err)
+	}
+	if got := d.takeOpened(); !equalCalls(got, []string{"primary"}) {
+		t.Fatalf("opened %q, want the primary only", got)
+	}
+}
+
+// blackholeAddr returns an address a TCP dial times out on and the dial
+// error, skipping the test if no address times out in this environment.
+func blackholeAddr(t *testing.T) (string, error) {
+	t.Helper()
+	d := net.Dialer{Timeout: 20 * time.Millisecond}
+	for _, addr := range []string{"10.255.255.1:1433", "[100::1]:1433"} {
+		conn, err := d.Dial("tcp", addr)
+		if conn != nil {
+			conn.Close()
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return addr, err
+		}
+	}
+	t.Skip("no blackhole address times out")
+	return "", nil
+}
+
+// blackholeDialErr returns the error of a TCP dial that times out.
+func blackholeDialErr(t *testing.T) error {
+	t.Helper()
+	_, err := blackholeAddr(t)
+	return err
+}
+
+func TestFailoverDialTimeout(t *testing.T) {
+	d := &hostDriver{down: map[string]error{}}
+	Register("failoverdialtimeout", d)
+	d.set("primary", blackholeDialErr(t))
+
+	pool, err := Open(&Config{
+		DriverName:       "failoverdialtimeout",
+		Hostname:         "primary",
+		FailoverPartners: []string{"partner"},
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Query(context.Background(), &Command{Arity: Zero}); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.takeOpened(); len(got) == 0 || got[len(got)-1] != "partner" {
+		t.Fatalf("opened %q, want the partner after the primary timed out", got)
+	}
+}
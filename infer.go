@@ -0,0 +1,283 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+	"unicode/utf16"
+)
+
+// Default lengths of inferred variable length parameters. Values up to these
+// lengths share one parameter length so the server can reuse query plans.
+// Longer values use a max length type.
+const (
+	InferTextLength   = 4000
+	InferBinaryLength = 8000
+)
+
+// InferParamType sets the Type of param from its Value if the Type is
+// TypeUnknown. The Length, Precision, and Scale are also set if not already
+// set. Optional values, such as Opt[T] and pointers, are unwrapped and
+// the Null field is set if they are null.
+//
+// Parameters are inferred before they are sent to the driver. A driver may
+// replace this with DriverInfo.InferParamType.
+func InferParamType(param *Param) error {
+	if param.Type != TypeUnknown {
+		return nil
+	}
+	value := param.Value
+	switch value.(type) {
+	case nil, NullType:
+		param.Type = TypeVarChar
+		param.Null = true
+		if param.Length == 0 {
+			param.Length = 1
+		}
+		return nil
+	case int64, int:
+		param.Type = TypeInt64
+		return nil
+	case int32:
+		param.Type = TypeInt32
+		return nil
+	case bool:
+		param.Type = TypeBool
+		return nil
+	case float64:
+		param.Type = TypeFloat64
+		return nil
+	case time.Time:
+		param.Type = TypeTimestampz
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for {
+		switch {
+		case rv.Kind() == reflect.Pointer:
+			if rv.IsNil() {
+				param.Null = true
+				param.Value = nil
+				return inferNull(param, rv.Type().Elem())
+			}
+			// Keep pointers to big.Rat, the drivers expect them.
+			if _, ok := rv.Interface().(*big.Rat); ok {
+				return inferValue(param, rv)
+			}
+			rv = rv.Elem()
+			param.Value = rv.Interface()
+			continue
+		case isOpt(rv.Type()):
+			if !rv.FieldByName("Valid").Bool() {
+				param.Null = true
+				param.Value = nil
+				return inferNull(param, rv.FieldByName("V").Type())
+			}
+			rv = rv.FieldByName("V")
+			param.Value = rv.Interface()
+			continue
+		}
+		break
+	}
+	return inferValue(param, rv)
+}
+
+var (
+	typeTime       = reflect.TypeFor[time.Time]()
+	typeDuration   = reflect.TypeFor[time.Duration]()
+	typeRat        = reflect.TypeFor[*big.Rat]()
+	typeDec        = reflect.TypeFor[Decimal]()
+	typeRawMessage = reflect.TypeFor[json.RawMessage]()
+)
+
+// isOpt returns true for Opt[T] and other structs with the same fields.
+func isOpt(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return false
+	}
+	v, okV := t.FieldByName("V")
+	valid, okValid := t.FieldByName("Valid")
+	return okV && okValid && v.Index[0] == 0 && valid.Type.Kind() == reflect.Bool
+}
+
+// inferValue sets the type from a non-null value, using the value to
+// size variable length types.
+func inferValue(param *Param, rv reflect.Value) error {
+	err := inferReflectType(param, rv.Type())
+	if err != nil {
+		return err
+	}
+	switch param.Type {
+	case TypeVarChar:
+		if param.Length == 0 && rv.Kind() == reflect.String {
+			if n := utf16Len(rv.String()); n <= InferTextLength {
+				param.Length = InferTextLength
+			}
+		}
+	case TypeBinary:
+		if param.Length == 0 && rv.Len() <= InferBinaryLength {
+			param.Length = InferBinaryLength
+		}
+	case TypeInt64:
+		switch rv.Kind() {
+		case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+			if rv.Uint() > math.MaxInt64 {
+				return fmt.Errorf("param %q value %d overflows int64", param.Name, rv.Uint())
+			}
+		}
+	case TypeDecimal:
+		if param.Scale != 0 {
+			break
+		}
+		switch v := rv.Interface().(type) {
+		case *big.Rat:
+			if v == nil {
+				break
+			}
+			if digits, exact := v.FloatPrec(); exact && digits <= 38 {
+				param.Scale = digits
+			} else {
+				param.Scale = 18
+			}
+		case Decimal:
+			param.Scale = v.Scale()
+		}
+	}
+	return nil
+}
+
+// inferNull sets the type of a null value from the Go type.
+func inferNull(param *Param, t reflect.Type) error {
+	err := inferReflectType(param, t)
+	if err != nil {
+		return err
+	}
+	if param.Length == 0 {
+		switch param.Type {
+		case TypeVarChar:
+			param.Length = InferTextLength
+		case TypeBinary:
+			param.Length = InferBinaryLength
+		}
+	}
+	return nil
+}
+
+// inferReflectType sets the type from the Go type alone.
+func inferReflectType(param *Param, t reflect.Type) error {
+	set := func(typ Type) {
+		param.Type = typ
+	}
+	switch t {
+	case typeTime:
+		set(TypeTimestampz)
+		return nil
+	case typeDuration:
+		set(TypeTime)
+		return nil
+	case typeRat, typeDec:
+		set(TypeDecimal)
+		if param.Precision == 0 {
+			param.Precision = 38
+		}
+		return nil
+	case typeRawMessage:
+		set(TypeJSON)
+		return nil
+	}
+	switch t.Kind() {
+	default:
+		return fmt.Errorf("param %q: unable to infer type from %v", param.Name, t)
+	case reflect.Pointer:
+		return inferReflectType(param, t.Elem())
+	case reflect.Bool:
+		set(TypeBool)
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		set(TypeInt16)
+	case reflect.Int32, reflect.Uint16:
+		set(TypeInt32)
+	case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		set(TypeInt64)
+	case reflect.Float32:
+		set(TypeFloat32)
+	case reflect.Float64:
+		set(TypeFloat64)
+	case reflect.String:
+		set(TypeVarChar)
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("param %q: unable to infer type from %v", param.Name, t)
+		}
+		set(TypeBinary)
+	case reflect.Array:
+		if t.Len() != 16 || t.Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("param %q: unable to infer type from %v", param.Name, t)
+		}
+		set(TypeUUID)
+	case reflect.Struct:
+		if isOpt(t) {
+			f, _ := t.FieldByName("V")
+			return inferReflectType(param, f.Type)
+		}
+		return fmt.Errorf("param %q: unable to infer type from %v", param.Name, t)
+	}
+	return nil
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// prepareParams encodes custom types and infers the type of each parameter
+// without one. The params are copied before they are changed.
+func prepareParams(info *DriverInfo, params []Param) ([]Param, error) {
+	copied := false
+	for i := range params {
+		p := params[i]
+		handled, err := encodeParam(&p)
+		if err != nil {
+			return nil, fmt.Errorf("param %q: %w", p.Name, err)
+		}
+		if !handled && p.Type != TypeUnknown {
+			continue
+		}
+		err = inferParam(info, &p)
+		if err != nil {
+			return nil, err
+		}
+		if !copied {
+			params = append([]Param(nil), params...)
+			copied = true
+		}
+		params[i] = p
+	}
+	return params, nil
+}
+
+// prepareParam encodes a custom type and infers the type if not set.
+func prepareParam(info *DriverInfo, param *Param) error {
+	_, err := encodeParam(param)
+	if err != nil {
+		return fmt.Errorf("param %q: %w", param.Name, err)
+	}
+	return inferParam(info, param)
+}
+
+func inferParam(info *DriverInfo, param *Param) error {
+	if info != nil && info.InferParamType != nil {
+		return info.InferParamType(param)
+	}
+	return InferParamType(param)
+}
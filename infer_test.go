@@ -0,0 +1,170 @@
+package rdb
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferParamType(t *testing.T) {
+	str := "hello"
+	var nilStr *string
+	list := []struct {
+		Value     interface{}
+		Type      Type
+		Length    int
+		Precision int
+		Scale     int
+		Null      bool
+		Want      interface{}
+	}{
+		{Value: nil, Type: TypeVarChar, Length: 1, Null: true},
+		{Value: Null, Type: TypeVarChar, Length: 1, Null: true},
+		{Value: true, Type: TypeBool, Want: true},
+		{Value: int8(1), Type: TypeInt16, Want: int8(1)},
+		{Value: uint8(1), Type: TypeInt16, Want: uint8(1)},
+		{Value: int16(1), Type: TypeInt16, Want: int16(1)},
+		{Value: int32(1), Type: TypeInt32, Want: int32(1)},
+		{Value: uint16(1), Type: TypeInt32, Want: uint16(1)},
+		{Value: 1, Type: TypeInt64, Want: 1},
+		{Value: uint64(1), Type: TypeInt64, Want: uint64(1)},
+		{Value: float32(1), Type: TypeFloat32, Want: float32(1)},
+		{Value: 1.5, Type: TypeFloat64, Want: 1.5},
+		{Value: "hello", Type: TypeVarChar, Length: InferTextLength, Want: "hello"},
+		{Value: []byte("hi"), Type: TypeBinary, Length: InferBinaryLength, Want: []byte("hi")},
+		{Value: [16]byte{1}, Type: TypeUUID, Want: [16]byte{1}},
+		{Value: time.Duration(0), Type: TypeTime, Want: time.Duration(0)},
+		{Value: time.Time{}, Type: TypeTimestampz, Want: time.Time{}},
+		{Value: big.NewRat(5, 4), Type: TypeDecimal, Precision: 38, Scale: 2, Want: big.NewRat(5, 4)},
+		{Value: big.NewRat(1, 3), Type: TypeDecimal, Precision: 38, Scale: 18, Want: big.NewRat(1, 3)},
+		{Value: NewDecimal(1250, 2), Type: TypeDecimal, Precision: 38, Scale: 2, Want: NewDecimal(1250, 2)},
+		{Value: Opt[Decimal]{}, Type: TypeDecimal, Precision: 38, Null: true},
+		{Value: json.RawMessage(`{}`), Type: TypeJSON, Want: json.RawMessage(`{}`)},
+		{Value: &str, Type: TypeVarChar, Length: InferTextLength, Want: "hello"},
+		{Value: nilStr, Type: TypeVarChar, Length: InferTextLength, Null: true},
+		{Value: Opt[int32]{V: 3, Valid: true}, Type: TypeInt32, Want: int32(3)},
+		{Value: Opt[int32]{}, Type: TypeInt32, Null: true},
+		{Value: &Opt[[]byte]{}, Type: TypeBinary, Length: InferBinaryLength, Null: true},
+	}
+	for _, item := range list {
+		p := Param{Name: "p", Value: item.Value}
+		if err := InferParamType(&p); err != nil {
+			t.Errorf("%T: %v", item.Value, err)
+			continue
+		}
+		if p.Type != item.Type || p.Length != item.Length || p.Precision != item.Precision || p.Scale != item.Scale || p.Null != item.Null {
+			t.Errorf("%T: got type=%v length=%d precision=%d scale=%d null=%t, want type=%v length=%d precision=%d scale=%d null=%t",
+				item.Value, p.Type, p.Length, p.Precision, p.Scale, p.Null,
+				item.Type, item.Length, item.Precision, item.Scale, item.Null)
+		}
+		if item.Null {
+			continue
+		}
+		if !equalValue(p.Value, item.Want) {
+			t.Errorf("%T: got value %#v, want %#v", item.Value, p.Value, item.Want)
+		}
+	}
+
+	// Long values use a max length type.
+	p := Param{Name: "long", Value: strings.Repeat("a", InferTextLength+1)}
+	if err := InferParamType(&p); err != nil || p.Length != 0 {
+		t.Errorf("long string: got length=%d err=%v, want length 0", p.Length, err)
+	}
+
+	// Set types are left alone.
+	p = Param{Name: "set", Type: TypeAnsiVarChar, Value: "a"}
+	if err := InferParamType(&p); err != nil || p.Type != TypeAnsiVarChar || p.Length != 0 {
+		t.Errorf("set type: got type=%v length=%d err=%v", p.Type, p.Length, err)
+	}
+
+	for _, v := range []interface{}{uint64(1 << 63), struct{}{}, []int{1}, [4]byte{}} {
+		p := Param{Name: "bad", Value: v}
+		if err := InferParamType(&p); err == nil {
+			t.Errorf("%T: expected error", v)
+		}
+	}
+}
+
+func equalValue(a, b interface{}) bool {
+	switch av := a.(type) {
+	case []byte:
+		bv, ok := b.([]byte)
+		return ok && string(av) == string(bv)
+	case json.RawMessage:
+		bv, ok := b.(json.RawMessage)
+		return ok && string(av) == string(bv)
+	case *big.Rat:
+		bv, ok := b.(*big.Rat)
+		return ok && av.Cmp(bv) == 0
+	}
+	return a == b
+}
+
+type inferDriver struct {
+	dummyDriver
+	params chan []Param
+}
+
+func (d *inferDriver) DriverInfo() *DriverInfo {
+	return &DriverInfo{
+		InferParamType: func(param *Param) error {
+			err := InferParamType(param)
+			if param.Type == TypeJSON {
+				param.Type = TypeVarChar
+			}
+			return err
+		},
+	}
+}
+
+func (d *inferDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	return &inferConn{dummyConn: dummyConn{opened: time.Now()}, params: d.params}, nil
+}
+
+type inferConn struct {
+	dummyConn
+	params chan []Param
+}
+
+func (c *inferConn) Query(ctx context.Context, cmd *Command, params []Param, preparedToken interface{}, val DriverValuer) error {
+	c.params <- params
+	return nil
+}
+
+func TestInferParamsDriver(t *testing.T) {
+	d := &inferDriver{params: make(chan []Param, 1)}
+	Register("infer_driver", d)
+	pool, err := Open(&Config{DriverName: "infer_driver"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	params := []Param{
+		{Name: "n", Value: Opt[int64]{V: 1, Valid: true}},
+		{Name: "j", Value: json.RawMessage(`[]`)},
+		{Name: "s", Type: TypeAnsiVarChar, Value: "a"},
+	}
+	res, err := pool.Query(context.Background(), &Command{SQL: "select"}, params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+
+	got := <-d.params
+	if got[0].Type != TypeInt64 || got[0].Value != int64(1) {
+		t.Errorf("n: got %v %#v", got[0].Type, got[0].Value)
+	}
+	if got[1].Type != TypeVarChar {
+		t.Errorf("j: got %v, want driver override", got[1].Type)
+	}
+	if got[2].Type != TypeAnsiVarChar {
+		t.Errorf("s: got %v", got[2].Type)
+	}
+	if params[0].Type != TypeUnknown {
+		t.Errorf("caller params changed")
+	}
+}
@@ -44,6 +44,10 @@ var (
 	prefillTimeout = 30 * time.Second
 )
 
+// Priorities is the number of priority levels accepted by GetPriority.
+// Zero is the lowest priority.
+const Priorities = 3
+
 // Factory is a function that can be used to create a resource.
 type Factory[T Resource] func(context.Context) (T, error)
 
@@ -64,14 +68,23 @@ type ResourcePool[T Resource] struct {
 	waitTime   atomic.Int64
 	idleClosed atomic.Int64
 	exhausted  atomic.Int64
+	invalid    atomic.Int64
 
 	capacity    atomic.Int64
 	idleTimeout atomic.Int64
 
+	// Number of callers waiting at each priority.
+	waiting [Priorities]atomic.Int64
+	// waitDone is closed and replaced when a caller stops waiting,
+	// to wake callers yielding to a higher priority.
+	waitMu   sync.Mutex
+	waitDone chan struct{}
+
 	resources chan resourceWrapper[T]
 	factory   Factory[T]
 	idleTimer *timer.Timer
 	logWait   func(time.Time)
+	validate  func(ctx context.Context, resource T, idle time.Duration) bool
 }
 
 type resourceWrapper[T Resource] struct {
@@ -98,6 +111,7 @@ func NewResourcePool[T Resource](prefillCtx context.Context, factory Factory[T],
 		resources: make(chan resourceWrapper[T], maxCap),
 		factory:   factory,
 		logWait:   logWait,
+		waitDone:  make(chan struct{}),
 	}
 	rp.available.Store(int64(capacity))
 	rp.capacity.Store(int64(capacity))
@@ -183,6 +197,19 @@ func (rp *ResourcePool[T]) closeIdleResources(ctx context.Context) {
 // it will wait till the next resource becomes available or a timeout.
 // A timeout of 0 is an indefinite wait.
 func (rp *ResourcePool[T]) Get(ctx, soft context.Context) (resource T, err error) {
+	return rp.GetPriority(ctx, soft, 0)
+}
+
+// GetPriority is like Get, but while callers with a higher priority are
+// waiting, resources are left for them.
+func (rp *ResourcePool[T]) GetPriority(ctx, soft context.Context, priority int) (resource T, err error) {
+	if priority < 0 {
+		priority = 0
+	}
+	if priority >= Priorities {
+		priority = Priorities - 1
+	}
+
 	// If ctx has already expired, avoid racing with rp's resource channel.
 	select {
 	case <-ctx.Done():
@@ -192,24 +219,67 @@ func (rp *ResourcePool[T]) Get(ctx, soft context.Context) (resource T, err error
 
 	// Fetch
 	var wrapper resourceWrapper[T]
-	var ok bool
-	select {
-	case wrapper, ok = <-rp.resources:
-	default:
-		startTime := time.Now()
+	var ok, got bool
+	if !rp.higherWaiting(priority) {
 		select {
 		case wrapper, ok = <-rp.resources:
-		case <-soft.Done():
-			return resource, ErrTimeout
-		case <-ctx.Done():
-			return resource, ErrCtxTimeout
+			got = true
+		default:
+		}
+	}
+	if !got {
+		startTime := time.Now()
+		rp.waiting[priority].Add(1)
+		for !got {
+			if rp.higherWaiting(priority) {
+				// Yield to the higher priority waiters until one stops waiting.
+				// The check is repeated after getting the channel so a wake up
+				// is not missed.
+				done := rp.waitChanged()
+				if rp.higherWaiting(priority) {
+					select {
+					case <-done:
+					case <-soft.Done():
+						rp.doneWaiting(priority)
+						return resource, ErrTimeout
+					case <-ctx.Done():
+						rp.doneWaiting(priority)
+						return resource, ErrCtxTimeout
+					}
+				}
+				continue
+			}
+			select {
+			case wrapper, ok = <-rp.resources:
+				if ok && rp.higherWaiting(priority) {
+					// A higher priority started waiting, leave it the resource.
+					rp.resources <- wrapper
+					continue
+				}
+				got = true
+			case <-soft.Done():
+				rp.doneWaiting(priority)
+				return resource, ErrTimeout
+			case <-ctx.Done():
+				rp.doneWaiting(priority)
+				return resource, ErrCtxTimeout
+			}
 		}
+		rp.doneWaiting(priority)
 		rp.recordWait(startTime)
 	}
 	if !ok {
 		return resource, ErrClosed
 	}
 
+	// Validate
+	if wrapper.resource != nil && rp.validate != nil && !rp.validate(ctx, wrapper.resource.(T), time.Since(wrapper.timeUsed)) {
+		wrapper.resource.Close()
+		wrapper.resource = nil
+		rp.active.Add(-1)
+		rp.invalid.Add(1)
+	}
+
 	// Unwrap
 	if wrapper.resource == nil {
 		wrapper.resource, err = rp.factory(ctx)
@@ -266,6 +336,55 @@ func (rp *ResourcePool[T]) reopenResource(ctx context.Context, wrapper *resource
 	}
 }
 
+// SetValidate sets a function that is called before an existing resource is
+// returned from Get. If it returns false the resource is closed and a new one
+// is created. Must be called before the pool is used.
+func (rp *ResourcePool[T]) SetValidate(validate func(ctx context.Context, resource T, idle time.Duration) bool) {
+	rp.validate = validate
+}
+
+// Keep checks each idle resource with check, replacing resources where
+// check returns false. It also opens new resources until at least minIdle
+// idle resources are open. A nil check only opens resources.
+// Returns the number of resources replaced.
+func (rp *ResourcePool[T]) Keep(ctx context.Context, check func(ctx context.Context, resource T) bool, minIdle int) (replaced int) {
+	available := int(rp.Available())
+
+	for i := 0; i < available; i++ {
+		var wrapper resourceWrapper[T]
+		select {
+		case wrapper = <-rp.resources:
+		default:
+			// stop early if we don't get anything new from the pool
+			return replaced
+		}
+
+		func() {
+			defer func() { rp.resources <- wrapper }()
+
+			if wrapper.resource == nil {
+				if rp.Active()-rp.InUse() >= int64(minIdle) {
+					return
+				}
+				r, err := rp.factory(ctx)
+				if err != nil {
+					return
+				}
+				wrapper.resource = r
+				wrapper.timeUsed = time.Now()
+				rp.active.Add(1)
+				return
+			}
+			if check != nil && !check(ctx, wrapper.resource.(T)) {
+				wrapper.resource.Close()
+				replaced++
+				rp.reopenResource(ctx, &wrapper)
+			}
+		}()
+	}
+	return replaced
+}
+
 // SetCapacity changes the capacity of the pool.
 // You can use it to shrink or expand, but not beyond
 // the max capacity. If the change requires the pool
@@ -314,6 +433,30 @@ func (rp *ResourcePool[T]) SetCapacity(capacity int) error {
 	return nil
 }
 
+// waitChanged returns a channel closed when a caller stops waiting.
+func (rp *ResourcePool[T]) waitChanged() <-chan struct{} {
+	rp.waitMu.Lock()
+	defer rp.waitMu.Unlock()
+	return rp.waitDone
+}
+
+func (rp *ResourcePool[T]) doneWaiting(priority int) {
+	rp.waiting[priority].Add(-1)
+	rp.waitMu.Lock()
+	close(rp.waitDone)
+	rp.waitDone = make(chan struct{})
+	rp.waitMu.Unlock()
+}
+
+func (rp *ResourcePool[T]) higherWaiting(priority int) bool {
+	for p := priority + 1; p < Priorities; p++ {
+		if rp.waiting[p].Load() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (rp *ResourcePool[T]) recordWait(start time.Time) {
 	rp.waitCount.Add(1)
 	rp.waitTime.Add(int64(time.Since(start)))
@@ -380,6 +523,15 @@ func (rp *ResourcePool[T]) WaitCount() int64 {
 	return rp.waitCount.Load()
 }
 
+// Waiting returns the number of callers currently waiting for a resource.
+func (rp *ResourcePool[T]) Waiting() int64 {
+	var n int64
+	for i := range rp.waiting {
+		n += rp.waiting[i].Load()
+	}
+	return n
+}
+
 // WaitTime returns the total wait time.
 func (rp *ResourcePool[T]) WaitTime() time.Duration {
 	return time.Duration(rp.waitTime.Load())
@@ -395,6 +547,11 @@ func (rp *ResourcePool[T]) IdleClosed() int64 {
 	return rp.idleClosed.Load()
 }
 
+// Invalid returns the count of resources closed because they failed validation on Get.
+func (rp *ResourcePool[T]) Invalid() int64 {
+	return rp.invalid.Load()
+}
+
 // Exhausted returns the number of times Available dropped below 1
 func (rp *ResourcePool[T]) Exhausted() int64 {
 	return rp.exhausted.Load()
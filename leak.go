@@ -0,0 +1,222 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kardianos/rdb/internal/pools/timer"
+)
+
+// ErrLeaked is reported to Transaction.OnRollback hooks when leak detection
+// closes the transaction.
+var ErrLeaked = errors.New("closed by leak detection")
+
+// Leak describes a Result, Transaction, or Connection that has held
+// a pooled connection without being used for longer than Config.LeakTimeout.
+type Leak struct {
+	Kind     string // "Result", "Transaction", or "Connection".
+	Name     string // Command name or SQL for a Result.
+	Acquired time.Time
+	LastUsed time.Time
+	Stack    string // Stack trace where the connection was checked out.
+	Closed   bool   // True if the resource was closed, see Config.LeakClose.
+}
+
+func (l *Leak) String() string {
+	action := "still open"
+	if l.Closed {
+		action = "closed"
+	}
+	return fmt.Sprintf("rdb: leaked %s %q acquired %s, idle %s, %s:\n%s", l.Kind, l.Name, l.Acquired.Format(time.RFC3339), time.Since(l.LastUsed).Round(time.Millisecond), action, l.Stack)
+}
+
+type leakEntry struct {
+	kind     string
+	name     string
+	acquired time.Time
+	stack    []byte
+	used     atomic.Int64 // Unix nano time of last use.
+	reported bool
+
+	// use is held while the owner calls into the connection, so a leak is
+	// never closed in the middle of a call, such as a long running query.
+	use sync.Mutex
+	// Set once forceClose has run.
+	closed atomic.Bool
+
+	// forceClose closes the leaked resource with use held. It may return
+	// a func to run after use is released, such as rollback hooks.
+	forceClose func() func()
+}
+
+func (e *leakEntry) touch() {
+	if e == nil {
+		return
+	}
+	e.used.Store(time.Now().UnixNano())
+}
+
+// lock marks the connection in use by the owner.
+func (e *leakEntry) lock() {
+	if e == nil {
+		return
+	}
+	e.use.Lock()
+	e.touch()
+}
+
+func (e *leakEntry) unlock() {
+	if e == nil {
+		return
+	}
+	e.touch()
+	e.use.Unlock()
+}
+
+// forced returns true if the connection was closed by leak detection.
+// Call with the entry locked.
+func (e *leakEntry) forced() bool {
+	return e != nil && e.closed.Load()
+}
+
+// leakDetector tracks checked out connections.
+type leakDetector struct {
+	timer *timer.Timer
+
+	mu      sync.Mutex
+	entries map[DriverConn]*leakEntry
+}
+
+func (cp *ConnPool) startLeakDetector() {
+	timeout := cp.conf.LeakTimeout
+	if timeout <= 0 {
+		return
+	}
+	interval := timeout / 2
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	cp.leak = &leakDetector{
+		timer:   timer.NewTimer(interval),
+		entries: make(map[DriverConn]*leakEntry),
+	}
+	cp.leak.timer.Start(cp.checkLeaks, timeout)
+}
+
+func (cp *ConnPool) stopLeakDetector() {
+	if cp.leak == nil {
+		return
+	}
+	cp.leak.timer.Stop()
+}
+
+// track registers conn as checked out by a resource. Returns nil if leak
+// detection is not enabled.
+func (cp *ConnPool) track(conn DriverConn, kind, name string, forceClose func() func()) *leakEntry {
+	ld := cp.leak
+	if ld == nil {
+		return nil
+	}
+	buf := make([]byte, 8000)
+	buf = buf[:runtime.Stack(buf, false)]
+	e := &leakEntry{
+		kind:       kind,
+		name:       name,
+		acquired:   time.Now(),
+		stack:      buf,
+		forceClose: forceClose,
+	}
+	e.touch()
+	ld.mu.Lock()
+	ld.entries[conn] = e
+	ld.mu.Unlock()
+	return e
+}
+
+// tracked returns the entry of a connection already checked out.
+func (cp *ConnPool) tracked(conn DriverConn) *leakEntry {
+	ld := cp.leak
+	if ld == nil {
+		return nil
+	}
+	ld.mu.Lock()
+	e := ld.entries[conn]
+	ld.mu.Unlock()
+	return e
+}
+
+func (cp *ConnPool) untrack(conn DriverConn) {
+	ld := cp.leak
+	if ld == nil {
+		return
+	}
+	ld.mu.Lock()
+	delete(ld.entries, conn)
+	ld.mu.Unlock()
+}
+
+func (cp *ConnPool) checkLeaks(ctx context.Context) {
+	ld := cp.leak
+	timeout := cp.conf.LeakTimeout
+	now := time.Now()
+
+	var found []*leakEntry
+	ld.mu.Lock()
+	for _, e := range ld.entries {
+		if e.reported && !cp.conf.LeakClose {
+			continue
+		}
+		if now.Sub(time.Unix(0, e.used.Load())) < timeout {
+			continue
+		}
+		if !e.use.TryLock() {
+			// The owner is in a call on the connection.
+			continue
+		}
+		e.reported = true
+		found = append(found, e)
+	}
+	ld.mu.Unlock()
+
+	for _, e := range found {
+		l := &Leak{
+			Kind:     e.kind,
+			Name:     e.name,
+			Acquired: e.acquired,
+			LastUsed: time.Unix(0, e.used.Load()),
+			Stack:    string(e.stack),
+		}
+		var after func()
+		if cp.conf.LeakClose && e.forceClose != nil {
+			after = e.forceClose()
+			e.closed.Store(true)
+			l.Closed = true
+		}
+		e.use.Unlock()
+		if after != nil {
+			after()
+		}
+		if cp.conf.LeakReport != nil {
+			cp.conf.LeakReport(l)
+		} else {
+			log.Print(l.String())
+		}
+	}
+}
+
+func commandName(cmd *Command) string {
+	if len(cmd.Name) > 0 {
+		return cmd.Name
+	}
+	return cmd.SQL
+}
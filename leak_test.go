@@ -0,0 +1,174 @@
+package rdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeakDetection(t *testing.T) {
+	Register("leak_test", &dummyDriver{})
+	leaks := make(chan *Leak, 10)
+	pool, err := Open(&Config{
+		DriverName:       "leak_test",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		LeakTimeout:      20 * time.Millisecond,
+		LeakClose:        true,
+		LeakReport: func(leak *Leak) {
+			leaks <- leak
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	res, err := pool.Query(ctx, &Command{Name: "leaky", Arity: Any})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leak *Leak
+	select {
+	case leak = <-leaks:
+	case <-time.After(time.Second):
+		t.Fatal("leak not reported")
+	}
+	if leak.Kind != "Result" || leak.Name != "leaky" || !leak.Closed {
+		t.Errorf("unexpected leak: %+v", leak)
+	}
+	if !strings.Contains(leak.Stack, "TestLeakDetection") {
+		t.Errorf("expected acquisition stack, got:\n%s", leak.Stack)
+	}
+	if err := res.Scan(); err != ErrClosed {
+		t.Errorf("expected ErrClosed from leaked result, got %v", err)
+	}
+	if s := pool.Stats(); s.InUse != 0 {
+		t.Errorf("expected connection returned to pool, got %d in use", s.InUse)
+	}
+
+	// A transaction in use is not reported.
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		res, err := tran.Query(ctx, &Command{Arity: Zero})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Close()
+	}
+	select {
+	case leak := <-leaks:
+		t.Fatalf("unexpected leak: %v", leak)
+	default:
+	}
+	tran.Commit()
+}
+
+// slowDriver opens connections that run each query for delay.
+type slowDriver struct {
+	dummyDriver
+	delay time.Duration
+}
+
+func (d *slowDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	return &slowConn{dummyConn: dummyConn{opened: time.Now()}, delay: d.delay}, nil
+}
+
+type slowConn struct {
+	dummyConn
+	delay time.Duration
+}
+
+func (c *slowConn) Query(ctx context.Context, cmd *Command, params []Param, preparedToken interface{}, val DriverValuer) error {
+	time.Sleep(c.delay)
+	return nil
+}
+
+func TestLeakCloseInUse(t *testing.T) {
+	Register("leakinuse", &slowDriver{delay: 60 * time.Millisecond})
+	leaks := make(chan *Leak, 10)
+	pool, err := Open(&Config{
+		DriverName:       "leakinuse",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		LeakTimeout:      20 * time.Millisecond,
+		LeakClose:        true,
+		LeakReport: func(leak *Leak) {
+			leaks <- leak
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hookErr error
+	tran.OnRollback(func(err error) {
+		hookErr = err
+		// The transaction may be used from a hook without a deadlock.
+		if _, err := tran.Query(ctx, &Command{Arity: Zero}); err != errTransactionClosed {
+			t.Errorf("expected closed transaction in hook, got %v", err)
+		}
+	})
+
+	// A query running longer than LeakTimeout is not a leak.
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				tran.Active()
+			}
+		}
+	}()
+	res, err := tran.Query(ctx, &Command{Arity: Zero})
+	close(stop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	select {
+	case leak := <-leaks:
+		t.Fatalf("unexpected leak during a query: %v", leak)
+	default:
+	}
+	if !tran.Active() {
+		t.Fatal("transaction closed during a query")
+	}
+
+	// Left idle, the transaction is rolled back.
+	select {
+	case leak := <-leaks:
+		if leak.Kind != "Transaction" || !leak.Closed {
+			t.Errorf("unexpected leak: %+v", leak)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("leak not reported")
+	}
+	if tran.Active() {
+		t.Error("expected leaked transaction to be closed")
+	}
+	if hookErr != ErrLeaked {
+		t.Errorf("expected ErrLeaked in OnRollback, got %v", hookErr)
+	}
+	if err := tran.Commit(); err != errTransactionClosed {
+		t.Errorf("expected closed transaction, got %v", err)
+	}
+	if s := pool.Stats(); s.InUse != 0 {
+		t.Errorf("expected connection returned to pool, got %d in use", s.InUse)
+	}
+}
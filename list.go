@@ -0,0 +1,146 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// defaultListParamMax is used when Config.ListParamMax is zero.
+const defaultListParamMax = 200
+
+// listValue returns the slice value of a list parameter. Byte slices and
+// types with a ParamValuer or registered encoder are not lists.
+func listValue(param *Param) (reflect.Value, bool) {
+	switch param.Value.(type) {
+	case nil, string, []byte, ParamValuer:
+		return reflect.Value{}, false
+	}
+	rv := reflect.ValueOf(param.Value)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return reflect.Value{}, false
+	}
+	if codec, ok := lookupType(rv.Type()); ok && codec.encode != nil {
+		return reflect.Value{}, false
+	}
+	return rv, true
+}
+
+// expandLists rewrites list parameters, such as a []int64 used in
+// "where ID in (@ids)". Each value is sent as its own parameter named
+// "ids_0", "ids_1", and so on. Lists longer than Config.ListParamMax are sent
+// as a single JSON array if the driver sets DriverInfo.ListSQL.
+// An empty list is replaced with an empty subquery, so "in" matches no rows
+// and "not in" matches every row.
+func expandLists(info *DriverInfo, conf *Config, cmd *Command, params []Param) (*Command, []Param, error) {
+	found := false
+	for i := range params {
+		if _, ok := listValue(&params[i]); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return cmd, params, nil
+	}
+	max := conf.ListParamMax
+	if max <= 0 {
+		max = defaultListParamMax
+	}
+	var listSQL func(name string, elem *Param) string
+	if info != nil {
+		listSQL = info.ListSQL
+	}
+
+	out := make([]Param, 0, len(params))
+	replace := make(map[string]string)
+	names := make(map[string]string)
+	for _, p := range params {
+		rv, ok := listValue(&p)
+		if !ok {
+			out = append(out, p)
+			continue
+		}
+		key := strings.ToLower(p.Name)
+		names[key] = p.Name
+		n := rv.Len()
+		switch {
+		case n == 0:
+			replace[key] = "select null where 1=0"
+		case n > max && listSQL != nil:
+			elem := Param{
+				Name:      p.Name,
+				Type:      p.Type,
+				Length:    p.Length,
+				Precision: p.Precision,
+				Scale:     p.Scale,
+				Value:     rv.Index(0).Interface(),
+			}
+			err := prepareParam(info, &elem)
+			if err != nil {
+				return nil, nil, err
+			}
+			if p.Length == 0 {
+				// Let the driver use a max length type.
+				elem.Length = 0
+			}
+			bb, err := json.Marshal(rv.Interface())
+			if err != nil {
+				return nil, nil, fmt.Errorf("param %q: %w", p.Name, err)
+			}
+			out = append(out, Param{Name: p.Name, Type: TypeVarChar, Value: string(bb)})
+			replace[key] = listSQL(p.Name, &elem)
+		default:
+			sb := &strings.Builder{}
+			for i := 0; i < n; i++ {
+				name := p.Name + "_" + strconv.Itoa(i)
+				if i > 0 {
+					sb.WriteString(", ")
+				}
+				sb.WriteByte('@')
+				sb.WriteString(name)
+				out = append(out, Param{
+					Name:      name,
+					Type:      p.Type,
+					Length:    p.Length,
+					Precision: p.Precision,
+					Scale:     p.Scale,
+					Value:     rv.Index(i).Interface(),
+				})
+			}
+			replace[key] = sb.String()
+		}
+	}
+
+	sql := cmd.SQL
+	sb := &strings.Builder{}
+	last := 0
+	for _, ph := range scanPlaceholders(sql) {
+		if ph.Kind != placeholderAt {
+			continue
+		}
+		key := strings.ToLower(ph.Name)
+		r, ok := replace[key]
+		if !ok {
+			continue
+		}
+		sb.WriteString(sql[last:ph.Start])
+		sb.WriteString(r)
+		last = ph.End
+		delete(names, key)
+	}
+	for _, name := range names {
+		return nil, nil, fmt.Errorf("param %q: list parameter not found in SQL", name)
+	}
+	sb.WriteString(sql[last:])
+
+	c := *cmd
+	c.SQL = sb.String()
+	return &c, out, nil
+}
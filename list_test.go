@@ -0,0 +1,149 @@
+package rdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanPlaceholders(t *testing.T) {
+	list := []struct {
+		SQL  string
+		Want []string
+	}{
+		{`select * from T where ID in (@ids) and Name = @name;`, []string{"ids", "name"}},
+		{`select '@a', 'it''s @b', N'@c', "@d", [@e]]f], @g`, []string{"g"}},
+		{"select @@ROWCOUNT, @a -- @b\n, @c", []string{"a", "c"}},
+		{`select /* @a /* @b */ @c */ @d`, []string{"d"}},
+		{`select a@b, @x#1, @`, []string{"x#1"}},
+		{`select 'unterminated @a`, nil},
+		{`select ?, $1, :a, x::int, $1a, '?'`, []string{"", "1", "a"}},
+	}
+	for _, item := range list {
+		var got []string
+		for _, ph := range scanPlaceholders(item.SQL) {
+			if prefix := item.SQL[ph.Start : ph.Start+1]; item.SQL[ph.Start:ph.End] != prefix+ph.Name {
+				t.Errorf("%s: bad offsets for %q", item.SQL, ph.Name)
+			}
+			got = append(got, ph.Name)
+		}
+		if !reflect.DeepEqual(got, item.Want) {
+			t.Errorf("%s: got %q, want %q", item.SQL, got, item.Want)
+		}
+	}
+}
+
+type listQuery struct {
+	sql    string
+	params []Param
+}
+
+type listDriver struct {
+	dummyDriver
+	queries chan listQuery
+}
+
+func (d *listDriver) DriverInfo() *DriverInfo {
+	return &DriverInfo{
+		ListSQL: func(name string, elem *Param) string {
+			return fmt.Sprintf("select v from list(@%s, %d)", name, elem.Type)
+		},
+	}
+}
+
+func (d *listDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	return &listConn{dummyConn: dummyConn{opened: time.Now()}, queries: d.queries}, nil
+}
+
+type listConn struct {
+	dummyConn
+	queries chan listQuery
+}
+
+func (c *listConn) Query(ctx context.Context, cmd *Command, params []Param, preparedToken interface{}, val DriverValuer) error {
+	c.queries <- listQuery{sql: cmd.SQL, params: params}
+	return nil
+}
+
+func TestExpandLists(t *testing.T) {
+	d := &listDriver{queries: make(chan listQuery, 1)}
+	Register("list_expand", d)
+	pool, err := Open(&Config{DriverName: "list_expand", ListParamMax: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	query := func(sql string, params ...Param) (listQuery, error) {
+		res, err := pool.Query(ctx, &Command{SQL: sql}, params...)
+		if err != nil {
+			return listQuery{}, err
+		}
+		res.Close()
+		return <-d.queries, nil
+	}
+
+	cmd := &Command{SQL: `select * from T where ID in (@IDs) and '@ids' <> @name or ID in (@ids);`}
+	res, err := pool.Query(ctx, cmd, Param{Name: "ids", Value: []int32{4, 5}}, Param{Name: "name", Value: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	got := <-d.queries
+	want := `select * from T where ID in (@ids_0, @ids_1) and '@ids' <> @name or ID in (@ids_0, @ids_1);`
+	if got.sql != want {
+		t.Errorf("got %s\nwant %s", got.sql, want)
+	}
+	if !strings.Contains(cmd.SQL, "(@IDs)") {
+		t.Errorf("command changed: %s", cmd.SQL)
+	}
+	if len(got.params) != 3 || got.params[0].Name != "ids_0" || got.params[0].Type != TypeInt32 ||
+		got.params[1].Value != int32(5) || got.params[2].Name != "name" {
+		t.Errorf("params: %+v", got.params)
+	}
+
+	got, err = query(`select * from T where ID in (@ids)`, Param{Name: "ids", Value: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.sql != `select * from T where ID in (select null where 1=0)` || len(got.params) != 0 {
+		t.Errorf("empty: %s %+v", got.sql, got.params)
+	}
+
+	// An empty exclusion list must not filter out every row.
+	got, err = query(`select * from T where ID not in (@ids)`, Param{Name: "ids", Value: []int64{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `select * from T where ID not in (select null where 1=0)`; got.sql != want || len(got.params) != 0 {
+		t.Errorf("not in: got %s %+v\nwant %s", got.sql, got.params, want)
+	}
+
+	got, err = query(`select * from T where ID in (@ids)`, Param{Name: "ids", Value: []int64{1, 2, 3, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf(`select * from T where ID in (select v from list(@ids, %d))`, TypeInt64); got.sql != want {
+		t.Errorf("got %s\nwant %s", got.sql, want)
+	}
+	if len(got.params) != 1 || got.params[0].Value != "[1,2,3,4]" || got.params[0].Type != TypeVarChar {
+		t.Errorf("json: %+v", got.params)
+	}
+
+	// Byte slices are not lists.
+	got, err = query(`select @b`, Param{Name: "b", Value: []byte("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.sql != `select @b` || got.params[0].Type != TypeBinary {
+		t.Errorf("bytes: %s %+v", got.sql, got.params)
+	}
+
+	if _, err := query(`select '@ids'`, Param{Name: "ids", Value: []int{1}}); err == nil {
+		t.Error("expected error for list not in SQL")
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+// Package metrics exports rdb connection pool statistics over expvar and
+// HTTP. It is kept apart from rdb so importing rdb does not import net/http.
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/kardianos/rdb"
+)
+
+// Expvar returns a variable that reports the pool statistics as JSON.
+//
+//	expvar.Publish("db", metrics.Expvar(pool))
+func Expvar(cp *rdb.ConnPool) expvar.Var {
+	return expvar.Func(func() any {
+		return cp.Stats()
+	})
+}
+
+// PrometheusHandler returns an http.Handler that serves the statistics of each
+// pool in the Prometheus text exposition format.
+// The map key is used as the "pool" label.
+func PrometheusHandler(pools map[string]*rdb.ConnPool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		rdb.WritePrometheus(w, pools)
+	})
+}
@@ -0,0 +1,46 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kardianos/rdb"
+)
+
+// idleDriver never opens a connection; the pool statistics are still reported.
+type idleDriver struct{}
+
+func (d *idleDriver) DriverInfo() *rdb.DriverInfo { return &rdb.DriverInfo{} }
+func (d *idleDriver) PingCommand() *rdb.Command   { return &rdb.Command{Arity: rdb.Zero} }
+func (d *idleDriver) Open(ctx context.Context, c *rdb.Config) (rdb.DriverConn, error) {
+	return nil, errors.New("idleDriver does not open connections")
+}
+
+func TestMetrics(t *testing.T) {
+	rdb.Register("metricsidle", &idleDriver{})
+	pool, err := rdb.Open(&rdb.Config{DriverName: "metricsidle", PoolInitCapacity: 1, PoolMaxCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if v := Expvar(pool).String(); !strings.Contains(v, `"MaxCapacity":2`) {
+		t.Errorf("unexpected expvar output: %s", v)
+	}
+
+	rec := httptest.NewRecorder()
+	PrometheusHandler(map[string]*rdb.ConnPool{"main": pool}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type %q", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `rdb_pool_max_capacity{pool="main"} 2`) {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+}
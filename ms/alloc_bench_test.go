@@ -532,7 +532,7 @@ func BenchmarkEncodeParamString(b *testing.B) {
 		if err := w.BeginMessage(ctx, packetRPC, false); err != nil {
 			b.Fatal(err)
 		}
-		if err := encodeParam(ctx, w, false, ver, param, param.Value, collation); err != nil {
+		if err := encodeParam(ctx, w, false, nil, ver, param, param.Value, collation); err != nil {
 			b.Fatal(err)
 		}
 		if err := w.EndMessage(ctx); err != nil {
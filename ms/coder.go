@@ -30,6 +30,26 @@ func zeroDateN(loc *time.Location) time.Time {
 	return time.Date(1, time.January, 1, 0, 0, 0, 0, loc)
 }
 
+// wallClock returns t, a value without a time zone decoded in UTC, as the same
+// wall clock time in loc. If loc is nil t is returned unchanged.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	return time.Date(year, month, day, hour, minute, second, t.Nanosecond(), loc)
+}
+
+// inLocation converts t to loc before it is sent as a value without a
+// time zone. If loc is nil t keeps its own wall clock time.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
 func encodeType(w *PacketWriter, ti paramTypeInfo, param *rdb.Param, collation [5]byte) error {
 	// Start TYPE_INFO.
 	// Write the type of field this is.
@@ -100,7 +120,7 @@ const (
 	textUnknown = 0xFFFFFFFFFFFFFFFE
 )
 
-func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *rdb.Param, truncValues bool, value interface{}) error {
+func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *rdb.Param, truncValues bool, loc *time.Location, value interface{}) error {
 	var nullValue bool
 	if value == rdb.Null || value == nil || param.Null {
 		nullValue = true
@@ -630,7 +650,16 @@ func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *
 		type rater interface {
 			Rat(r *big.Rat) *big.Rat
 		}
+		var dec *rdb.Decimal
 		switch v := value.(type) {
+		case rdb.Decimal:
+			dec = &v
+		case *rdb.Decimal:
+			if v == nil {
+				_, err := w.Write(ctx, []byte{0})
+				return err
+			}
+			dec = v
 		case **big.Rat:
 			if v == nil || *v == nil {
 				_, err := w.Write(ctx, []byte{0})
@@ -652,6 +681,15 @@ func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *
 			}
 		}
 
+		if dec != nil {
+			payload, err := encodeDecimal(*dec, param.Scale)
+			if err != nil {
+				return fmt.Errorf("decimal value of (%s) too large for param %s %s", dec, param.Name, ti.TypeString(param))
+			}
+			w.WriteByte(byte(len(payload)))
+			w.WriteBuffer(payload)
+			return nil
+		}
 		// pow10 uses precomputed *big.Int (scale 0–38); no int64 overflow at scale 19+.
 		payload, err := encodeDecimalWire(&pv, param.Scale)
 		if err != nil {
@@ -745,9 +783,13 @@ func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *
 			if v.Before(minDateTime) {
 				return fmt.Errorf("time for @%s must be after %s", param.Name, minDateTime.String())
 			}
-			vNoTime := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, v.Location())
+			v = inLocation(v, loc)
+			// Count days and seconds from the wall clock so the value
+			// is not shifted by the offset of its location.
+			vNoTime := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
 			day := int64(vNoTime.Sub(zeroDateTime).Hours()) / 24
-			sec := v.Sub(vNoTime).Seconds()
+			hour, minute, second := v.Clock()
+			sec := float64(hour*3600+minute*60+second) + float64(v.Nanosecond())/1e9
 			w.WriteUint32(uint32(day))
 			w.WriteUint32(uint32(sec * 300))
 		default:
@@ -821,6 +863,8 @@ func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *
 		_, offset := v.Zone()
 		if (ti.Dt & dtZone) != 0 {
 			v = v.UTC()
+		} else if dur == 0 {
+			v = inLocation(v, loc)
 		}
 		days, seconds, ns := dateTime2(v)
 
@@ -857,7 +901,16 @@ func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *
 		type rater interface {
 			Rat(r *big.Rat) *big.Rat
 		}
+		var dec *rdb.Decimal
 		switch v := value.(type) {
+		case rdb.Decimal:
+			dec = &v
+		case *rdb.Decimal:
+			if v == nil {
+				w.WriteByte(0)
+				return nil
+			}
+			dec = v
 		case **big.Rat:
 			if v == nil || *v == nil {
 				w.WriteByte(0)
@@ -879,13 +932,27 @@ func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *
 		}
 
 		// Money is stored as value × 10000.
-		// Num / Denom == Value
-		// Num / Denom * 10000 == StoredValue
-		mult := big.NewInt(10000)
-		num := new(big.Int).Set(pv.Num())
-		denom := pv.Denom()
-		num.Mul(num, mult)
-		num.Div(num, denom)
+		var val int64
+		if dec != nil {
+			d, err := dec.Round(4, rdb.RoundDown)
+			neg, hi, lo := d.Uint128()
+			if err != nil || hi != 0 || lo > math.MaxInt64 {
+				return fmt.Errorf("money value of (%s) too large for param @%s", dec, param.Name)
+			}
+			val = int64(lo)
+			if neg {
+				val = -val
+			}
+		} else {
+			// Num / Denom == Value
+			// Num / Denom * 10000 == StoredValue
+			mult := big.NewInt(10000)
+			num := new(big.Int).Set(pv.Num())
+			denom := pv.Denom()
+			num.Mul(num, mult)
+			num.Div(num, denom)
+			val = num.Int64()
+		}
 
 		// Determine the width to use.
 		width := ti.W
@@ -904,11 +971,10 @@ func encodeValue(ctx context.Context, w *PacketWriter, ti paramTypeInfo, param *
 
 		if width == 4 {
 			// SmallMoney: 4 bytes, int32.
-			w.WriteUint32(uint32(num.Int64()))
+			w.WriteUint32(uint32(val))
 		} else {
 			// Money: 8 bytes, but stored in special format.
 			// SQL Server Money is stored as two 32-bit parts: high 4 bytes first, then low 4 bytes.
-			val := num.Int64()
 			high := uint32(val >> 32)
 			low := uint32(val)
 			w.WriteUint32(high)
@@ -1076,7 +1142,7 @@ func getParamTypeInfo(tdsVer *semver.Version, paramType rdb.Type) (paramTypeInfo
 	return ti, nil
 }
 
-func encodeParam(ctx context.Context, w *PacketWriter, truncValues bool, tdsVer *semver.Version, param *rdb.Param, value interface{}, collation [5]byte) error {
+func encodeParam(ctx context.Context, w *PacketWriter, truncValues bool, loc *time.Location, tdsVer *semver.Version, param *rdb.Param, value interface{}, collation [5]byte) error {
 	// Write field name.
 	if len(param.Name) == 0 {
 		w.WriteByte(0) // No name. Length zero.
@@ -1101,7 +1167,7 @@ func encodeParam(ctx context.Context, w *PacketWriter, truncValues bool, tdsVer
 	if err != nil {
 		return err
 	}
-	return encodeValue(ctx, w, ti, param, truncValues, value)
+	return encodeValue(ctx, w, ti, param, truncValues, loc, value)
 }
 
 type colFlags struct {
@@ -1648,7 +1714,7 @@ func (tds *Connection) decodeFieldValue(read uconv.PanicReader, column *SQLColum
 			dt := time.Duration(binary.LittleEndian.Uint32(bb))
 			tm := time.Duration(binary.LittleEndian.Uint32(bb[4:]))
 			t := time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
-			v := t.Add(time.Hour*24*dt + time.Millisecond*tm*1000/300)
+			v := wallClock(t.Add(time.Hour*24*dt+time.Millisecond*tm*1000/300), tds.loc)
 			if havePrep {
 				if doneDirect(rdb.DirectAssignTime(prep, v, false, defNull)) {
 					return
@@ -1661,9 +1727,9 @@ func (tds *Connection) decodeFieldValue(read uconv.PanicReader, column *SQLColum
 			high := int64(binary.LittleEndian.Uint32(bb[0:4]))
 			low := int64(binary.LittleEndian.Uint32(bb[4:8]))
 			rawVal := (high << 32) | low
-			v := big.NewRat(rawVal, 10000)
+			v := rdb.NewDecimal(rawVal, 4)
 			if havePrep {
-				if doneDirect(rdb.DirectAssignRat(prep, v, false, defNull)) {
+				if doneDirect(rdb.DirectAssignDecimal(prep, v, false, defNull)) {
 					return
 				}
 			}
@@ -1672,9 +1738,9 @@ func (tds *Connection) decodeFieldValue(read uconv.PanicReader, column *SQLColum
 		case typeMoneySmall:
 			// SmallMoney: 4 bytes int32, value × 10000.
 			rawVal := int64(int32(binary.LittleEndian.Uint32(bb)))
-			v := big.NewRat(rawVal, 10000)
+			v := rdb.NewDecimal(rawVal, 4)
 			if havePrep {
-				if doneDirect(rdb.DirectAssignRat(prep, v, false, defNull)) {
+				if doneDirect(rdb.DirectAssignDecimal(prep, v, false, defNull)) {
 					return
 				}
 			}
@@ -1745,10 +1811,15 @@ func (tds *Connection) decodeFieldValue(read uconv.PanicReader, column *SQLColum
 	}
 
 	if column.info.IsPrSc {
-		// Use pow10 (big.Int): getMult's int64 overflows for scale >= 19,
-		// which garbled values like decimal(38,20) 1234.567891 → ~15896.516.
-		all := read(dataLen)
-		v := decodeDecimalWire(all, column.Scale)
+		v, decErr := decodeDecimalWire(read(dataLen), column.Scale)
+		if decErr != nil {
+			panic(recoverError{err: fmt.Errorf("proto error Decimal: %w", decErr)})
+		}
+		if havePrep {
+			if doneDirect(rdb.DirectAssignDecimal(prep, v, false, defNull)) {
+				return
+			}
+		}
 		emit(false, v, false, false, false)
 		return
 	}
@@ -1782,16 +1853,26 @@ func (tds *Connection) decodeFieldValue(read uconv.PanicReader, column *SQLColum
 		switch dataLen {
 		case 4:
 			// SmallMoney: 4 bytes int32, value × 10000.
-			rawVal := int64(int32(binary.LittleEndian.Uint32(read(4))))
-			emit(false, big.NewRat(rawVal, 10000), false, false, false)
+			v := rdb.NewDecimal(int64(int32(binary.LittleEndian.Uint32(read(4)))), 4)
+			if havePrep {
+				if doneDirect(rdb.DirectAssignDecimal(prep, v, false, defNull)) {
+					return
+				}
+			}
+			emit(false, v, false, false, false)
 			return
 		case 8:
 			// Money: 8 bytes stored as high 4 bytes + low 4 bytes, value × 10000.
 			bb := read(8)
 			high := int64(binary.LittleEndian.Uint32(bb[0:4]))
 			low := int64(binary.LittleEndian.Uint32(bb[4:8]))
-			rawVal := (high << 32) | low
-			emit(false, big.NewRat(rawVal, 10000), false, false, false)
+			v := rdb.NewDecimal((high<<32)|low, 4)
+			if havePrep {
+				if doneDirect(rdb.DirectAssignDecimal(prep, v, false, defNull)) {
+					return
+				}
+			}
+			emit(false, v, false, false, false)
 			return
 		default:
 			panic(recoverError{err: fmt.Errorf("proto error MoneyN, unknown data len %d", dataLen)})
@@ -1806,7 +1887,7 @@ func (tds *Connection) decodeFieldValue(read uconv.PanicReader, column *SQLColum
 			// tmf counts 300 per second, from midnight.
 			tm := time.Duration(int64(tmf / 300 * 1000000000))
 
-			v := zeroDateTime.Add(dt).Add(tm)
+			v := wallClock(zeroDateTime.Add(dt).Add(tm), tds.loc)
 			emit(false, v, false, false, false)
 			return
 		default:
@@ -1838,11 +1919,17 @@ func (tds *Connection) decodeFieldValue(read uconv.PanicReader, column *SQLColum
 			}
 			return
 		}
-		if t, ok := v.(time.Time); ok {
-			emit(false, t, false, false, false)
-		} else {
-			emit(false, zeroDateN(time.UTC), false, false, false)
+		t, ok := v.(time.Time)
+		if !ok {
+			t = zeroDateN(time.UTC)
+		}
+		switch {
+		case column.info.Dt&dtZone == 0:
+			t = wallClock(t, tds.loc)
+		case tds.offsetLocation != nil:
+			t = t.In(tds.offsetLocation)
 		}
+		emit(false, t, false, false, false)
 		return
 	}
 
@@ -45,6 +45,9 @@ type Connection struct {
 	available bool
 	resetNext bool
 
+	// Set when CONTEXT_INFO was set by SetSession and must be cleared on Reset.
+	contextInfoSet bool
+
 	ProductVersion  *semver.Version
 	ProtocolVersion *semver.Version
 	Encrypted       bool
@@ -73,6 +76,12 @@ type Connection struct {
 	paramCollation    [5]byte // Collation bytes to send with text parameters.
 	preferUTF8Varchar bool    // Config opt-in: use varchar (UTF-8) instead of nvarchar (UTF-16).
 
+	// Time zone policy from the Config. loc is the location of the current
+	// command, either Command.Location or location.
+	location       *time.Location
+	offsetLocation *time.Location
+	loc            *time.Location
+
 	// Reused per-field value to avoid heap-allocating DriverValue on every cell.
 	dv rdb.DriverValue
 	// Reused UTF-8 decode output for NChar fields (paired with MustCopy).
@@ -80,6 +89,13 @@ type Connection struct {
 	// NBCROW null bitmap scratch: 16 bytes covers 128 columns (almost all
 	// result sets). Wider metadata falls back to a heap copy.
 	nbcNullScratch [16]byte
+
+	// Set while a row is paused at a PLP column streamed to a *io.Reader.
+	// rowNext is the next column to decode and rowNulls the NBCROW null
+	// bitmap of the row, nil for ROW.
+	stream   *plpReader
+	rowNext  int
+	rowNulls []byte
 }
 
 func NewConnection(c net.Conn, defaultResetTimeout, RollbackTimeout time.Duration) *Connection {
@@ -151,6 +167,8 @@ func (tds *Connection) Open(ctx context.Context, config *rdb.Config) (*ServerInf
 	var err error
 
 	tds.allHeaders, tds.allHeaderNumberOffset = getHeaderTemplate()
+	tds.location = config.Location
+	tds.offsetLocation = config.OffsetLocation
 
 	encrypt := encryptOn
 	if config.InsecureDisableEncryption {
@@ -160,7 +178,7 @@ func (tds *Connection) Open(ctx context.Context, config *rdb.Config) (*ServerInf
 		encrypt = encryptRequired
 	}
 
-	err = tds.pw.PreLogin(ctx, config.Instance, encrypt)
+	err = tds.pw.PreLogin(ctx, config.Instance, encrypt, len(config.AccessToken) > 0)
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +220,7 @@ func (tds *Connection) Open(ctx context.Context, config *rdb.Config) (*ServerInf
 	}
 
 	// Write LOGIN7 message.
-	err = tds.pw.Login(ctx, config)
+	err = tds.pw.Login(ctx, config, sc.FedAuthRequired)
 	if err != nil {
 		return nil, err
 	}
@@ -245,6 +263,8 @@ func (tds *Connection) OpenTDS8(ctx context.Context, config *rdb.Config) (*Serve
 	}
 
 	tds.allHeaders, tds.allHeaderNumberOffset = getHeaderTemplate()
+	tds.location = config.Location
+	tds.offsetLocation = config.OffsetLocation
 
 	// TDS 8.0: Establish TLS immediately with ALPN "tds/8.0".
 	tlsConfig := &tls.Config{
@@ -274,18 +294,18 @@ func (tds *Connection) OpenTDS8(ctx context.Context, config *rdb.Config) (*Serve
 
 	// TDS 8.0: PRELOGIN is sent over TLS (encryption already established).
 	// The encryption field in PRELOGIN is informational only.
-	err = tds.pw.PreLogin(ctx, config.Instance, encryptOn)
+	err = tds.pw.PreLogin(ctx, config.Instance, encryptOn, len(config.AccessToken) > 0)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = tds.pr.Prelogin(ctx)
+	sc, err := tds.pr.Prelogin(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Write LOGIN7 message.
-	err = tds.pw.Login(ctx, config)
+	err = tds.pw.Login(ctx, config, sc.FedAuthRequired)
 	if err != nil {
 		return nil, err
 	}
@@ -422,8 +442,14 @@ func (c passthroughConn) SetWriteDeadline(t time.Time) error {
 }
 
 func (tds *Connection) Reset(c *rdb.Config) error {
+	// The reset flag on the next request clears session context values.
 	tds.resetNext = true
-	if len(c.ResetQuery) == 0 {
+	resetQuery := c.ResetQuery
+	if tds.contextInfoSet {
+		tds.contextInfoSet = false
+		resetQuery = "set context_info 0x;\n" + resetQuery
+	}
+	if len(resetQuery) == 0 {
 		return nil
 	}
 	ctx := context.Background()
@@ -432,7 +458,7 @@ func (tds *Connection) Reset(c *rdb.Config) error {
 		ctx, cancel = context.WithTimeout(ctx, c.ResetConnectionTimeout)
 		defer cancel()
 	}
-	return tds.Query(ctx, &rdb.Command{SQL: c.ResetQuery}, nil, nil, nil)
+	return tds.Query(ctx, &rdb.Command{SQL: resetQuery}, nil, nil, nil)
 }
 
 func (tds *Connection) ConnectionInfo() *rdb.ConnectionInfo {
@@ -469,6 +495,44 @@ func (tds *Connection) Status() rdb.DriverConnStatus {
 	return status
 }
 
+// Ping implements rdb.DriverPinger. It sends the ping command without
+// reading any columns or rows into the caller.
+func (tds *Connection) Ping(ctx context.Context) error {
+	val := &errValuer{}
+	err := tds.Query(ctx, pingCommand, nil, nil, val)
+	if err == nil {
+		err = tds.NextQuery(ctx)
+	}
+	if err == nil && len(val.errs) > 0 {
+		err = val.errs
+	}
+	return err
+}
+
+// Cancel implements rdb.DriverCanceler. It sends an attention signal to
+// stop the running command. The pending query returns once the server
+// acknowledges the attention.
+func (tds *Connection) Cancel() error {
+	if tds.Status() != rdb.StatusQuery {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tds.rollbackTimeout)
+	defer cancel()
+	return tds.sendAttention(ctx)
+}
+
+// sendAttention sends an attention packet from a goroutine other than the
+// one running the command. The packet writer is held from BeginMessage until
+// the message is written, so the attention is never interleaved with a
+// message the command is still sending.
+func (tds *Connection) sendAttention(ctx context.Context) error {
+	err := tds.pw.BeginMessage(ctx, packetAttention, false)
+	if err != nil {
+		return err
+	}
+	return tds.pw.EndMessage(ctx)
+}
+
 func (tds *Connection) Prepare(*rdb.Command) (preparedStatementToken interface{}, err error) {
 	return nil, rdb.ErrNotImplemented
 }
@@ -612,6 +676,18 @@ func (noopValuer) WriteField(c *rdb.Column, value *rdb.DriverValue, assign rdb.A
 }
 func (noopValuer) RowsAffected(count uint64) {}
 
+// errValuer discards rows but keeps server errors.
+type errValuer struct {
+	noopValuer
+	errs rdb.Errors
+}
+
+func (v *errValuer) Message(msg *rdb.Message) {
+	if msg.Type == rdb.SqlError {
+		v.errs = append(v.errs, msg)
+	}
+}
+
 func (tds *Connection) Query(ctx context.Context, cmd *rdb.Command, params []rdb.Param, preparedToken interface{}, valuer rdb.DriverValuer) error {
 	if debugAPI {
 		fmt.Printf("API Query\n")
@@ -627,6 +703,10 @@ func (tds *Connection) Query(ctx context.Context, cmd *rdb.Command, params []rdb
 		valuer = noopValuer{}
 	}
 	tds.val = valuer
+	tds.loc = tds.location
+	if cmd.Location != nil {
+		tds.loc = cmd.Location
+	}
 
 	if tds.mr != nil && !tds.mr.packetEOM {
 		return fmt.Errorf("connection not ready to be re-used yet for query")
@@ -667,20 +747,10 @@ func (tds *Connection) asyncWaitCancel(ctx context.Context, cancelTimeout time.D
 		cancelContext, stopCancelContext := context.WithTimeout(context.Background(), cancelTimeout)
 		defer stopCancelContext()
 
-		err := tds.pw.BeginMessage(cancelContext, packetAttention, false)
-		if err != nil {
-			// TODO: Determine a better error path.
-			log.Printf("Cancel begin message: %v", err)
-			select {
-			case <-tds.onDone:
-			case <-tds.onClose:
-			}
-			return
-		}
-		err = tds.pw.EndMessage(cancelContext)
+		err := tds.sendAttention(cancelContext)
 		if err != nil {
 			// TODO: Determine a better error path.
-			log.Printf("Cancel end message: %v\n", err)
+			log.Printf("Cancel message: %v", err)
 		}
 		select {
 		case <-tds.onDone:
@@ -768,6 +838,7 @@ func (tds *Connection) done() error {
 
 	mrCloseErr := tds.mr.Close()
 	tds.params = nil
+	tds.closeStream()
 
 	tds.syncClose.Lock()
 	tds.col = nil
@@ -809,14 +880,32 @@ func (tds *Connection) scan(ctx context.Context) error {
 	}
 	tds.syncClose.Unlock()
 
+	return tds.scanTokens(ctx, false)
+}
+
+// FinishRow implements rdb.DriverRowFinisher. It discards the rest of the
+// open column stream and decodes the remaining columns of the row.
+func (tds *Connection) FinishRow(ctx context.Context) error {
+	if tds.stream == nil {
+		return nil
+	}
+	return tds.scanTokens(ctx, true)
+}
+
+// scanTokens reads tokens up to the next row or the end of the result.
+// A row paused at a column stream is finished first; its remaining columns
+// are reported if finish is set and skipped otherwise.
+func (tds *Connection) scanTokens(ctx context.Context, finish bool) error {
 	var lastMessage *rdb.Message
 	hasCol := false
+	skipRow := tds.stream != nil && !finish
 	for {
 		var res interface{}
 		var err error
 		withLock(&tds.syncClose, func() {
-			res, err = tds.getSingleResponse(ctx, tds.mr, true)
+			res, err = tds.getSingleResponse(ctx, tds.mr, !skipRow)
 		})
+		skipRow = false
 		if err != nil {
 			// Protocol/parse errors often leave the stream mid-row. Force a
 			// RESETCONNECTION on the next use so the pool does not hand out a
@@ -848,6 +937,18 @@ func (tds *Connection) scan(ctx context.Context) error {
 			// The prior prep values are no longer valid as they are filled
 			// during the row scan.
 			tds.val.RowScanned()
+			if tds.stream != nil {
+				// Paused at a column stream.
+				return nil
+			}
+		case MsgRowEnd:
+			if tds.stream != nil {
+				// Paused at a later column stream.
+				return nil
+			}
+			if !finish {
+				continue
+			}
 		case MsgRowCount:
 			tds.val.RowsAffected(v.Count)
 		case MsgOrder:
@@ -1059,11 +1160,11 @@ func (tds *Connection) sendRPC(ctx context.Context, sql string, truncValue bool,
 			}
 			fmt.Fprintf(paramNames, "@%s %s", param.Name, st.TypeString(param))
 		}
-		err = encodeParam(ctx, w, truncValue, tds.ProtocolVersion, rpcHeaderParam, []byte(sql), tds.paramCollation)
+		err = encodeParam(ctx, w, truncValue, tds.loc, tds.ProtocolVersion, rpcHeaderParam, []byte(sql), tds.paramCollation)
 		if err != nil {
 			return err
 		}
-		err = encodeParam(ctx, w, truncValue, tds.ProtocolVersion, rpcHeaderParam, paramNames.Bytes(), tds.paramCollation)
+		err = encodeParam(ctx, w, truncValue, tds.loc, tds.ProtocolVersion, rpcHeaderParam, paramNames.Bytes(), tds.paramCollation)
 		if err != nil {
 			return err
 		}
@@ -1078,7 +1179,7 @@ func (tds *Connection) sendRPC(ctx context.Context, sql string, truncValue bool,
 		param := &params[i]
 		adjusted := *param
 		adjusted.Type = tds.adjustParamType(param.Type)
-		err = encodeParam(ctx, w, truncValue, tds.ProtocolVersion, &adjusted, param.Value, tds.paramCollation)
+		err = encodeParam(ctx, w, truncValue, tds.loc, tds.ProtocolVersion, &adjusted, param.Value, tds.paramCollation)
 		if err != nil {
 			return err
 		}
@@ -1168,7 +1269,7 @@ loop:
 		w.WriteByte(byte(tokenRow))
 		for i, p := range params {
 			ti := meta[i]
-			err = encodeValue(ctx, w, ti, &p, truncValue, p.Value)
+			err = encodeValue(ctx, w, ti, &p, truncValue, tds.loc, p.Value)
 			if err != nil {
 				return false, err
 			}
@@ -1213,6 +1314,12 @@ func (tds *Connection) getSingleResponse(ctx context.Context, m *MessageReader,
 		}
 		return bb
 	}
+	if tds.stream != nil {
+		tds.stream.drain(read)
+		tds.closeStream()
+		tds.decodeRow(ctx, read, reportRow)
+		return MsgRowEnd{}, nil
+	}
 	tokenBuf, err := m.Fetch(ctx, 1)
 	if err != nil {
 		if len(tokenBuf) != 1 && err == io.EOF {
@@ -1248,6 +1355,7 @@ func (tds *Connection) getSingleResponse(ctx context.Context, m *MessageReader,
 		sqlMsg.LineNumber = int32(binary.LittleEndian.Uint32(read(4)))
 		sqlMsg.State = state
 		sqlMsg.Class = class
+		sqlMsg.Err = classify(sqlMsg)
 
 		return sqlMsg, nil
 	case tokenColumnMetaData:
@@ -1310,10 +1418,9 @@ func (tds *Connection) getSingleResponse(ctx context.Context, m *MessageReader,
 		}
 		return msg, nil
 	case tokenRow:
-		for _, column := range tds.col {
-			tds.decodeFieldValue(read, column, tds.val.WriteField, reportRow)
-		}
-
+		tds.rowNext = 0
+		tds.rowNulls = nil
+		tds.decodeRow(ctx, read, reportRow)
 		return MsgRow{}, nil
 	case tokenNBCRow:
 		// Copy the null bitmap out of msgBuf immediately. decodeFieldValue may
@@ -1330,19 +1437,9 @@ func (tds *Connection) getSingleResponse(ctx context.Context, m *MessageReader,
 		} else {
 			nulls = append([]byte(nil), read(byteLen)...)
 		}
-		for i, column := range tds.col {
-			if nulls[i/8]&(1<<(uint(i)%8)) != 0 {
-				err = tds.val.WriteField(&column.Column, &rdb.DriverValue{
-					Null: true,
-				}, nil)
-				if err != nil {
-					panic(recoverError{err: err})
-				}
-				continue
-			}
-			tds.decodeFieldValue(read, column, tds.val.WriteField, reportRow)
-		}
-
+		tds.rowNext = 0
+		tds.rowNulls = nulls
+		tds.decodeRow(ctx, read, reportRow)
 		return MsgRow{}, nil
 	case tokenOrder:
 		// Just read the token.
@@ -5,7 +5,9 @@
 package ms
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"reflect"
 	"testing"
 	"time"
@@ -283,3 +285,103 @@ func TestDateTZ(t *testing.T) {
 		t.Fatalf("wanted %q, got %q", wantDate, dOut)
 	}
 }
+
+// decodeTestValue decodes the value of one column of type code from payload.
+func decodeTestValue(t *testing.T, tds *Connection, code driverType, payload []byte) interface{} {
+	t.Helper()
+	column := &SQLColumn{code: code, info: typeInfoLookup[code]}
+	column.Scale = 7
+	var got interface{}
+	wf := func(c *rdb.Column, value *rdb.DriverValue, assign rdb.Assigner) error {
+		got = value.Value
+		return nil
+	}
+	read := func(n int) []byte {
+		b := payload[:n]
+		payload = payload[n:]
+		return b
+	}
+	tds.decodeFieldValue(read, column, wf, true)
+	return got
+}
+
+// encodeTestValue returns the value of param as written on the wire.
+func encodeTestValue(t *testing.T, loc *time.Location, param *rdb.Param) []byte {
+	t.Helper()
+	ctx := context.Background()
+	sink := &bytes.Buffer{}
+	w := NewPacketWriter(&deadlineNop{w: sink})
+	ti, err := getParamTypeInfo(protoVer74, param.Type)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.BeginMessage(ctx, packetRPC, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := encodeValue(ctx, w, ti, param, false, loc, param.Value); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.EndMessage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return sink.Bytes()[8:]
+}
+
+func TestDateTimeLocation(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	// Sent as the wall clock time in Chicago, 10:30 CDT.
+	wire := encodeTestValue(t, chicago, &rdb.Param{Type: rdb.TypeTimestamp, Value: at})
+	got := decodeTestValue(t, &Connection{}, typeDateTime2N, wire)
+	if want := time.Date(2026, 3, 10, 10, 30, 0, 0, time.UTC); got != want {
+		t.Errorf("no location: got %v, want %v", got, want)
+	}
+	got = decodeTestValue(t, &Connection{loc: chicago}, typeDateTime2N, wire)
+	if tm, ok := got.(time.Time); !ok || !tm.Equal(at) || tm.Location() != chicago {
+		t.Errorf("chicago: got %v, want %v", got, at.In(chicago))
+	}
+
+	// Without a location the value keeps its own wall clock time.
+	wire = encodeTestValue(t, nil, &rdb.Param{Type: rdb.TypeTimestamp, Value: at.In(chicago)})
+	got = decodeTestValue(t, &Connection{}, typeDateTime2N, wire)
+	if want := time.Date(2026, 3, 10, 10, 30, 0, 0, time.UTC); got != want {
+		t.Errorf("param location: got %v, want %v", got, want)
+	}
+
+	wire = encodeTestValue(t, chicago, &rdb.Param{Type: rdb.TypeDate, Value: time.Date(2026, 3, 11, 2, 0, 0, 0, time.UTC)})
+	got = decodeTestValue(t, &Connection{loc: chicago}, typeDateN, wire)
+	if want := time.Date(2026, 3, 10, 0, 0, 0, 0, chicago); got != want {
+		t.Errorf("date: got %v, want %v", got, want)
+	}
+
+	// 2026-03-10 10:30 as datetime.
+	payload := []byte{0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(payload, uint32(at.Sub(zeroDateTime).Hours()/24))
+	binary.LittleEndian.PutUint32(payload[4:], (10*3600+30*60)*300)
+	got = decodeTestValue(t, &Connection{loc: chicago}, typeDateTime, payload)
+	if want := time.Date(2026, 3, 10, 10, 30, 0, 0, chicago); got != want {
+		t.Errorf("datetime: got %v, want %v", got, want)
+	}
+	got = decodeTestValue(t, &Connection{loc: chicago}, typeDateTimeN, append([]byte{8}, payload...))
+	if want := time.Date(2026, 3, 10, 10, 30, 0, 0, chicago); got != want {
+		t.Errorf("datetimen: got %v, want %v", got, want)
+	}
+
+	// The offset of datetimeoffset is kept unless an offset location is set.
+	plus2 := time.FixedZone("", 2*60*60)
+	wire = encodeTestValue(t, chicago, &rdb.Param{Type: rdb.TypeTimestampz, Value: at.In(plus2)})
+	got = decodeTestValue(t, &Connection{loc: chicago}, typeDateTimeOffsetN, wire)
+	if tm, ok := got.(time.Time); !ok || !tm.Equal(at) {
+		t.Errorf("offset: got %v, want %v", got, at)
+	} else if _, offset := tm.Zone(); offset != 2*60*60 {
+		t.Errorf("offset: got zone offset %d", offset)
+	}
+	got = decodeTestValue(t, &Connection{offsetLocation: chicago}, typeDateTimeOffsetN, wire)
+	if tm, ok := got.(time.Time); !ok || !tm.Equal(at) || tm.Location() != chicago {
+		t.Errorf("offset location: got %v, want %v", got, at.In(chicago))
+	}
+}
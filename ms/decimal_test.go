@@ -53,14 +53,17 @@ func TestDecimalWireHighScale(t *testing.T) {
 	if err != nil {
 		t.Fatalf("encode: %v", err)
 	}
-	out := decodeDecimalWire(payload, scale)
+	out, err := decodeDecimalWire(payload, scale)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
 
-	if out.Cmp(in) != 0 {
-		t.Fatalf("round-trip: got %s, want %s", out.FloatString(scale), in.FloatString(scale))
+	if out.Rat(nil).Cmp(in) != 0 {
+		t.Fatalf("round-trip: got %s, want %s", out, in.FloatString(scale))
 	}
 	// Explicit check against the reported value.
-	if out.FloatString(6) != "1234.567891" {
-		t.Fatalf("got %s, want 1234.567891", out.FloatString(6))
+	if out.Rat(nil).FloatString(6) != "1234.567891" {
+		t.Fatalf("got %s, want 1234.567891", out)
 	}
 }
 
@@ -92,10 +95,25 @@ func TestDecimalWireRoundTrip(t *testing.T) {
 			if err != nil {
 				t.Fatalf("encode: %v", err)
 			}
-			out := decodeDecimalWire(payload, tc.scale)
+			out, err := decodeDecimalWire(payload, tc.scale)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
 			// Compare at the declared scale (truncation toward zero on encode).
-			if out.FloatString(tc.scale) != in.FloatString(tc.scale) {
-				t.Fatalf("got %s, want %s (payload=%x)", out.FloatString(tc.scale), in.FloatString(tc.scale), payload)
+			if out.String() != in.FloatString(tc.scale) {
+				t.Fatalf("got %s, want %s (payload=%x)", out, in.FloatString(tc.scale), payload)
+			}
+
+			d, err := rdb.ParseDecimal(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decPayload, err := encodeDecimal(d, tc.scale)
+			if err != nil {
+				t.Fatalf("encode decimal: %v", err)
+			}
+			if string(decPayload) != string(payload) {
+				t.Fatalf("decimal payload %x, want %x", decPayload, payload)
 			}
 		})
 	}
@@ -159,13 +177,13 @@ func TestDecimalHighScaleSQL(t *testing.T) {
 				} else {
 					got = gotLiteral
 				}
-				r, ok := got.(*big.Rat)
+				d, ok := got.(rdb.Decimal)
 				if !ok {
 					t.Errorf("%s: unexpected type %T", name, got)
 					continue
 				}
-				if r.FloatString(tc.scale) != want {
-					t.Errorf("%s: got %s, want %s (sql string=%v)", name, r.FloatString(tc.scale), want, gotStr)
+				if d.String() != want {
+					t.Errorf("%s: got %s, want %s (sql string=%v)", name, d, want, gotStr)
 				}
 			}
 		})
@@ -73,5 +73,41 @@ To prevent truncation, set TEXTSIZE in your connection's ResetQuery configuratio
 	}
 
 Reference: https://learn.microsoft.com/en-us/sql/t-sql/statements/set-textsize-transact-sql
+
+# Session Context
+
+Values set with rdb.WithSessionContext are applied with sp_set_session_context
+each time a connection is checked out, and rdb.WithContextInfo sets CONTEXT_INFO.
+Both are cleared before the connection is used by another request.
+
+	ctx = rdb.WithSessionContext(ctx, map[string]any{"tenant_id": tenantID})
+	res, err := db.Query(ctx, cmd)
+
+# Database Mirroring
+
+Set the failover_partner DSN option to connect to the mirror when the
+principal refuses the login or reports the database is acting as a mirror
+or is restoring. The principal is tried again every failback_interval.
+
+	ms://app@principal/?db=app&failover_partner=mirror
+
+# List Parameters
+
+A slice parameter, other than []byte, is expanded to one parameter per value:
+
+	cmd := &rdb.Command{SQL: `select * from Account where ID in (@ids);`}
+	res, err := db.Query(ctx, cmd, rdb.Param{Name: "ids", Value: []int64{1, 2, 3}})
+
+Lists longer than the list_param_max DSN option are sent as one JSON array
+and read with OPENJSON, which requires database compatibility level 130.
+Values must be numbers, strings, or other values SQL Server can convert
+from their JSON form.
+
+# database/sql
+
+The driver is also registered with database/sql as "ms":
+
+	db, err := sql.Open("ms", "ms://app@localhost/SqlExpress?db=app")
+	rows, err := db.QueryContext(ctx, `select * from Account where ID = @id;`, sql.Named("id", 2))
 */
 package ms
@@ -404,10 +404,10 @@ func TestDockerDecimalPrecision(t *testing.T) {
 
 			// Verify the rat value round-trips correctly
 			// Parse expected value fresh (the input rat may be modified by encoder)
-			if r, ok := gotRat.(*big.Rat); ok {
+			if d, ok := gotRat.(rdb.Decimal); ok {
 				expectedRat := new(big.Rat)
 				expectedRat.SetString(tc.value)
-				gotStr := r.FloatString(tc.scale)
+				gotStr := d.String()
 				wantStr := expectedRat.FloatString(tc.scale)
 				if gotStr != wantStr {
 					t.Errorf("rat value mismatch: got %v, want %v", gotStr, wantStr)
@@ -7,7 +7,9 @@ package ms
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"strconv"
@@ -202,9 +204,40 @@ func (dr *Driver) DriverInfo() *rdb.DriverInfo {
 			Notification:     false,
 			UserDataTypes:    false,
 		},
+		InferParamType: inferParamType,
+		ListSQL:        listSQL,
 	}
 }
 
+// listSQL selects the values of a JSON array parameter with OPENJSON,
+// which requires database compatibility level 130 or later.
+func listSQL(name string, elem *rdb.Param) string {
+	typeName := "nvarchar(max)"
+	if st, ok := sqlTypeLookup[elem.Type]; ok {
+		typeName = st.TypeString(elem)
+	}
+	return fmt.Sprintf("select v from openjson(@%s) with (v %s '$')", name, typeName)
+}
+
+// inferParamType infers parameter types, sending JSON as text.
+func inferParamType(param *rdb.Param) error {
+	err := rdb.InferParamType(param)
+	if err != nil {
+		return err
+	}
+	if param.Type == rdb.TypeJSON {
+		param.Type = rdb.TypeVarChar
+		v, _ := param.Value.(json.RawMessage)
+		if !param.Null {
+			param.Value = string(v)
+		}
+		if param.Length == 0 && len(v) <= rdb.InferTextLength {
+			param.Length = rdb.InferTextLength
+		}
+	}
+	return nil
+}
+
 var pingCommand = &rdb.Command{
 	SQL:   "select top 0 1;",
 	Arity: rdb.ZeroMust,
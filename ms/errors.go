@@ -7,6 +7,9 @@ package ms
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/kardianos/rdb"
 )
 
 var connectionOpenError = errors.New("Connection already open")
@@ -29,3 +32,116 @@ type InputToolong struct {
 func (err InputToolong) Error() string {
 	return fmt.Sprintf("Value too long: data length is %d bytes, type lenth is %d bytes", err.DataLen, err.TypeLen)
 }
+
+// transientErrors are server error numbers where retrying the transaction
+// on a new or existing connection may succeed.
+var transientErrors = map[int32]bool{
+	1205:  true, // Transaction was deadlocked and chosen as the deadlock victim.
+	1222:  true, // Lock request time out period exceeded.
+	3960:  true, // Snapshot isolation transaction aborted due to update conflict.
+	3961:  true, // Snapshot isolation transaction failed, object modified in another session.
+	4060:  true, // Cannot open database requested by the login.
+	4221:  true, // Login to read-secondary failed due to long wait on HADR.
+	10928: true, // Resource limit reached.
+	10929: true, // Resource limit reached, minimum guarantee.
+	10053: true, // Transport-level error receiving results.
+	10054: true, // Transport-level error sending the request.
+	10060: true, // Network-related error establishing a connection.
+	40143: true, // Connection could not be initialized.
+	40197: true, // Service error processing the request.
+	40501: true, // Service is currently busy.
+	40540: true, // Service has encountered an error processing the request.
+	40613: true, // Database is not currently available.
+	49918: true, // Not enough resources to process the request.
+	49919: true, // Too many create or update operations in progress.
+	49920: true, // Too many operations in progress.
+}
+
+// Transient implements rdb.ErrorClassifier.
+func (dr *Driver) Transient(msg *rdb.Message) bool {
+	return transientErrors[msg.Number]
+}
+
+// classify maps a server error message to a *rdb.ServerError.
+// Returns nil if the message number is not recognized.
+func classify(msg *rdb.Message) error {
+	if msg.Type != rdb.SqlError {
+		return nil
+	}
+	text := msg.Message
+	se := &rdb.ServerError{Msg: msg}
+	switch msg.Number {
+	default:
+		return nil
+	case 2627:
+		// Violation of PRIMARY KEY constraint 'PK_T'. Cannot insert duplicate key in object 'dbo.T'. ...
+		se.Kind = rdb.ErrUniqueViolation
+		se.Constraint = quotedAfter(text, "constraint ")
+		se.Object = quotedAfter(text, "object ")
+	case 2601:
+		// Cannot insert duplicate key row in object 'dbo.T' with unique index 'IX_T'. ...
+		se.Kind = rdb.ErrUniqueViolation
+		se.Constraint = quotedAfter(text, "unique index ")
+		se.Object = quotedAfter(text, "object ")
+	case 547:
+		// The INSERT statement conflicted with the FOREIGN KEY constraint "FK_T". ... table "dbo.T", column 'ID'.
+		// The same number is used for CHECK constraints, which are not classified.
+		if !strings.Contains(text, "FOREIGN KEY") && !strings.Contains(text, "REFERENCE constraint") {
+			return nil
+		}
+		se.Kind = rdb.ErrForeignKey
+		se.Constraint = quotedAfter(text, "constraint ")
+		se.Object = quotedAfter(text, "table ")
+	case 1205:
+		se.Kind = rdb.ErrDeadlock
+	case 18456, 18452, 18486, 18487, 18488:
+		// Login failed for user 'U'.
+		se.Kind = rdb.ErrLoginFailed
+		se.Object = quotedAfter(text, "user ")
+	case 4060:
+		// Cannot open database "D" requested by the login. The login failed.
+		se.Kind = rdb.ErrLoginFailed
+		se.Object = quotedAfter(text, "database ")
+	case 927, 942, 954, 955, 976, 978, 983:
+		// Database 'D' cannot be opened. It is in the middle of a restore.
+		// The database "D" cannot be opened. It is acting as a mirror database.
+		se.Kind = rdb.ErrUnavailable
+		se.Object = quotedAfter(text, "database ")
+		if len(se.Object) == 0 {
+			se.Object = quotedAfter(text, "Database ")
+		}
+	case 229, 230, 300:
+		// The SELECT permission was denied on the object 'T', database 'D', schema 'dbo'.
+		se.Kind = rdb.ErrPermission
+		se.Object = quotedAfter(text, "object ")
+	case 262:
+		// CREATE TABLE permission denied in database 'D'.
+		se.Kind = rdb.ErrPermission
+		se.Object = quotedAfter(text, "database ")
+	case 297, 15247:
+		se.Kind = rdb.ErrPermission
+	}
+	return se
+}
+
+// quotedAfter returns the first single or double quoted name
+// directly following prefix in text.
+func quotedAfter(text, prefix string) string {
+	i := strings.Index(text, prefix)
+	if i < 0 {
+		return ""
+	}
+	text = text[i+len(prefix):]
+	if len(text) == 0 {
+		return ""
+	}
+	q := text[0]
+	if q != '\'' && q != '"' {
+		return ""
+	}
+	end := strings.IndexByte(text[1:], q)
+	if end < 0 {
+		return ""
+	}
+	return text[1 : end+1]
+}
@@ -0,0 +1,61 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package ms
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kardianos/rdb"
+)
+
+func TestClassifyMessage(t *testing.T) {
+	list := []struct {
+		Number     int32
+		Text       string
+		Kind       error
+		Constraint string
+		Object     string
+	}{
+		{2627, `Violation of PRIMARY KEY constraint 'PK_Account'. Cannot insert duplicate key in object 'dbo.Account'. The duplicate key value is (1).`, rdb.ErrUniqueViolation, "PK_Account", "dbo.Account"},
+		{2601, `Cannot insert duplicate key row in object 'dbo.Account' with unique index 'IX_Account_Name'. The duplicate key value is (a).`, rdb.ErrUniqueViolation, "IX_Account_Name", "dbo.Account"},
+		{547, `The INSERT statement conflicted with the FOREIGN KEY constraint "FK_Order_Account". The conflict occurred in database "app", table "dbo.Account", column 'ID'.`, rdb.ErrForeignKey, "FK_Order_Account", "dbo.Account"},
+		{547, `The INSERT statement conflicted with the CHECK constraint "CK_Amount".`, nil, "", ""},
+		{1205, `Transaction (Process ID 52) was deadlocked on lock resources with another process and has been chosen as the deadlock victim. Rerun the transaction.`, rdb.ErrDeadlock, "", ""},
+		{18456, `Login failed for user 'app'. (1, 14)`, rdb.ErrLoginFailed, "", "app"},
+		{954, `The database "app" cannot be opened. It is acting as a mirror database.`, rdb.ErrUnavailable, "", "app"},
+		{927, `Database 'app' cannot be opened. It is in the middle of a restore.`, rdb.ErrUnavailable, "", "app"},
+		{229, `The SELECT permission was denied on the object 'Account', database 'app', schema 'dbo'.`, rdb.ErrPermission, "", "Account"},
+		{208, `Invalid object name 'Account'.`, nil, "", ""},
+	}
+	for _, item := range list {
+		msg := &rdb.Message{Type: rdb.SqlError, Number: item.Number, Message: item.Text}
+		msg.Err = classify(msg)
+		var err error = fmt.Errorf("query: %w", rdb.Errors{msg})
+
+		if item.Kind == nil {
+			if msg.Err != nil {
+				t.Errorf("%d: expected no classification, got %v", item.Number, msg.Err)
+			}
+			continue
+		}
+		if !errors.Is(err, item.Kind) {
+			t.Errorf("%d: expected errors.Is %v, got %v", item.Number, item.Kind, msg.Err)
+			continue
+		}
+		var se *rdb.ServerError
+		if !errors.As(err, &se) {
+			t.Errorf("%d: expected *rdb.ServerError", item.Number)
+			continue
+		}
+		if se.Constraint != item.Constraint || se.Object != item.Object {
+			t.Errorf("%d: got constraint %q object %q, want %q %q", item.Number, se.Constraint, se.Object, item.Constraint, item.Object)
+		}
+		if se.Msg != msg {
+			t.Errorf("%d: expected original message", item.Number)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package ms
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestAppendFedAuthToken(t *testing.T) {
+	for _, echo := range []bool{false, true} {
+		options := fedAuthLibrarySecurityToken << 1
+		if echo {
+			options |= 0x01
+		}
+		got := appendFedAuthToken(nil, "ab", echo)
+		want := []byte{
+			featureIDFedAuth,
+			9, 0, 0, 0, // FeatureDataLen.
+			options,
+			4, 0, 0, 0, // Token length.
+			'a', 0, 'b', 0,
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("echo %t: got % x\nwant % x", echo, got, want)
+		}
+	}
+}
+
+func TestPreloginFedAuth(t *testing.T) {
+	ctx := context.Background()
+	for _, fedAuth := range []bool{false, true} {
+		sink := &bytes.Buffer{}
+		w := NewPacketWriter(&deadlineNop{w: sink})
+		if err := w.PreLogin(ctx, "", encryptOn, fedAuth); err != nil {
+			t.Fatal(err)
+		}
+		// Read the request back as if the server echoed the same options.
+		packet := buildTDSPacket(packetTabularResult, sink.Bytes()[8:])
+		pr := NewPacketReader(&deadlineNop{r: bytes.NewReader(packet)})
+		sc, err := pr.Prelogin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sc.FedAuthRequired != fedAuth {
+			t.Fatalf("fedAuth %t: got FedAuthRequired %t", fedAuth, sc.FedAuthRequired)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/kardianos/rdb"
@@ -24,6 +25,7 @@ const (
 	preloginEncryption = 0x01
 	preloginInstance   = 0x02
 	preloginMars       = 0x04
+	preloginFedAuth    = 0x06
 	preloginTerminator = 0xff
 )
 
@@ -51,8 +53,11 @@ const (
 )
 
 const (
+	featureIDFedAuth     byte = 0x02
 	featureIDUTF8Support byte = 0x0A
 	featureIDTerminator  byte = 0xFF
+
+	fedAuthLibrarySecurityToken byte = 0x01
 )
 
 // Document the highest version this driver can handle.
@@ -67,8 +72,9 @@ const (
 	encryptRequired     EncryptAvailable = 3 // Encryption is required.
 )
 
-// Pre-Login
-func (tds *PacketWriter) PreLogin(ctx context.Context, instance string, encrypt EncryptAvailable) error {
+// Pre-Login. Set fedAuth to request FEDAUTHREQUIRED when an access token
+// will be sent in LOGIN7.
+func (tds *PacketWriter) PreLogin(ctx context.Context, instance string, encrypt EncryptAvailable, fedAuth bool) error {
 	var err error
 	type option struct {
 		t byte
@@ -93,6 +99,9 @@ func (tds *PacketWriter) PreLogin(ctx context.Context, instance string, encrypt
 	if len(instance) > 0 {
 		addToken(preloginInstance, uconv.Encode.FromString(instance))
 	}
+	if fedAuth {
+		addToken(preloginFedAuth, []byte{0x01})
+	}
 
 	tds.BeginMessage(ctx, packetPreLogin, false)
 
@@ -134,6 +143,10 @@ type ServerConnection struct {
 	Encryption EncryptAvailable
 	Instance   string
 	MARS       bool
+
+	// FedAuthRequired is the server FEDAUTHREQUIRED response. It must be
+	// echoed in the LOGIN7 FEDAUTH feature extension.
+	FedAuthRequired bool
 }
 
 // Returned from Login.
@@ -207,6 +220,10 @@ func (tds *PacketReader) Prelogin(ctx context.Context) (*ServerConnection, error
 			if o.d[0] != 0 {
 				si.MARS = true
 			}
+		case preloginFedAuth:
+			if len(o.d) > 0 && o.d[0] != 0 {
+				si.FedAuthRequired = true
+			}
 		default:
 			// Ignore.
 		}
@@ -216,7 +233,8 @@ func (tds *PacketReader) Prelogin(ctx context.Context) (*ServerConnection, error
 }
 
 // Write LOGIN7. Page 53.
-func (tds *PacketWriter) Login(ctx context.Context, config *rdb.Config) error {
+// fedAuthEcho is the FEDAUTHREQUIRED value returned by the server in PRELOGIN.
+func (tds *PacketWriter) Login(ctx context.Context, config *rdb.Config, fedAuthEcho bool) error {
 	var err error
 	/*
 		Versions:
@@ -290,15 +308,22 @@ func (tds *PacketWriter) Login(ctx context.Context, config *rdb.Config) error {
 
 	// TODO: Check max lengths, truncate if too long.
 	writeToken(0, uconv.Encode.FromString(config.Hostname), true)
-	writeToken(1, uconv.Encode.FromString(config.Username), true)
-
-	passwordBytes := uconv.Encode.FromString(config.Password)
-	for i, b := range passwordBytes {
-		passwordBytes[i] = ((b << 4) | (b >> 4)) ^ 0xA5
+	if len(config.AccessToken) > 0 {
+		// The token is sent in the FEDAUTH feature extension instead.
+		writeToken(1, nil, true)
+		writeToken(2, nil, true)
+	} else {
+		writeToken(1, uconv.Encode.FromString(config.Username), true)
+
+		passwordBytes := uconv.Encode.FromString(config.Password)
+		for i, b := range passwordBytes {
+			passwordBytes[i] = ((b << 4) | (b >> 4)) ^ 0xA5
+		}
+		writeToken(2, passwordBytes, true) // The password is obfuscated here.
 	}
-	writeToken(2, passwordBytes, true) // The password is obfuscated here.
 
-	writeToken(3, uconv.Encode.FromString(""), true) // AppName - Name of the client application.
+	appName, _ := config.KV[rdb.OptApplicationName].(string)
+	writeToken(3, uconv.Encode.FromString(appName), true) // AppName - Name of the client application.
 	writeToken(4, uconv.Encode.FromString(config.Instance), true)
 
 	// Token 5 - Extension (ibExtension): 4-byte ibFeatureExtLong DWORD.
@@ -335,8 +360,11 @@ func (tds *PacketWriter) Login(ctx context.Context, config *rdb.Config) error {
 		featureIDUTF8Support,       // FeatureId = 0x0A (UTF8_SUPPORT)
 		0x01, 0x00, 0x00, 0x00,    // FeatureDataLen = 1
 		0x01,                       // FeatureData: request UTF-8
-		featureIDTerminator,        // 0xFF terminator
 	}
+	if len(config.AccessToken) > 0 {
+		featureExtData = appendFedAuthToken(featureExtData, config.AccessToken, fedAuthEcho)
+	}
+	featureExtData = append(featureExtData, featureIDTerminator)
 	at += len(featureExtData)
 
 	buf := make([]byte, at)
@@ -351,6 +379,9 @@ func (tds *PacketWriter) Login(ctx context.Context, config *rdb.Config) error {
 	buf[24] = 0    // OptionFlags1.
 	buf[25] = 0    // OptionFlags2.
 	buf[26] = 1    // TypeFlags. Flip first bit to use TSQL.
+	if intent, _ := config.KV[rdb.OptApplicationIntent].(string); strings.EqualFold(intent, "ReadOnly") {
+		buf[26] |= 0x20 // fReadOnlyIntent.
+	}
 	buf[27] = 0x10 // OptionFlags3: fExtension bit (bit 4) — FeatureExt is present.
 
 	_, zone := time.Now().Zone()
@@ -398,6 +429,24 @@ func (tds *PacketWriter) Login(ctx context.Context, config *rdb.Config) error {
 	return nil
 }
 
+// appendFedAuthToken appends the FEDAUTH feature extension with an access
+// token using the security token library. echo is the server
+// FEDAUTHREQUIRED PRELOGIN response.
+func appendFedAuthToken(buf []byte, token string, echo bool) []byte {
+	tokenBytes := uconv.Encode.FromString(token)
+	dataLen := 1 + 4 + len(tokenBytes)
+
+	options := fedAuthLibrarySecurityToken << 1 // bFedAuthLibrary.
+	if echo {
+		options |= 0x01 // fFedAuthEcho.
+	}
+	buf = append(buf, featureIDFedAuth)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(dataLen))
+	buf = append(buf, options)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(tokenBytes)))
+	return append(buf, tokenBytes...)
+}
+
 func (tds *PacketReader) LoginAck(ctx context.Context) (*ServerInfo, error) {
 	// Page 95.
 	read := tds.BeginMessage(ctx, packetTabularResult)
@@ -447,6 +496,7 @@ func (tds *PacketReader) LoginAck(ctx context.Context) (*ServerInfo, error) {
 
 			sqlMsg.LineNumber = int32(binary.LittleEndian.Uint32(bb[at:]))
 			at += 4
+			sqlMsg.Err = classify(sqlMsg)
 			return nil, rdb.Errors{sqlMsg}
 		}
 		return nil, fmt.Errorf("expected type %X but got %X", tokenLoginAck, bb[at])
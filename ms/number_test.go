@@ -143,7 +143,7 @@ select S = isnull(convert(nvarchar(100), @D), 'NULL');
 	for _, item := range list {
 		t.Run(item.Name, func(t *testing.T) {
 			res := db.Query(context.Background(), cmd,
-				rdb.Param{Name: "V", Type: rdb.Decimal, Precision: 38, Scale: item.Scale, Value: item.Input},
+				rdb.Param{Name: "V", Type: rdb.Numeric, Precision: 38, Scale: item.Scale, Value: item.Input},
 			)
 			defer res.Close()
 
@@ -206,7 +206,7 @@ func TestNullNumbers(t *testing.T) {
 	}
 
 	params := []rdb.Param{
-		{Name: "decimal", Type: rdb.Decimal, Value: nil, Precision: 38, Scale: 6, Null: true},
+		{Name: "decimal", Type: rdb.Numeric, Value: nil, Precision: 38, Scale: 6, Null: true},
 	}
 
 	res := db.Query(context.Background(), cmd, params...)
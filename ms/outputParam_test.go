@@ -101,7 +101,7 @@ end
 	db.Query(context.Background(), callProc,
 		rdb.Param{Name: "p1", Out: true, Value: &val1, Type: rdb.Text},
 		rdb.Param{Name: "p2", Out: true, Value: &val2, Type: rdb.Integer},
-		rdb.Param{Name: "p3", Out: true, Value: &val3, Precision: 38, Scale: 7, Type: rdb.Decimal},
+		rdb.Param{Name: "p3", Out: true, Value: &val3, Precision: 38, Scale: 7, Type: rdb.Numeric},
 	)
 	if val1 != "Hello" {
 		t.Fatalf("Incorrect value. Want 'Hello' was %v", val1)
@@ -13,6 +13,10 @@ import (
 
 type MsgEom struct{}
 type MsgRow struct{}
+
+// MsgRowEnd is sent after the rest of a row paused at a column stream is read.
+type MsgRowEnd struct{}
+
 type MsgColumn struct{}
 type MsgFinalDone struct{}
 type MsgCancel struct {
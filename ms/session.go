@@ -0,0 +1,92 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package ms
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kardianos/rdb"
+)
+
+// SetSession implements rdb.DriverSessionConn. Values are set with
+// sp_set_session_context and the context info with SET CONTEXT_INFO in a
+// single batch. Values are cleared by the connection reset before the
+// connection is used again.
+func (tds *Connection) SetSession(ctx context.Context, s *rdb.Session) error {
+	keys := make([]string, 0, len(s.Values))
+	for k := range s.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sql := &strings.Builder{}
+	params := make([]rdb.Param, 0, len(keys)*2+1)
+	for i, k := range keys {
+		v := s.Values[k]
+		vt, length, err := sessionValueType(v)
+		if err != nil {
+			return fmt.Errorf("session value %q: %w", k, err)
+		}
+		n := strconv.Itoa(i)
+		fmt.Fprintf(sql, "exec sp_set_session_context @k%s, @v%s;\n", n, n)
+		params = append(params,
+			rdb.Param{Name: "k" + n, Type: rdb.TypeVarChar, Length: 128, Value: k},
+			rdb.Param{Name: "v" + n, Type: vt, Length: length, Value: v, Null: v == nil},
+		)
+	}
+	if len(s.ContextInfo) > 0 {
+		if len(s.ContextInfo) > 128 {
+			return fmt.Errorf("context info is %d bytes, must be at most 128 bytes", len(s.ContextInfo))
+		}
+		sql.WriteString("set context_info @ci;\n")
+		params = append(params, rdb.Param{Name: "ci", Type: rdb.TypeBinary, Length: 128, Value: s.ContextInfo})
+		tds.contextInfoSet = true
+	}
+
+	val := &errValuer{}
+	err := tds.Query(ctx, &rdb.Command{SQL: sql.String(), Arity: rdb.Zero, Name: "session"}, params, nil, val)
+	if err == nil {
+		err = tds.NextQuery(ctx)
+	}
+	if err == nil && len(val.errs) > 0 {
+		err = val.errs
+	}
+	return err
+}
+
+// sessionValueType returns the parameter type for a session value.
+// Session values are stored as sql_variant, which does not allow max types.
+func sessionValueType(v any) (rdb.Type, int, error) {
+	switch v.(type) {
+	case nil:
+		return rdb.TypeVarChar, 1, nil
+	case string:
+		return rdb.TypeVarChar, 4000, nil
+	case []byte:
+		return rdb.TypeBinary, 8000, nil
+	case bool:
+		return rdb.TypeBool, 0, nil
+	case int8, uint8:
+		return rdb.TypeInt16, 0, nil
+	case int16:
+		return rdb.TypeInt16, 0, nil
+	case int32, uint16:
+		return rdb.TypeInt32, 0, nil
+	case int, int64, uint32:
+		return rdb.TypeInt64, 0, nil
+	case float32:
+		return rdb.TypeFloat32, 0, nil
+	case float64:
+		return rdb.TypeFloat64, 0, nil
+	case time.Time:
+		return rdb.TypeTimestampz, 0, nil
+	}
+	return rdb.TypeUnknown, 0, fmt.Errorf("unsupported session value type %T", v)
+}
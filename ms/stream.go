@@ -0,0 +1,200 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package ms
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+
+	"github.com/kardianos/rdb"
+	"github.com/kardianos/rdb/internal/uconv"
+)
+
+// maxDrainRead limits each read when discarding the rest of a stream so a
+// large PLP chunk is not buffered in whole.
+const maxDrainRead = 32 * 1024
+
+// plpReader streams a PLP value (varbinary(max), varchar(max), nvarchar(max))
+// from the message reader into a *io.Reader Prep destination. The row is
+// paused at the column until the stream is closed by reading a later column,
+// the next row, or the end of the query.
+//
+// nvarchar(max) is decoded to UTF-8 one chunk at a time.
+type plpReader struct {
+	tds   *Connection
+	mr    *MessageReader
+	ctx   context.Context
+	nchar bool
+
+	left   int  // Unread bytes of the current chunk.
+	done   bool // The terminating zero length chunk was read.
+	eof    bool // The whole value was read by the caller.
+	closed bool
+
+	// Decoded UTF-8 of an nvarchar chunk not yet read.
+	buf     []byte
+	scratch []byte
+}
+
+func (s *plpReader) Read(p []byte) (n int, err error) {
+	if s.eof {
+		return 0, io.EOF
+	}
+	if s.closed {
+		return 0, rdb.ErrStreamClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	withLock(&s.tds.syncClose, func() {
+		n, err = s.read(p)
+	})
+	return n, err
+}
+
+func (s *plpReader) read(p []byte) (n int, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			re, is := recovered.(recoverError)
+			if !is {
+				panic(recovered)
+			}
+			err = re.err
+		}
+	}()
+	read := func(n int) []byte {
+		bb, err := s.mr.Fetch(s.ctx, n)
+		if err != nil {
+			panic(recoverError{err: err})
+		}
+		return bb
+	}
+	for len(s.buf) == 0 {
+		if s.left == 0 {
+			s.left = int(binary.LittleEndian.Uint32(read(4)))
+			if s.left == 0 {
+				s.done = true
+				s.eof = true
+				s.tds.ucs2HasNext = false
+				return 0, io.EOF
+			}
+		}
+		if !s.nchar {
+			size := min(s.left, len(p))
+			s.left -= size
+			return copy(p, read(size)), nil
+		}
+		// Decode the whole chunk so a surrogate pair is only split where
+		// the server split it.
+		size := s.left
+		s.left = 0
+		s.buf = s.tds.appendPLPNCharUTF8(s.scratch[:0], read, size)
+		s.scratch = s.buf[:0]
+	}
+	n = copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// drain discards the unread part of the stream.
+func (s *plpReader) drain(read uconv.PanicReader) {
+	for !s.done {
+		if s.left == 0 {
+			s.left = int(binary.LittleEndian.Uint32(read(4)))
+			if s.left == 0 {
+				s.done = true
+				break
+			}
+		}
+		size := min(s.left, maxDrainRead)
+		read(size)
+		s.left -= size
+	}
+	s.buf = nil
+	s.tds.ucs2HasNext = false
+}
+
+// closeStream closes the open column stream, if any. Later reads of the
+// stream return rdb.ErrStreamClosed.
+func (tds *Connection) closeStream() {
+	if tds.stream == nil {
+		return
+	}
+	tds.stream.closed = true
+	tds.stream = nil
+}
+
+// decodeRow decodes the columns of the current row from rowNext. If reportRow
+// is set, the row is paused at a PLP column prepared with *io.Reader and
+// tds.stream is set.
+func (tds *Connection) decodeRow(ctx context.Context, read uconv.PanicReader, reportRow bool) {
+	for tds.rowNext < len(tds.col) {
+		i := tds.rowNext
+		column := tds.col[i]
+		tds.rowNext++
+		if tds.rowNulls != nil && tds.rowNulls[i/8]&(1<<(uint(i)%8)) != 0 {
+			err := tds.val.WriteField(&column.Column, &rdb.DriverValue{
+				Null: true,
+			}, nil)
+			if err != nil {
+				panic(recoverError{err: err})
+			}
+			continue
+		}
+		if reportRow && tds.streamPrep(column) {
+			if tds.openStream(ctx, read, column) {
+				return
+			}
+			continue
+		}
+		tds.decodeFieldValue(read, column, tds.val.WriteField, reportRow)
+	}
+	tds.rowNulls = nil
+}
+
+// streamPrep reports whether column is a PLP column prepared with *io.Reader.
+// XML is decoded in whole as it may need converting from BINXML.
+func (tds *Connection) streamPrep(column *SQLColumn) bool {
+	if !column.Unlimit || column.code == typeXml {
+		return false
+	}
+	prep, _, ok := tds.directPrep(column)
+	if !ok {
+		return false
+	}
+	_, ok = prep.(*io.Reader)
+	return ok
+}
+
+// openStream reads the PLP length of column and reports a stream of its value.
+// Returns false if the value is NULL and the row was not paused.
+func (tds *Connection) openStream(ctx context.Context, read uconv.PanicReader, column *SQLColumn) bool {
+	totalSize := binary.LittleEndian.Uint64(read(8))
+	if totalSize == textNULL {
+		err := tds.val.WriteField(&column.Column, &rdb.DriverValue{
+			Null: true,
+		}, nil)
+		if err != nil {
+			panic(recoverError{err: err})
+		}
+		return false
+	}
+	tds.ucs2HasNext = false
+	tds.stream = &plpReader{
+		tds:   tds,
+		mr:    tds.mr,
+		ctx:   ctx,
+		nchar: column.info.NChar,
+	}
+	err := tds.val.WriteField(&column.Column, &rdb.DriverValue{
+		Value:  tds.stream,
+		Stream: true,
+	}, nil)
+	if err != nil {
+		panic(recoverError{err: err})
+	}
+	return true
+}
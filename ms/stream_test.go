@@ -0,0 +1,187 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package ms
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/kardianos/rdb"
+	"github.com/kardianos/rdb/internal/uconv"
+)
+
+// streamValuer records field values and sets *io.Reader Prep destinations
+// from streamed fields.
+type streamValuer struct {
+	prep   []interface{}
+	values []interface{}
+	rows   int
+	done   bool
+}
+
+func (v *streamValuer) Columns(cc []*rdb.Column) error {
+	v.prep = make([]interface{}, len(cc))
+	v.values = make([]interface{}, len(cc))
+	return nil
+}
+func (v *streamValuer) Done() error          { v.done = true; return nil }
+func (v *streamValuer) RowScanned()          { v.rows++ }
+func (v *streamValuer) Message(*rdb.Message) {}
+func (v *streamValuer) RowsAffected(uint64)  {}
+func (v *streamValuer) PrepAt(index int) interface{} {
+	return v.prep[index]
+}
+func (v *streamValuer) HasConverter(int) bool     { return false }
+func (v *streamValuer) FieldNull(int) interface{} { return nil }
+func (v *streamValuer) WriteField(c *rdb.Column, value *rdb.DriverValue, assign rdb.Assigner) error {
+	if value.Stream {
+		*v.prep[c.Index].(*io.Reader) = value.Value.(io.Reader)
+		return nil
+	}
+	v.values[c.Index] = value.Value
+	if v.prep[c.Index] == nil {
+		return nil
+	}
+	return rdb.AssignValue(c, rdb.Nullable{Null: value.Null, Value: value.Value}, v.prep[c.Index], assign)
+}
+
+// plpRowStream builds a result of an int, an nvarchar(max), and an int column.
+// Each text is sent in PLP chunks of chunk bytes; a nil text is NULL.
+func plpRowStream(texts []*string, chunk int) []byte {
+	var body bytes.Buffer
+	intCol := func(name string) {
+		binary.Write(&body, binary.LittleEndian, uint32(0))
+		body.Write([]byte{0x01, 0x00})
+		body.WriteByte(byte(typeIntN))
+		body.WriteByte(4)
+		n := uconv.Encode.FromString(name)
+		body.WriteByte(byte(len(n) / 2))
+		body.Write(n)
+	}
+	body.WriteByte(byte(tokenColumnMetaData))
+	binary.Write(&body, binary.LittleEndian, uint16(3))
+	intCol("a")
+	binary.Write(&body, binary.LittleEndian, uint32(0))
+	body.Write([]byte{0x01, 0x00})
+	body.WriteByte(byte(typeNVarChar))
+	binary.Write(&body, binary.LittleEndian, uint16(0xFFFF))
+	body.Write([]byte{0x09, 0x04, 0xD0, 0x00, 0x34})
+	n := uconv.Encode.FromString("data")
+	body.WriteByte(byte(len(n) / 2))
+	body.Write(n)
+	intCol("b")
+
+	for i, text := range texts {
+		body.WriteByte(byte(tokenRow))
+		body.WriteByte(4)
+		binary.Write(&body, binary.LittleEndian, int32(i*2))
+		if text == nil {
+			binary.Write(&body, binary.LittleEndian, uint64(textNULL))
+		} else {
+			u16 := uconv.Encode.FromString(*text)
+			binary.Write(&body, binary.LittleEndian, uint64(len(u16)))
+			for len(u16) > 0 {
+				size := min(chunk, len(u16))
+				binary.Write(&body, binary.LittleEndian, uint32(size))
+				body.Write(u16[:size])
+				u16 = u16[size:]
+			}
+			binary.Write(&body, binary.LittleEndian, uint32(0))
+		}
+		body.WriteByte(4)
+		binary.Write(&body, binary.LittleEndian, int32(i*2+1))
+	}
+
+	body.WriteByte(byte(tokenDone))
+	binary.Write(&body, binary.LittleEndian, uint16(0))
+	binary.Write(&body, binary.LittleEndian, uint16(0))
+	binary.Write(&body, binary.LittleEndian, uint64(len(texts)))
+	return buildMultiPacket(packetTabularResult, body.Bytes())
+}
+
+func TestStreamPLP(t *testing.T) {
+	ctx := context.Background()
+	long := string(bytes.Repeat([]byte("héllo wörld 😀 "), 2000))
+	first, third := "first value", "never read"
+	stream := plpRowStream([]*string{&long, nil, &first, &third}, 4095)
+
+	pr := NewPacketReader(&deadlineNop{r: bytes.NewReader(stream)})
+	val := &streamValuer{}
+	tds := &Connection{pr: pr, mr: pr.BeginMessage(ctx, packetTabularResult), val: val, status: rdb.StatusQuery}
+
+	if err := tds.scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var rd io.Reader
+	val.prep[1] = &rd
+
+	// Read the whole value one byte at a time; the odd chunk size splits
+	// UTF-16 code units between chunks.
+	if err := tds.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if val.values[0] != int32(0) || val.values[2] != nil {
+		t.Fatalf("row paused at the stream: %v", val.values)
+	}
+	bb, err := io.ReadAll(iotest.OneByteReader(rd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bb) != long {
+		t.Errorf("got %d bytes, want %d", len(bb), len(long))
+	}
+	if err := tds.FinishRow(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if val.values[2] != int32(1) {
+		t.Errorf("after the stream: %v", val.values[2])
+	}
+
+	// NULL does not pause the row.
+	if err := tds.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rd != nil || val.values[2] != int32(3) {
+		t.Errorf("null row: %v, %v", rd, val.values[2])
+	}
+
+	// FinishRow discards the rest of a partly read stream.
+	if err := tds.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if n, err := rd.Read(buf); err != nil || string(buf[:n]) != "first" {
+		t.Fatalf("read %q, %v", buf[:n], err)
+	}
+	if err := tds.FinishRow(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if val.values[2] != int32(5) {
+		t.Errorf("after the partial stream: %v", val.values[2])
+	}
+	if _, err := rd.Read(buf); !errors.Is(err, rdb.ErrStreamClosed) {
+		t.Errorf("got %v, want ErrStreamClosed", err)
+	}
+
+	// Scan skips the rest of an unread row and finds the end of the result.
+	if err := tds.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	unread := rd
+	if err := tds.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unread.Read(buf); !errors.Is(err, rdb.ErrStreamClosed) {
+		t.Errorf("got %v, want ErrStreamClosed", err)
+	}
+	if !val.done || val.rows != 4 || tds.Status() != rdb.StatusReady {
+		t.Errorf("done %t, rows %d, status %d", val.done, val.rows, tds.Status())
+	}
+}
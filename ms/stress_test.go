@@ -7,7 +7,6 @@ package ms
 import (
 	"context"
 	"fmt"
-	"math/big"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -343,7 +342,7 @@ ORDER BY arity.DateDue ASC, arity.ID ASC;
 				switch schema[i].Name {
 				case "HourDurationWindow", "MoneyAmt", "DaySpanDue":
 					if v != nil {
-						if _, ok := v.(*big.Rat); !ok {
+						if _, ok := v.(rdb.Decimal); !ok {
 							return fmt.Errorf("col %s type %T", schema[i].Name, v)
 						}
 					}
@@ -7,6 +7,7 @@ package ms
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"math/big"
 	"time"
 
@@ -160,10 +161,10 @@ var typeInfoLookup = map[driverType]typeInfo{
 	typeInt32:         {Name: "Int32", Fixed: true, Len: 4, Specific: rdb.TypeInt32, Generic: rdb.Integer},
 	typeDateTimeSmall: {Name: "DateTimeSmall", Fixed: true, Len: 4, Specific: rdb.TypeTimestamp, Generic: rdb.Integer},
 	typeFloat32:       {Name: "Float32", Fixed: true, Len: 4, Specific: rdb.TypeFloat32, Generic: rdb.Float},
-	typeMoney:         {Name: "Money", Fixed: true, Len: 8, Specific: rdb.TypeDecimal, Generic: rdb.Decimal},
+	typeMoney:         {Name: "Money", Fixed: true, Len: 8, Specific: rdb.TypeDecimal, Generic: rdb.Numeric},
 	typeDateTime:      {Name: "DateTime", Fixed: true, Len: 8, Specific: rdb.TypeTimestamp, Generic: rdb.Time},
 	typeFloat64:       {Name: "Float64", Fixed: true, Len: 8, Specific: rdb.TypeFloat64, Generic: rdb.Float},
-	typeMoneySmall:    {Name: "MoneySmall", Fixed: true, Len: 4, Specific: rdb.TypeDecimal, Generic: rdb.Decimal},
+	typeMoneySmall:    {Name: "MoneySmall", Fixed: true, Len: 4, Specific: rdb.TypeDecimal, Generic: rdb.Numeric},
 	typeInt64:         {Name: "Int64", Fixed: true, Len: 8, Specific: rdb.TypeInt64, Generic: rdb.Integer},
 
 	typeGuid: {Name: "GUID", Len: 1, Specific: rdb.TypeUUID, Generic: rdb.Other},
@@ -174,14 +175,14 @@ var typeInfoLookup = map[driverType]typeInfo{
 		8: rdb.TypeInt64,
 	}, Generic: rdb.Integer},
 	typeBitN:    {Name: "BitN", Len: 1, Specific: rdb.TypeBool, Generic: rdb.Bool},
-	typeDecimal: {Name: "Decimal", IsPrSc: true, Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Decimal},
-	typeNumeric: {Name: "Numeric", IsPrSc: true, Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Decimal},
+	typeDecimal: {Name: "Decimal", IsPrSc: true, Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Numeric},
+	typeNumeric: {Name: "Numeric", IsPrSc: true, Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Numeric},
 
 	typeFloatN: {Name: "FloatN", Len: 1, SpecificMap: map[byte]rdb.Type{
 		4: rdb.TypeFloat32,
 		8: rdb.TypeFloat64,
 	}, Generic: rdb.Float},
-	typeMoneyN:          {Name: "MoneyN", Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Decimal},
+	typeMoneyN:          {Name: "MoneyN", Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Numeric},
 	typeDateTimeN:       {Name: "DateTimeN", Len: 1, Specific: rdb.TypeTimestamp, MinVer: protoVer72, Generic: rdb.Time},
 	// TDS 7.3 TYPE_INFO: DATE = VARLENTYPE only (Len 0); TIME/DATETIME2/DTO = VARLENTYPE SCALE (Len 1).
 	// Row values still use TYPE_VARBYTE BYTELEN (GEN_NULL or length + payload).
@@ -191,8 +192,8 @@ var typeInfoLookup = map[driverType]typeInfo{
 	typeDateTimeOffsetN: {Name: "DateTimeOffsetN", Len: 1, Specific: rdb.TypeTimestampz, Dt: dtDate | dtTime | dtZone, MinVer: protoVer73A, Generic: rdb.Time},
 
 	// Probably don't worry about these.
-	typeDecimalOld:   {Name: "DecimalOld", Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Decimal},
-	typeNumericOld:   {Name: "NumericOld", Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Decimal},
+	typeDecimalOld:   {Name: "DecimalOld", Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Numeric},
+	typeNumericOld:   {Name: "NumericOld", Len: 1, Specific: rdb.TypeDecimal, Generic: rdb.Numeric},
 	typeCharOld:      {Name: "CharOld", Len: 1, Specific: rdb.TypeAnsiChar, Generic: rdb.Text},
 	typeVarCharOld:   {Name: "VarCharOld", Len: 1, Specific: rdb.TypeAnsiVarChar, Generic: rdb.Text},
 	typeBinaryOld:    {Name: "BinaryOld", Len: 1, Specific: rdb.TypeBinary, Generic: rdb.Binary},
@@ -420,41 +421,48 @@ func pow10(scale int) *big.Int {
 
 // decodeDecimalWire decodes a TDS decimal/numeric value payload:
 // byte 0 = sign (1 positive, 0 negative), remaining bytes = little-endian integer
-// representing the value × 10^scale.
-//
-// Allocations: one *big.Int (magnitude) and one *big.Rat (result). The scale
-// denominator is shared from powersOf10.
-func decodeDecimalWire(payload []byte, scale int) *big.Rat {
+// representing the value × 10^scale. The integer is at most 16 bytes, so the
+// value decodes into an rdb.Decimal without allocating.
+func decodeDecimalWire(payload []byte, scale int) (rdb.Decimal, error) {
 	if len(payload) == 0 {
-		return new(big.Rat)
+		return rdb.Decimal{}, nil
 	}
-	signByte := payload[0]
 	le := payload[1:]
-	n := len(le)
-
-	// Reverse little-endian → big-endian. TDS max integer is 16 bytes; allow
-	// larger defensively so a corrupt length cannot panic mid-row (which leaves
-	// the connection desynced and surfaces as "unknown token peek: tdsToken(0)").
-	var beStack [16]byte
-	var be []byte
-	if n <= 16 {
-		be = beStack[:n]
-	} else {
-		be = make([]byte, n)
-	}
-	for i := 0; i < n; i++ {
-		be[i] = le[n-1-i]
+	if len(le) > 16 {
+		return rdb.Decimal{}, fmt.Errorf("decimal integer of %d bytes exceeds 16", len(le))
 	}
+	var b [16]byte
+	copy(b[:], le)
+	return rdb.DecimalFromUint128(payload[0] == 0, binary.LittleEndian.Uint64(b[8:]), binary.LittleEndian.Uint64(b[:8]), scale)
+}
 
-	mag := new(big.Int).SetBytes(be)
-	if signByte == 0 {
-		mag.Neg(mag)
+// encodeDecimal encodes d as a TDS decimal/numeric value payload for the given
+// scale, truncating extra fractional digits like encodeDecimalWire.
+// Returns the payload (sign byte + little-endian integer, no length prefix).
+func encodeDecimal(d rdb.Decimal, scale int) ([]byte, error) {
+	d, err := d.Round(scale, rdb.RoundDown)
+	if err != nil {
+		return nil, err
+	}
+	neg, hi, lo := d.Uint128()
+	sign := byte(1)
+	if neg {
+		sign = 0
 	}
-	if scale <= 0 {
-		return new(big.Rat).SetInt(mag)
+	dataLen := 16
+	switch {
+	case hi == 0 && lo <= math.MaxUint32:
+		dataLen = 4
+	case hi == 0:
+		dataLen = 8
+	case hi <= math.MaxUint32:
+		dataLen = 12
 	}
-	// value = mag / 10^scale. SetFrac copies mag; shared pow10 is not mutated.
-	return new(big.Rat).SetFrac(mag, pow10(scale))
+	payload := make([]byte, 1+16)
+	payload[0] = sign
+	binary.LittleEndian.PutUint64(payload[1:], lo)
+	binary.LittleEndian.PutUint64(payload[9:], hi)
+	return payload[:1+dataLen], nil
 }
 
 // encodeDecimalWire encodes r as a TDS decimal/numeric value payload for the given scale.
@@ -556,7 +564,7 @@ var sqlTypeLookup = map[rdb.Type]typeWidth{
 
 	rdb.TypeDecimal: {T: typeDecimal, SqlName: "decimal"},
 	TypeNumeric:     {T: typeNumeric, SqlName: "decimal"},
-	rdb.Decimal:     {T: typeDecimal, SqlName: "decimal"},
+	rdb.Numeric:     {T: typeDecimal, SqlName: "decimal"},
 
 	rdb.TypeFloat32: {T: typeFloatN, W: 4, SqlName: "float"},
 	rdb.TypeFloat64: {T: typeFloatN, W: 8, SqlName: "float"},
@@ -10,9 +10,10 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"math/big"
 	"time"
 	"unicode/utf16"
+
+	"github.com/kardianos/rdb"
 )
 
 // MS-BINXML token constants.
@@ -676,8 +677,7 @@ func (d *binxmlDecoder) readAtomicValueString(token byte) (string, error) {
 			return "", err
 		}
 		val := int64(binary.LittleEndian.Uint64(bb))
-		r := big.NewRat(val, 10000)
-		return r.FloatString(4), nil
+		return rdb.NewDecimal(val, 4).String(), nil
 
 	case binxmlSQLSMALLMONEY:
 		bb := make([]byte, 4)
@@ -685,8 +685,7 @@ func (d *binxmlDecoder) readAtomicValueString(token byte) (string, error) {
 			return "", err
 		}
 		val := int64(int32(binary.LittleEndian.Uint32(bb)))
-		r := big.NewRat(val, 10000)
-		return r.FloatString(4), nil
+		return rdb.NewDecimal(val, 4).String(), nil
 
 	case binxmlSQLDECIMAL, binxmlSQLNUMERIC, binxmlXSDDECIMAL:
 		return d.readDecimal()
@@ -833,7 +832,11 @@ func (d *binxmlDecoder) readDecimal() (string, error) {
 	copy(payload[1:], bb)
 
 	_ = prec // precision not used for formatting
-	return decodeDecimalWire(payload, int(scale)).FloatString(int(scale)), nil
+	v, err := decodeDecimalWire(payload, int(scale))
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
 }
 
 func (d *binxmlDecoder) readXSDDate() (string, error) {
@@ -87,6 +87,19 @@ func (must ConnPool) Close() {
 	must.norm.Close()
 }
 
+// Shutdown waits for checked out connections to be released before
+// closing the pool. Panics if ctx is done first.
+func (must ConnPool) Shutdown(ctx context.Context) {
+	err := must.norm.Shutdown(ctx)
+	if err != nil {
+		panic(Error{Err: err})
+	}
+}
+
+func (must ConnPool) Drain() {
+	must.norm.Drain()
+}
+
 func (must ConnPool) Valid() bool {
 	return must.norm != nil
 }
@@ -145,6 +158,10 @@ func (must ConnPool) PoolAvailable() (capacity, available int) {
 	return must.norm.PoolAvailable()
 }
 
+func (must ConnPool) Stats() rdb.PoolStats {
+	return must.norm.Stats()
+}
+
 // Input parameter values can either be specified in the paremeter definition
 // or on each query. If the value is not put in the parameter definition
 // then the command instance may be reused for every query.
@@ -158,6 +175,17 @@ func (must Transaction) Query(ctx context.Context, cmd *rdb.Command, params ...r
 	}
 }
 
+// Same as Begin but will panic on an error.
+func (must Transaction) Begin() Transaction {
+	tran, err := must.norm.Begin()
+	if err != nil {
+		panic(Error{Err: err})
+	}
+	return Transaction{
+		norm: tran,
+	}
+}
+
 func (must Transaction) Commit() {
 	err := must.norm.Commit()
 	if err != nil {
@@ -182,6 +210,15 @@ func (must Transaction) SavePoint(name string) {
 		panic(Error{Err: err})
 	}
 }
+func (must Transaction) BeforeCommit(f func(ctx context.Context) error) {
+	must.norm.BeforeCommit(f)
+}
+func (must Transaction) OnCommit(f func()) {
+	must.norm.OnCommit(f)
+}
+func (must Transaction) OnRollback(f func(err error)) {
+	must.norm.OnRollback(f)
+}
 func (must Transaction) Active() bool {
 	if must.norm == nil {
 		return false
@@ -4,14 +4,20 @@
 
 package must
 
-import "context"
+import (
+	"context"
+	"errors"
+
+	"github.com/kardianos/rdb"
+)
 
 type Roller func(t Transaction, savepoint string)
 
 // A method to take many panicing members and return a normal error.
 // The Roller function will rollback to an existing savepoint if it has not
 // already been commited. An empty savepoint parameter to Roller will roll
-// the transaction back entirely.
+// the transaction back entirely. Registered transactions are also rolled
+// back if f panics.
 /*
 	func ExampleRun() error {
 		return rdb.Run(func(r rdb.Roller) error {
@@ -34,42 +40,78 @@ type Roller func(t Transaction, savepoint string)
 func Run(ctx context.Context, f func(r Roller) error) (err error) {
 	trans := make(map[Transaction]string)
 	defer func() {
-		if recovered := recover(); recovered != nil {
+		recovered := recover()
+		terr := roll(trans)
+		if recovered != nil {
 			if must, is := recovered.(Error); is {
 				err = must.Err
 				return
 			}
 			panic(recovered)
 		}
+		if err == nil {
+			err = terr
+		}
 	}()
 	err = f(func(t Transaction, savepoint string) {
 		trans[t] = savepoint
 	})
+	return
+}
+
+// RunRetry is like Run, but calls f again if the returned error is
+// classified as transient, such as a deadlock, by the driver of cp.
+// Registered transactions are rolled back before each retry.
+// See rdb.RunInTx for the retry and backoff behavior.
+func RunRetry(ctx context.Context, cp ConnPool, opts *rdb.TxOptions, f func(r Roller) error) error {
+	return rdb.Retry(ctx, cp.Normal(), opts, func() error {
+		return Run(ctx, f)
+	})
+}
+
+func roll(trans map[Transaction]string) error {
 	var terr error
-	for t, savepoint := range trans {
-		if !t.Active() {
-			continue
-		}
-		var loopErr error
-		nt := t.Normal()
-		if len(savepoint) == 0 {
-			loopErr = nt.Rollback()
+	// A transaction cannot end while a transaction nested in it is active,
+	// so repeat until no more transactions end.
+	for ended := true; ended; {
+		ended = false
+		for t, savepoint := range trans {
+			if !t.Active() {
+				delete(trans, t)
+				continue
+			}
+			loopErr := rollOne(t.Normal(), savepoint)
+			if errors.Is(loopErr, rdb.ErrNestedActive) {
+				if t.Active() {
+					continue
+				}
+				// Rolled back with the nested transactions.
+				loopErr = nil
+			}
+			ended = true
+			delete(trans, t)
 			if terr == nil {
 				terr = loopErr
 			}
-			continue
-		}
-		loopErr = nt.RollbackTo(savepoint)
-		if terr == nil {
-			terr = loopErr
-		}
-		loopErr = nt.Commit()
-		if terr == nil {
-			terr = loopErr
 		}
 	}
+	if terr == nil && len(trans) > 0 {
+		terr = rdb.ErrNestedActive
+	}
+	return terr
+}
+
+func rollOne(nt *rdb.Transaction, savepoint string) error {
+	if len(savepoint) == 0 {
+		return nt.Rollback()
+	}
+	err := nt.RollbackTo(savepoint)
+	if errors.Is(err, rdb.ErrNestedActive) {
+		return err
+	}
+	cerr := nt.Commit()
 	if err == nil {
-		err = terr
+		err = cerr
 	}
-	return
+	return err
 }
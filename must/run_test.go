@@ -0,0 +1,72 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package must
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kardianos/rdb"
+)
+
+// retryDriver classifies deadlock messages as transient. It never opens
+// a connection.
+type retryDriver struct{}
+
+func (d *retryDriver) DriverInfo() *rdb.DriverInfo { return &rdb.DriverInfo{} }
+func (d *retryDriver) PingCommand() *rdb.Command   { return &rdb.Command{Arity: rdb.Zero} }
+func (d *retryDriver) Open(ctx context.Context, c *rdb.Config) (rdb.DriverConn, error) {
+	return nil, errors.New("retryDriver does not open connections")
+}
+func (d *retryDriver) Transient(msg *rdb.Message) bool {
+	return msg.Number == 1205
+}
+
+func init() {
+	rdb.Register("mustretry", &retryDriver{})
+}
+
+var errDeadlock = rdb.Errors{{Type: rdb.SqlError, Number: 1205, Message: "deadlock victim"}}
+
+func TestRunRetryTransient(t *testing.T) {
+	cp := Open(&rdb.Config{DriverName: "mustretry", PoolInitCapacity: 1})
+	defer cp.Close()
+
+	runs := 0
+	err := RunRetry(context.Background(), cp, &rdb.TxOptions{MinBackoff: time.Millisecond}, func(r Roller) error {
+		runs++
+		if runs == 1 {
+			// A must method panics with the driver error.
+			panic(Error{Err: errDeadlock})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 2 {
+		t.Errorf("expected 2 runs, got %d", runs)
+	}
+}
+
+func TestRunRetryNotTransient(t *testing.T) {
+	cp := Open(&rdb.Config{DriverName: "mustretry", PoolInitCapacity: 1})
+	defer cp.Close()
+
+	errPermanent := rdb.Errors{{Type: rdb.SqlError, Number: 547, Message: "constraint violation"}}
+	runs := 0
+	err := RunRetry(context.Background(), cp, &rdb.TxOptions{MinBackoff: time.Millisecond}, func(r Roller) error {
+		runs++
+		panic(Error{Err: errPermanent})
+	})
+	if !errors.As(err, new(rdb.Errors)) {
+		t.Fatalf("expected server error, got %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("expected 1 run, got %d", runs)
+	}
+}
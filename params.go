@@ -4,7 +4,10 @@
 
 package rdb
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // If the N (Name) field is not specified is not specified, then the order
 // of the parameter should be used if the driver supports it.
@@ -150,6 +153,14 @@ type Command struct {
 	// Optional name of the command. May be used if logging.
 	Name string
 
+	// ReadOnly marks the command as safe to run on a replica
+	// when run through a Cluster.
+	ReadOnly bool
+
+	// If set, overrides Config.Location for date and time values without
+	// a time zone read or sent by this command.
+	Location *time.Location
+
 	// Log messages, both info and error messages.
 	Log func(msg *Message)
 }
@@ -0,0 +1,144 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RewritePlaceholders rewrites the placeholders in sql to the form a driver
+// expects and returns the params to send with it.
+//
+// The "?" placeholder uses the next param in order and "$n" uses the n-th
+// param, starting at one. Params without a name are named "p1", "p2", and so
+// on by position. A ":name" placeholder uses the param of that name; it is
+// left alone if there is no such param. An "@name" placeholder is already
+// in native form. Placeholders in string literals, quoted and bracketed
+// identifiers, and comments are not changed.
+//
+// If named is true, as for a driver with DriverSupport.NamedParameter,
+// placeholders are written as "@name". Otherwise placeholders are written as
+// "?" and the returned params are in the order they are used.
+func RewritePlaceholders(sql string, params []Param, named bool) (string, []Param, error) {
+	return rewritePlaceholderList(sql, scanPlaceholders(sql), params, named)
+}
+
+// rewritePlaceholderList rewrites the placeholders in list, found in sql.
+func rewritePlaceholderList(sql string, list []sqlPlaceholder, params []Param, named bool) (string, []Param, error) {
+	if len(list) == 0 {
+		return sql, params, nil
+	}
+
+	params = append([]Param(nil), params...)
+	byName := make(map[string]int, len(params))
+	for i := range params {
+		if len(params[i].Name) == 0 {
+			params[i].Name = "p" + strconv.Itoa(i+1)
+		}
+		byName[strings.ToLower(params[i].Name)] = i
+	}
+
+	var out []Param
+	sb := &strings.Builder{}
+	last := 0
+	next := 0
+	for _, ph := range list {
+		index := -1
+		switch ph.Kind {
+		case placeholderAt:
+			if named {
+				continue
+			}
+			i, ok := byName[strings.ToLower(ph.Name)]
+			if !ok {
+				return "", nil, fmt.Errorf("placeholder %s has no parameter", sql[ph.Start:ph.End])
+			}
+			index = i
+		case placeholderColon:
+			i, ok := byName[strings.ToLower(ph.Name)]
+			if !ok {
+				continue
+			}
+			index = i
+		case placeholderQuestion:
+			index = next
+			next++
+		case placeholderDollar:
+			n, err := strconv.Atoi(ph.Name)
+			if err != nil {
+				return "", nil, fmt.Errorf("placeholder %s: %w", sql[ph.Start:ph.End], err)
+			}
+			index = n - 1
+		}
+		if index < 0 || index >= len(params) {
+			return "", nil, fmt.Errorf("placeholder %s at offset %d has no parameter", sql[ph.Start:ph.End], ph.Start)
+		}
+		sb.WriteString(sql[last:ph.Start])
+		if named {
+			sb.WriteByte('@')
+			sb.WriteString(params[index].Name)
+		} else {
+			sb.WriteByte('?')
+			out = append(out, params[index])
+		}
+		last = ph.End
+	}
+	sb.WriteString(sql[last:])
+	if !named {
+		params = out
+	}
+	return sb.String(), params, nil
+}
+
+// rewritePlaceholders rewrites the command SQL for the driver if it uses a
+// ":name" placeholder for a param, or a "?" or "$n" placeholder while any
+// param is unnamed. If every param is named, "?" and "$n" are left alone.
+func rewritePlaceholders(info *DriverInfo, cmd *Command, params []Param) (*Command, []Param, error) {
+	if len(params) == 0 {
+		return cmd, params, nil
+	}
+	allNamed := true
+	names := make(map[string]bool, len(params))
+	for i := range params {
+		name := params[i].Name
+		if len(name) == 0 {
+			allNamed = false
+			name = "p" + strconv.Itoa(i+1)
+		}
+		names[strings.ToLower(name)] = true
+	}
+	list := scanPlaceholders(cmd.SQL)
+	need := false
+	n := 0
+	for _, ph := range list {
+		switch ph.Kind {
+		case placeholderColon:
+			need = need || names[strings.ToLower(ph.Name)]
+		case placeholderQuestion, placeholderDollar:
+			if allNamed {
+				continue
+			}
+			need = true
+		}
+		list[n] = ph
+		n++
+	}
+	if !need {
+		return cmd, params, nil
+	}
+	named := info == nil || info.NamedParameter
+	sql, params, err := rewritePlaceholderList(cmd.SQL, list[:n], params, named)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sql == cmd.SQL {
+		return cmd, params, nil
+	}
+	c := *cmd
+	c.SQL = sql
+	return &c, params, nil
+}
@@ -0,0 +1,143 @@
+package rdb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRewritePlaceholders(t *testing.T) {
+	list := []struct {
+		SQL    string
+		Params []Param
+		Named  bool
+		Want   string
+		Names  []string
+	}{
+		{
+			SQL:    `select * from T where A = ? and B = ? and C = '?'`,
+			Params: []Param{{Value: 1}, {Value: 2}},
+			Named:  true,
+			Want:   `select * from T where A = @p1 and B = @p2 and C = '?'`,
+			Names:  []string{"p1", "p2"},
+		},
+		{
+			SQL:    `select $2, $1, $2 -- $3`,
+			Params: []Param{{Value: 1}, {Name: "b", Value: 2}},
+			Named:  true,
+			Want:   `select @b, @p1, @b -- $3`,
+			Names:  []string{"p1", "b"},
+		},
+		{
+			SQL:    `select :ID, :Other, cast(x as t)::text, [:ID], @local`,
+			Params: []Param{{Name: "id", Value: 1}},
+			Named:  true,
+			Want:   `select @id, :Other, cast(x as t)::text, [:ID], @local`,
+			Names:  []string{"id"},
+		},
+		{
+			SQL:    `select :b, @a, ?, :b`,
+			Params: []Param{{Name: "a", Value: 1}, {Name: "b", Value: 2}},
+			Named:  false,
+			Want:   `select ?, ?, ?, ?`,
+			Names:  []string{"b", "a", "a", "b"},
+		},
+		{
+			SQL:    `select 1`,
+			Params: []Param{{Value: 1}},
+			Named:  true,
+			Want:   `select 1`,
+			Names:  []string{""},
+		},
+	}
+	for _, item := range list {
+		sql, params, err := RewritePlaceholders(item.SQL, item.Params, item.Named)
+		if err != nil {
+			t.Errorf("%s: %v", item.SQL, err)
+			continue
+		}
+		if sql != item.Want {
+			t.Errorf("got  %s\nwant %s", sql, item.Want)
+		}
+		var names []string
+		for _, p := range params {
+			names = append(names, p.Name)
+		}
+		if !reflect.DeepEqual(names, item.Names) {
+			t.Errorf("%s: got names %q, want %q", item.SQL, names, item.Names)
+		}
+	}
+	if item := list[0]; item.Params[0].Name != "" {
+		t.Error("params changed")
+	}
+
+	for _, bad := range []string{`select ?, ?`, `select $3`, `select $0`} {
+		if _, _, err := RewritePlaceholders(bad, []Param{{Value: 1}}, true); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+	if _, _, err := RewritePlaceholders(`select @x`, []Param{{Name: "a"}}, false); err == nil {
+		t.Error("expected error for unknown param")
+	}
+}
+
+func TestRewritePlaceholdersNeed(t *testing.T) {
+	named := &DriverInfo{DriverSupport: DriverSupport{NamedParameter: true}}
+	list := []struct {
+		SQL    string
+		Params []Param
+		Want   string
+	}{
+		// No placeholder outside of literals and comments.
+		{"select ':a', [b:c] -- :a\nfrom T", []Param{{Name: "a"}}, "select ':a', [b:c] -- :a\nfrom T"},
+		{"select x::int, @a", []Param{{Name: "a"}}, "select x::int, @a"},
+		// All params named: positional placeholders are left alone.
+		{"select ?, $1, @a", []Param{{Name: "a"}}, "select ?, $1, @a"},
+		{"select ?, $1, :a", []Param{{Name: "a"}}, "select ?, $1, @a"},
+		{"select :b", []Param{{Name: "a"}}, "select :b"},
+		// Unnamed params.
+		{"select ?, $1", []Param{{}}, "select @p1, @p1"},
+	}
+	for _, item := range list {
+		cmd, _, err := rewritePlaceholders(named, &Command{SQL: item.SQL}, item.Params)
+		if err != nil {
+			t.Errorf("%s: %v", item.SQL, err)
+			continue
+		}
+		if cmd.SQL != item.Want {
+			t.Errorf("%s: got %s, want %s", item.SQL, cmd.SQL, item.Want)
+		}
+	}
+}
+
+type namedDriver struct {
+	listDriver
+}
+
+func (d *namedDriver) DriverInfo() *DriverInfo {
+	return &DriverInfo{DriverSupport: DriverSupport{NamedParameter: true}}
+}
+
+func TestQueryRewritePlaceholders(t *testing.T) {
+	d := &namedDriver{listDriver{queries: make(chan listQuery, 1)}}
+	Register("placeholder_rewrite", d)
+	pool, err := Open(&Config{DriverName: "placeholder_rewrite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	res, err := pool.Query(context.Background(), &Command{SQL: `select * from T where ID in (?) and Name = ?`},
+		Param{Value: []int{1, 2}}, Param{Value: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	got := <-d.queries
+	if want := `select * from T where ID in (@p1_0, @p1_1) and Name = @p2`; got.sql != want {
+		t.Errorf("got  %s\nwant %s", got.sql, want)
+	}
+	if len(got.params) != 3 || got.params[2].Name != "p2" {
+		t.Errorf("params: %+v", got.params)
+	}
+}
@@ -3,11 +3,14 @@ package rdb
 import (
 	"fmt"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"context"
 
 	"github.com/kardianos/rdb/internal/pools"
+	"github.com/kardianos/rdb/internal/pools/timer"
 )
 
 const debugConnectionReuse = false
@@ -22,11 +25,37 @@ type Queryer interface {
 // Represents a connection or connection configuration to a database.
 type ConnPool struct {
 	dr   Driver
+	info *DriverInfo
 	conf *Config
 	pool *pools.ResourcePool[DriverConn]
 
 	softWait time.Duration
 	expandBy int
+
+	// Set with SetMaxCapacity, SetInitCapacity, and SetMaxLifetime.
+	maxCap      atomic.Int64
+	minIdle     atomic.Int64
+	maxLifetime atomic.Int64
+
+	stats    poolCounters
+	leak     *leakDetector
+	health   *timer.Timer
+	failover *failover
+	breaker  *breaker
+	waiters  atomic.Int64
+	limit    *priorityLimit
+
+	closing atomic.Bool
+	drainAt atomic.Int64 // Unix nano time of the last Drain.
+
+	// resizeMu orders resizing with Close. Close waits for resizing, the
+	// shrinks still running in the background.
+	resizeMu sync.Mutex
+	resizing sync.WaitGroup
+
+	// Connections checked out of the pool.
+	outMu sync.Mutex
+	out   map[DriverConn]outConn
 }
 
 // OpenContext opens a connection pool and populates initial connections.
@@ -40,21 +69,50 @@ func OpenContext(ctx context.Context, config *Config) (*ConnPool, error) {
 	if err != nil {
 		return nil, err
 	}
-	if config.Secure && !dr.DriverInfo().SecureConnection {
+	info := dr.DriverInfo()
+	if config.Secure && !info.SecureConnection {
 		return nil, fmt.Errorf("driver %s does not support secure connections", config.DriverName)
 	}
+	cp := &ConnPool{
+		dr:   dr,
+		info: info,
+		conf: config,
+	}
+	cp.failover, err = newFailover(config)
+	if err != nil {
+		return nil, err
+	}
+	cp.breaker = newBreaker(config)
+	cp.limit, err = newPriorityLimit(config)
+	if err != nil {
+		return nil, err
+	}
 	factory := func(ctx context.Context) (DriverConn, error) {
 		if debugConnectionReuse {
 			fmt.Println("Conn.Open() NEW")
 		}
-		conn, err := dr.Open(ctx, config)
+		if cp.closing.Load() {
+			// Do not replace a connection closed during a shutdown.
+			return nil, ErrPoolClosing
+		}
+		if err := cp.breaker.allow(); err != nil {
+			cp.stats.circuitRejected.Add(1)
+			return nil, err
+		}
+		conn, err := cp.open(ctx)
 		if conn == nil && err == nil {
-			return nil, fmt.Errorf("new connection is nil")
+			err = fmt.Errorf("new connection is nil")
 		}
+		if err == nil {
+			err = conn.Reset(config)
+		}
+		cp.breaker.done(ctx, err)
 		if err != nil {
+			cp.stats.openFailed.Add(1)
 			return conn, err
 		}
-		return conn, conn.Reset(config)
+		cp.stats.opened.Add(1)
+		return conn, nil
 	}
 
 	initSize := config.PoolInitCapacity
@@ -75,18 +133,27 @@ func OpenContext(ctx context.Context, config *Config) (*ConnPool, error) {
 		expandBy = 6
 	}
 
-	return &ConnPool{
-		dr:   dr,
-		conf: config,
-		pool: pools.NewResourcePool(ctx, factory, initSize, maxSize, config.PoolIdleTimeout, 0, nil),
-
-		softWait: softWait,
-		expandBy: expandBy,
-	}, nil
+	cp.softWait = softWait
+	cp.expandBy = expandBy
+	cp.minIdle.Store(int64(initSize))
+	cp.maxLifetime.Store(int64(config.ConnectionMaxLifetime))
+	cp.pool = pools.NewResourcePool(ctx, factory, initSize, maxSize, config.PoolIdleTimeout, 0, nil)
+	if config.Validate != ValidateNever {
+		cp.pool.SetValidate(cp.validateCheckout)
+	}
+	cp.startHealthCheck()
+	cp.startLeakDetector()
+	return cp, nil
 }
 
 // Close the connection pool.
 func (cp *ConnPool) Close() {
+	cp.resizeMu.Lock()
+	cp.closing.Store(true)
+	cp.resizeMu.Unlock()
+	cp.stopHealthCheck()
+	cp.stopLeakDetector()
+	cp.resizing.Wait()
 	cp.pool.Close()
 }
 
@@ -113,18 +180,28 @@ func (cp *ConnPool) ConnectionInfo(ctx context.Context) (*ConnectionInfo, error)
 }
 
 func (cp *ConnPool) releaseConn(ctx context.Context, conn DriverConn, kill bool) error {
+	cp.untrack(conn)
+	if cp.checkin(conn) {
+		kill = true
+	}
 	if conn.Status() != StatusReady {
 		kill = true
 	}
-	if life := cp.conf.ConnectionMaxLifetime; life > 0 {
+	if !kill && cp.drained(conn) {
+		kill = true
+		cp.stats.drained.Add(1)
+	}
+	if life := time.Duration(cp.maxLifetime.Load()); life > 0 {
 		now := time.Now()
 		op := conn.Opened()
 		diff := now.Sub(op)
-		if diff > life {
+		if diff > life && !kill {
 			kill = true
+			cp.stats.lifetimeClosed.Add(1)
 		}
 	}
 	if kill {
+		cp.stats.killedOnRelease.Add(1)
 		if debugConnectionReuse {
 			fmt.Println("Result.Close() CLOSE")
 		}
@@ -141,6 +218,7 @@ func (cp *ConnPool) releaseConn(ctx context.Context, conn DriverConn, kill bool)
 	if conn.Available() {
 		err := conn.Reset(cp.conf)
 		if err != nil {
+			cp.stats.resetFailed.Add(1)
 			conn.SetAvailable(false)
 			cp.pool.Free(ctx)
 			return err
@@ -153,6 +231,38 @@ func (cp *ConnPool) releaseConn(ctx context.Context, conn DriverConn, kill bool)
 	}
 	return nil
 }
+
+// acquire checks out a connection for a query, transaction, or Connection
+// and prepares it for use with ctx.
+func (cp *ConnPool) acquire(ctx context.Context) (DriverConn, error) {
+	if cp.closing.Load() {
+		return nil, ErrPoolClosing
+	}
+	done, err := cp.wait()
+	if err != nil {
+		return nil, err
+	}
+	p := PriorityFrom(ctx)
+	err = cp.limit.hold(ctx, p)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	conn, err := cp.getConn(ctx, true)
+	done()
+	if err != nil {
+		cp.limit.release(p)
+		return nil, err
+	}
+	cp.checkout(conn, p)
+	err = cp.applySession(ctx, conn)
+	if err != nil {
+		cp.releaseConn(ctx, conn, false)
+		return nil, err
+	}
+	return conn, nil
+}
+
 func (cp *ConnPool) getConn(ctx context.Context, again bool) (DriverConn, error) {
 	var conn DriverConn
 
@@ -163,13 +273,13 @@ func (cp *ConnPool) getConn(ctx context.Context, again bool) (DriverConn, error)
 		defer cancel()
 	}
 
-	conn, err := cp.pool.Get(ctx, getCtx)
+	conn, err := cp.pool.GetPriority(ctx, getCtx, int(PriorityFrom(ctx)))
 	if conn != nil {
 		conn.SetAvailable(true)
 	}
 	// Logic to expand the pool capacity up to the max capacity.
 	if again && err == pools.ErrTimeout {
-		maxCap := cp.pool.MaxCap()
+		maxCap := cp.maxCapacity()
 		curCap := cp.pool.Capacity()
 
 		if curCap >= maxCap {
@@ -180,7 +290,8 @@ func (cp *ConnPool) getConn(ctx context.Context, again bool) (DriverConn, error)
 		if curCap > maxCap {
 			curCap = maxCap
 		}
-		cp.pool.SetCapacity(int(curCap))
+		cp.resize(curCap)
+		cp.stats.expansions.Add(1)
 
 		conn, err = cp.getConn(ctx, false)
 		return conn, err
@@ -199,22 +310,48 @@ func (cp *ConnPool) Query(ctx context.Context, cmd *Command, params ...Param) (*
 	return cp.query(ctx, false, nil, cmd, nil, params...)
 }
 
-// keepOnClose used to not recycle the DB connection after a query result is done. Used for transactions and connections.
-func (cp *ConnPool) query(ctx context.Context, keepOnClose bool, conn DriverConn, cmd *Command, ci **ConnectionInfo, params ...Param) (res *Result, err error) {
+// prepareQuery converts the params with the command Converter, rewrites
+// placeholders and list parameters, and prepares each param for the driver.
+func prepareQuery(info *DriverInfo, conf *Config, cmd *Command, params []Param) (*Command, []Param, error) {
 	if cmd.Converter != nil {
 		for i := range params {
-			err = cmd.Converter.ConvertParam(&params[i])
+			err := cmd.Converter.ConvertParam(&params[i])
 			if err != nil {
-				return nil, fmt.Errorf("ConvertParam: %w", err)
+				return nil, nil, fmt.Errorf("ConvertParam: %w", err)
 			}
 		}
 	}
 
+	cmd, params, err := rewritePlaceholders(info, cmd, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd, params, err = expandLists(info, conf, cmd, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, err = prepareParams(info, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cmd, params, nil
+}
+
+// keepOnClose used to not recycle the DB connection after a query result is done. Used for transactions and connections.
+func (cp *ConnPool) query(ctx context.Context, keepOnClose bool, conn DriverConn, cmd *Command, ci **ConnectionInfo, params ...Param) (res *Result, err error) {
+	cmd, params, err = prepareQuery(cp.info, cp.conf, cmd, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var leak *leakEntry
 	if conn == nil {
-		conn, err = cp.getConn(ctx, true)
+		conn, err = cp.acquire(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("getConn: %w", err)
 		}
+	} else {
+		leak = cp.tracked(conn)
 	}
 	if ctx == nil {
 		ctx = context.Background()
@@ -230,6 +367,12 @@ func (cp *ConnPool) query(ctx context.Context, keepOnClose bool, conn DriverConn
 		keepOnClose: keepOnClose,
 
 		closing: make(chan struct{}, 3),
+		leak:    leak,
+	}
+	if !keepOnClose {
+		res.leak = cp.track(conn, "Result", commandName(cmd), res.forceClose)
+		res.leak.lock()
+		defer res.leak.unlock()
 	}
 
 	defer func() {
@@ -276,7 +419,7 @@ func (cp *ConnPool) Begin(ctx context.Context) (*Transaction, error) {
 
 // BeginLevel starts a Transaction with the specified isolation level.
 func (cp *ConnPool) BeginLevel(ctx context.Context, level IsolationLevel) (*Transaction, error) {
-	conn, err := cp.getConn(ctx, true)
+	conn, err := cp.acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -287,6 +430,9 @@ func (cp *ConnPool) BeginLevel(ctx context.Context, level IsolationLevel) (*Tran
 		conn:  conn,
 		level: level,
 	}
+	tran.leak = cp.track(conn, "Transaction", "", tran.forceClose)
+	tran.leak.lock()
+	defer tran.leak.unlock()
 	err = conn.Begin(ctx, level)
 	if err != nil {
 		cp.releaseConn(ctx, conn, true)
@@ -297,7 +443,7 @@ func (cp *ConnPool) BeginLevel(ctx context.Context, level IsolationLevel) (*Tran
 
 // Connection returns a dedicated database connection from the connection pool.
 func (cp *ConnPool) Connection(ctx context.Context) (*Connection, error) {
-	conn, err := cp.getConn(ctx, true)
+	conn, err := cp.acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -307,6 +453,7 @@ func (cp *ConnPool) Connection(ctx context.Context) (*Connection, error) {
 		conn: conn,
 		ctx:  ctx,
 	}
+	c.leak = cp.track(conn, "Connection", "", c.forceClose)
 	return c, nil
 }
 
@@ -314,3 +461,83 @@ func (cp *ConnPool) PoolAvailable() (capacity, available int) {
 	c, a := cp.pool.Capacity(), cp.pool.Available()
 	return int(c), int(a)
 }
+
+// SetMaxCapacity limits the number of open connections to n, up to
+// Config.PoolMaxCapacity. If n is zero or less, the limit is
+// Config.PoolMaxCapacity. If more connections are open, the pool
+// shrinks as they are returned.
+func (cp *ConnPool) SetMaxCapacity(n int) {
+	cp.maxCap.Store(int64(n))
+	max := cp.maxCapacity()
+	if cp.minIdle.Load() > max {
+		cp.minIdle.Store(max)
+	}
+	if cp.pool.Capacity() > max {
+		cp.resize(max)
+	}
+}
+
+// SetInitCapacity sets the capacity of the pool to n, as if it was opened
+// with a Config.PoolInitCapacity of n. The pool still expands up to the max
+// capacity when busy. The health check keeps at least n idle connections
+// open. If n is less than one, one is used.
+func (cp *ConnPool) SetInitCapacity(n int) {
+	max := cp.maxCapacity()
+	if n > int(max) {
+		n = int(max)
+	}
+	if n < 1 {
+		n = 1
+	}
+	cp.minIdle.Store(int64(n))
+	cp.resize(int64(n))
+}
+
+// SetMinIdle sets the number of idle connections the health check keeps
+// open, up to the max capacity, without changing the capacity of the pool.
+// If n is less than zero, zero is used.
+func (cp *ConnPool) SetMinIdle(n int) {
+	if max := int(cp.maxCapacity()); n > max {
+		n = max
+	}
+	if n < 0 {
+		n = 0
+	}
+	cp.minIdle.Store(int64(n))
+}
+
+// SetMaxLifetime sets the time after which a connection is closed when it
+// is returned to the pool, as Config.ConnectionMaxLifetime.
+// If d is zero or less, connections are reused forever.
+func (cp *ConnPool) SetMaxLifetime(d time.Duration) {
+	cp.maxLifetime.Store(int64(d))
+}
+
+// maxCapacity returns the number of connections the pool may expand to.
+func (cp *ConnPool) maxCapacity() int64 {
+	max := cp.pool.MaxCap()
+	if n := cp.maxCap.Load(); n > 0 && n < max {
+		return n
+	}
+	return max
+}
+
+// resize sets the pool capacity to n. Shrinking waits for the connections
+// in use to be returned, so it is done in the background. The pool is not
+// resized once it is closing.
+func (cp *ConnPool) resize(n int64) {
+	cp.resizeMu.Lock()
+	defer cp.resizeMu.Unlock()
+	if cp.closing.Load() {
+		return
+	}
+	if n < cp.pool.Capacity() {
+		cp.resizing.Add(1)
+		go func() {
+			defer cp.resizing.Done()
+			cp.pool.SetCapacity(int(n))
+		}()
+		return
+	}
+	cp.pool.SetCapacity(int(n))
+}
@@ -155,3 +155,118 @@ func TestPoolExpansion(t *testing.T) {
 		t.Errorf("Expected 0 errors from pool expansion, but got %d", errCount)
 	}
 }
+
+func TestPoolSetCapacity(t *testing.T) {
+	Register("pool_set_capacity", &fakeStdDriver{})
+	pool, err := Open(&Config{
+		DriverName:       "pool_set_capacity",
+		PoolInitCapacity: 2,
+		PoolMaxCapacity:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	pool.SetMaxCapacity(3)
+	if got := pool.Stats().MaxCapacity; got != 3 {
+		t.Errorf("max capacity %d, want 3", got)
+	}
+	pool.SetInitCapacity(5)
+	if got := pool.Stats().Capacity; got != 3 {
+		t.Errorf("capacity %d, want 3", got)
+	}
+	pool.SetMinIdle(1)
+	if got := pool.Stats().Capacity; got != 3 {
+		t.Errorf("capacity %d after SetMinIdle, want 3", got)
+	}
+	if got := pool.minIdle.Load(); got != 1 {
+		t.Errorf("min idle %d, want 1", got)
+	}
+	pool.SetMinIdle(5)
+	if got := pool.minIdle.Load(); got != 3 {
+		t.Errorf("min idle %d, want 3", got)
+	}
+
+	// The pool may not expand past the max capacity.
+	var held []DriverConn
+	for i := 0; i < 3; i++ {
+		conn, err := pool.getConn(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held = append(held, conn)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	_, err = pool.getConn(waitCtx, true)
+	cancel()
+	if err == nil {
+		t.Fatal("expected timeout past max capacity")
+	}
+
+	// Shrinking waits for the held connections.
+	pool.SetInitCapacity(1)
+	pool.SetMaxLifetime(time.Nanosecond)
+	for _, conn := range held {
+		pool.releaseConn(ctx, conn, false)
+	}
+	for i := 0; pool.Stats().Capacity != 1; i++ {
+		if i == 100 {
+			t.Fatalf("capacity %d, want 1", pool.Stats().Capacity)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := pool.Stats().LifetimeClosed; got != 3 {
+		t.Errorf("lifetime closed %d, want 3", got)
+	}
+
+	pool.SetMaxCapacity(0)
+	if got := pool.Stats().MaxCapacity; got != 10 {
+		t.Errorf("max capacity %d, want 10", got)
+	}
+}
+
+func TestPoolShrinkClose(t *testing.T) {
+	Register("poolshrinkclose", &fakeStdDriver{})
+	pool, err := Open(&Config{
+		DriverName:       "poolshrinkclose",
+		PoolInitCapacity: 3,
+		PoolMaxCapacity:  3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var held []DriverConn
+	for i := 0; i < 3; i++ {
+		conn, err := pool.getConn(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held = append(held, conn)
+	}
+	// The shrink waits for two connections in the background. Close must not
+	// take one of them and close the pool under it.
+	pool.SetMaxCapacity(1)
+	time.Sleep(20 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	for _, conn := range held {
+		pool.releaseConn(ctx, conn, false)
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+	if got := pool.pool.Available(); got != 0 {
+		t.Errorf("available %d, want 0", got)
+	}
+}
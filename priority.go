@@ -0,0 +1,92 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// Priority orders requests waiting for a connection from a saturated pool.
+// Waiting requests with a higher priority are given connections first.
+type Priority byte
+
+const (
+	Low    Priority = iota // Background and batch work.
+	Normal                 // Default priority.
+	High                   // Interactive requests.
+)
+
+func (p Priority) String() string {
+	switch p {
+	default:
+		return "?"
+	case Low:
+		return "low"
+	case Normal:
+		return "normal"
+	case High:
+		return "high"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context that checks out connections with priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority set in ctx, or Normal if none is set.
+func PriorityFrom(ctx context.Context) Priority {
+	if ctx == nil {
+		return Normal
+	}
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p <= High {
+		return p
+	}
+	return Normal
+}
+
+// priorityLimit caps how many connections a priority may hold.
+// Limits are set from Config.PriorityMaxCapacity.
+type priorityLimit [High + 1]chan struct{}
+
+func newPriorityLimit(conf *Config) (*priorityLimit, error) {
+	if len(conf.PriorityMaxCapacity) == 0 {
+		return nil, nil
+	}
+	pl := &priorityLimit{}
+	for p, n := range conf.PriorityMaxCapacity {
+		if p > High {
+			return nil, fmt.Errorf("unknown priority %d", p)
+		}
+		if n <= 0 {
+			continue
+		}
+		pl[p] = make(chan struct{}, n)
+	}
+	return pl, nil
+}
+
+// hold waits until a connection may be checked out with priority p.
+func (pl *priorityLimit) hold(ctx context.Context, p Priority) error {
+	if pl == nil || pl[p] == nil {
+		return nil
+	}
+	select {
+	case pl[p] <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (pl *priorityLimit) release(p Priority) {
+	if pl == nil || pl[p] == nil {
+		return
+	}
+	<-pl[p]
+}
@@ -0,0 +1,151 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitQueued(t *testing.T, pool *ConnPool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Stats().Queued != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d waiters", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriorityOrder(t *testing.T) {
+	Register("priority_order", &dummyDriver{})
+	pool, err := Open(&Config{DriverName: "priority_order", PoolInitCapacity: 1, PoolMaxCapacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	held, err := pool.Connection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type got struct {
+		p    Priority
+		conn *Connection
+	}
+	acquired := make(chan got, 2)
+	start := func(p Priority) {
+		go func() {
+			conn, err := pool.Connection(WithPriority(ctx, p))
+			if err != nil {
+				t.Error(err)
+			}
+			acquired <- got{p, conn}
+		}()
+	}
+	start(Low)
+	waitQueued(t, pool, 1)
+	start(High)
+	waitQueued(t, pool, 2)
+
+	held.Close()
+	first := <-acquired
+	if first.p != High {
+		t.Fatalf("first connection went to %v, want high", first.p)
+	}
+	first.conn.Close()
+	second := <-acquired
+	if second.p != Low {
+		t.Fatalf("second connection went to %v, want low", second.p)
+	}
+	second.conn.Close()
+}
+
+func TestPriorityMaxCapacity(t *testing.T) {
+	Register("priority_cap", &dummyDriver{})
+	pool, err := Open(&Config{
+		DriverName:          "priority_cap",
+		PoolInitCapacity:    2,
+		PoolMaxCapacity:     2,
+		PriorityMaxCapacity: map[Priority]int{Low: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+	lowCtx := WithPriority(ctx, Low)
+
+	low, err := pool.Connection(lowCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(lowCtx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Connection(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second low connection: got %v, want deadline exceeded", err)
+	}
+
+	normal, err := pool.Connection(ctx)
+	if err != nil {
+		t.Fatalf("normal connection: %v", err)
+	}
+	normal.Close()
+
+	low.Close()
+	low, err = pool.Connection(lowCtx)
+	if err != nil {
+		t.Fatalf("low connection after release: %v", err)
+	}
+	low.Close()
+}
+
+func TestPriorityWaiterLeaves(t *testing.T) {
+	Register("priority_leave", &dummyDriver{})
+	pool, err := Open(&Config{DriverName: "priority_leave", PoolInitCapacity: 1, PoolMaxCapacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	held, err := pool.Connection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	highCtx, cancelHigh := context.WithCancel(WithPriority(ctx, High))
+	highDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Connection(highCtx)
+		highDone <- err
+	}()
+	waitQueued(t, pool, 1)
+
+	lowDone := make(chan *Connection, 1)
+	go func() {
+		conn, err := pool.Connection(WithPriority(ctx, Low))
+		if err != nil {
+			t.Error(err)
+		}
+		lowDone <- conn
+	}()
+	waitQueued(t, pool, 2)
+
+	// The low waiter yields to the high waiter until it gives up.
+	cancelHigh()
+	if err := <-highDone; err == nil {
+		t.Fatal("high connection: want error after cancel")
+	}
+	held.Close()
+	select {
+	case conn := <-lowDone:
+		conn.Close()
+	case <-time.After(2 * time.Second):
+		t.Fatal("low waiter did not get the connection")
+	}
+}
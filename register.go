@@ -12,12 +12,14 @@ var drivers = map[string]Driver{}
 
 // Panics if called twice with the same name.
 // Make the driver instance available clients.
+// The driver is also registered with database/sql, see NewConnector.
 func Register(name string, dr Driver) {
 	_, found := drivers[name]
 	if found {
 		panic(fmt.Sprintf("Driver already present: %s", name))
 	}
 	drivers[name] = dr
+	registerStd(name, dr)
 }
 
 func getDriver(name string) (Driver, error) {
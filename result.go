@@ -26,6 +26,13 @@ type Result struct {
 	lastHit time.Time
 	closing chan struct{}
 	closed  bool
+
+	// Set if leak detection is enabled.
+	leak *leakEntry
+
+	// Error from finishing a row paused at a column stream,
+	// returned by the next Scan or Close.
+	streamErr error
 }
 
 // Results should automatically close when all rows have been read.
@@ -33,12 +40,37 @@ func (r *Result) Close() error {
 	if r == nil {
 		return nil
 	}
+	r.leak.lock()
+	defer r.leak.unlock()
 	return r.close(true)
 }
 func (r *Result) updateHit() {
 	r.m.Lock()
 	r.lastHit = time.Now()
 	r.m.Unlock()
+	r.leak.touch()
+}
+
+func (r *Result) isClosed() bool {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.closed
+}
+
+// forceClose closes the connection of a leaked result
+// and releases it to the connection pool.
+func (r *Result) forceClose() func() {
+	r.m.Lock()
+	if r.closed {
+		r.m.Unlock()
+		return nil
+	}
+	r.closed = true
+	conn, cp := r.conn, r.cp
+	r.m.Unlock()
+
+	cp.releaseConn(context.Background(), conn, true)
+	return nil
 }
 
 func (r *Result) RowsAffected() uint64 {
@@ -61,20 +93,25 @@ func (r *Result) close(explicit bool) error {
 	if explicit {
 		r.val.clearBuffer()
 	}
-	var err error
+	r.val.stream = nil
+	err := r.streamErr
+	r.streamErr = nil
 
-	if r.conn == nil {
-		return nil
+	if r.conn == nil || r.leak.forced() {
+		// The transaction or connection of the result was closed
+		// by leak detection.
+		return err
 	}
 
-	err = r.conn.NextQuery(r.ctx)
-	if err != nil {
+	if qerr := r.conn.NextQuery(r.ctx); qerr != nil {
 		r.cp.releaseConn(r.ctx, r.conn, true)
-		return err
+		return qerr
 	}
 
 	if r.keepOnClose == false {
-		err = r.cp.releaseConn(r.ctx, r.conn, false)
+		if rerr := r.cp.releaseConn(r.ctx, r.conn, false); err == nil {
+			err = rerr
+		}
 		r.cp = nil
 		r.conn = nil
 	}
@@ -95,14 +132,16 @@ func (r *Result) Info() []*Message {
 }
 
 // Prep registers a destination for column name before Scan.
-// Common destinations: *T, *Opt[T], *NullFlagPrep, io.Writer, *Nullable.
+// Common destinations: *T, *Opt[T], *NullFlagPrep, io.Writer, *io.Reader, *Nullable.
 // Drivers use DirectAssign when possible (no interface{} box for scalars/Opt).
+// A *io.Reader may stream a large value from the connection, see Reader.
 // Will panic if name is not a valid column name.
 func (r *Result) Prep(name string, value interface{}) *Result {
 	col, found := r.val.columnLookup[name]
 	if !found {
 		panic(ErrorColumnNotFound{At: "Prep", Name: name})
 	}
+	r.readTo(len(r.val.columns))
 	r.val.prep[col.Index] = value
 	return r
 }
@@ -113,6 +152,7 @@ func (r *Result) Prepx(index int, value interface{}) *Result {
 	if index < 0 || index >= len(r.val.columns) {
 		panic(ErrorColumnNotFound{At: "Prepx", Index: index})
 	}
+	r.readTo(len(r.val.columns))
 	r.val.prep[index] = value
 	return r
 }
@@ -124,6 +164,7 @@ func (r *Result) Get(name string) interface{} {
 	if !found {
 		panic(ErrorColumnNotFound{At: "Get", Name: name})
 	}
+	r.readTo(col.Index)
 	bv := r.val.buffer[col.Index]
 	return bv.Value
 }
@@ -134,6 +175,7 @@ func (r *Result) Getx(index int) interface{} {
 	if index < 0 || index >= len(r.val.columns) {
 		panic(ErrorColumnNotFound{At: "Getx", Index: index})
 	}
+	r.readTo(index)
 	bv := r.val.buffer[index]
 	return bv.Value
 }
@@ -145,6 +187,7 @@ func (r *Result) GetN(name string) Nullable {
 	if !found {
 		panic(ErrorColumnNotFound{At: "GetN", Name: name})
 	}
+	r.readTo(col.Index)
 	return r.val.buffer[col.Index]
 }
 
@@ -154,6 +197,7 @@ func (r *Result) GetxN(index int) Nullable {
 	if index < 0 || index >= len(r.val.columns) {
 		panic(ErrorColumnNotFound{At: "GetxN", Index: index})
 	}
+	r.readTo(index)
 	return r.val.buffer[index]
 }
 
@@ -161,6 +205,7 @@ func (r *Result) GetxN(index int) Nullable {
 // into a prepared value. Not all fields will be populated if some have
 // been prepared.
 func (r *Result) GetRowN() []Nullable {
+	r.readTo(len(r.val.columns))
 	rowBuf := r.val.buffer
 	ret := make([]Nullable, len(rowBuf))
 	for i := range rowBuf {
@@ -172,6 +217,7 @@ func (r *Result) GetRowN() []Nullable {
 // Optional to call. Determine if there is another row.
 // Scan actually advances to the next row.
 func (r *Result) Next() (more bool) {
+	r.readTo(len(r.val.columns))
 	if r.conn == nil {
 		return false
 	}
@@ -183,6 +229,12 @@ func (r *Result) Next() (more bool) {
 }
 
 func (r *Result) NextResult() (more bool, err error) {
+	r.readTo(len(r.val.columns))
+	r.leak.lock()
+	defer r.leak.unlock()
+	if r.leak.forced() {
+		return false, ErrClosed
+	}
 	if r.conn == nil {
 		return false, nil
 	}
@@ -195,7 +247,17 @@ func (r *Result) NextResult() (more bool, err error) {
 // Return value "more" is false if no more rows.
 // Results should automatically close when all rows have been read.
 func (r *Result) Scan(values ...interface{}) error {
-	if r.closed {
+	if r.isClosed() {
+		return ErrClosed
+	}
+	r.readTo(len(r.val.columns))
+	if err := r.streamErr; err != nil {
+		r.streamErr = nil
+		return err
+	}
+	r.leak.lock()
+	defer r.leak.unlock()
+	if r.isClosed() || r.leak.forced() {
 		return ErrClosed
 	}
 	r.updateHit()
@@ -208,8 +270,21 @@ func (r *Result) Scan(values ...interface{}) error {
 
 	r.val.clearBuffer()
 	err := r.conn.Scan(r.ctx)
+	if r.val.stream != nil {
+		// The row is paused at a column stream. The rest of the row
+		// is read by finishRow.
+		r.wrapStream()
+		if err == nil && len(r.val.errorList) != 0 {
+			err = r.val.errorList
+		}
+		return err
+	}
 	r.val.clearPrep()
+	return r.endRow(err)
+}
 
+// endRow checks the arity and closes the result after the last row.
+func (r *Result) endRow(err error) error {
 	// Only show SQL errors if no connection errors,
 	// but show before any other errors.
 	if err == nil && len(r.val.errorList) != 0 {
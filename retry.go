@@ -0,0 +1,174 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrorClassifier may be implemented by a Driver to report which server
+// messages describe a transient condition, such as a deadlock victim or
+// a failover in progress, where retrying the whole transaction may succeed.
+type ErrorClassifier interface {
+	Transient(msg *Message) bool
+}
+
+// TxOptions controls how RunInTx starts and retries a transaction.
+type TxOptions struct {
+	// Isolation level used for each transaction attempt.
+	Level IsolationLevel
+
+	// Maximum number of attempts, including the first one.
+	// If zero, defaults to 3.
+	MaxAttempts int
+
+	// Backoff before the first retry. Each following retry doubles it.
+	// If zero, defaults to 10ms.
+	MinBackoff time.Duration
+
+	// Upper bound of the backoff between two attempts.
+	// If zero, defaults to 1s.
+	MaxBackoff time.Duration
+
+	// Retryable, if set, replaces the driver classifier.
+	// Return true if the transaction should be attempted again.
+	Retryable func(err error) bool
+}
+
+func (opts *TxOptions) maxAttempts() int {
+	if opts == nil || opts.MaxAttempts <= 0 {
+		return 3
+	}
+	return opts.MaxAttempts
+}
+
+// backoff returns a jittered wait duration before the attempt
+// following the given zero based attempt.
+func (opts *TxOptions) backoff(attempt int) time.Duration {
+	min, max := 10*time.Millisecond, time.Second
+	if opts != nil {
+		if opts.MinBackoff > 0 {
+			min = opts.MinBackoff
+		}
+		if opts.MaxBackoff > 0 {
+			max = opts.MaxBackoff
+		}
+	}
+	d := min
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	// Use half of the wait as a floor and jitter the rest.
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Transient returns true if err contains a server message the driver
+// reports as transient. Returns false if the driver does not classify errors.
+func (cp *ConnPool) Transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	cl, ok := cp.dr.(ErrorClassifier)
+	if !ok {
+		return false
+	}
+	var errs Errors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	for _, msg := range errs {
+		if msg == nil || msg.Type == SqlInfo {
+			continue
+		}
+		if cl.Transient(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry calls f until it succeeds, returns an error that is not retryable,
+// or the maximum attempts are reached. Between attempts it waits
+// a jittered, exponentially growing backoff. If opts.Retryable is nil,
+// the driver classifier of cp is used.
+func Retry(ctx context.Context, cp *ConnPool, opts *TxOptions, f func() error) error {
+	retryable := cp.Transient
+	if opts != nil && opts.Retryable != nil {
+		retryable = opts.Retryable
+	}
+	max := opts.maxAttempts()
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if attempt+1 >= max || !retryable(err) {
+			return err
+		}
+		t := time.NewTimer(opts.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// RunInTx runs f within a transaction and commits it if f returns nil.
+// If f returns an error or panics the transaction is rolled back.
+// If the error or the commit failure is classified as transient,
+// such as a deadlock, the whole transaction is run again.
+// A panic is re-raised after the rollback and is never retried.
+// If f returns nil while a nested transaction is still active, the
+// transaction is rolled back and ErrNestedActive is returned.
+//
+// Because f may be called more then once, it must not have side effects
+// outside of the transaction.
+func RunInTx(ctx context.Context, cp *ConnPool, opts *TxOptions, f func(tran *Transaction) error) error {
+	level := LevelDefault
+	if opts != nil {
+		level = opts.Level
+	}
+	return Retry(ctx, cp, opts, func() error {
+		tran, err := cp.BeginLevel(ctx, level)
+		if err != nil {
+			return err
+		}
+		return runTx(tran, f)
+	})
+}
+
+func runTx(tran *Transaction, f func(tran *Transaction) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if tran.Active() {
+				tran.rollback(nil)
+			}
+			panic(recovered)
+		}
+	}()
+	err = f(tran)
+	if !tran.Active() {
+		return err
+	}
+	if err == nil && tran.child != nil {
+		err = ErrNestedActive
+	}
+	if err != nil {
+		// The server may have already rolled back the transaction,
+		// such as for a deadlock victim. Report the original error.
+		tran.rollback(nil)
+		return err
+	}
+	return tran.Commit()
+}
@@ -0,0 +1,178 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// txDriver is a fake driver that records transaction calls and
+// classifies deadlock messages as transient.
+type txDriver struct {
+	mu    sync.Mutex
+	calls []string
+
+	// Errors returned from successive Commit calls.
+	commitErrs []error
+}
+
+func (d *txDriver) DriverInfo() *DriverInfo { return &DriverInfo{} }
+func (d *txDriver) PingCommand() *Command   { return &Command{Arity: Zero} }
+func (d *txDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	return &txConn{dummyConn: dummyConn{opened: time.Now()}, d: d}, nil
+}
+func (d *txDriver) Transient(msg *Message) bool {
+	return msg.Number == 1205
+}
+
+func (d *txDriver) record(call string) {
+	d.mu.Lock()
+	d.calls = append(d.calls, call)
+	d.mu.Unlock()
+}
+
+func (d *txDriver) nextCommitErr() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.commitErrs) == 0 {
+		return nil
+	}
+	err := d.commitErrs[0]
+	d.commitErrs = d.commitErrs[1:]
+	return err
+}
+
+func (d *txDriver) getCalls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.calls...)
+}
+
+type txConn struct {
+	dummyConn
+	d *txDriver
+}
+
+func (c *txConn) Begin(ctx context.Context, level IsolationLevel) error {
+	c.d.record("begin")
+	return nil
+}
+func (c *txConn) Commit(ctx context.Context) error {
+	c.d.record("commit")
+	return c.d.nextCommitErr()
+}
+func (c *txConn) Rollback(savepoint string) error {
+	c.d.record("rollback" + savepoint)
+	return nil
+}
+func (c *txConn) SavePoint(ctx context.Context, name string) error {
+	c.d.record("save" + name)
+	return nil
+}
+
+func openTxPool(t *testing.T, name string, d *txDriver) *ConnPool {
+	t.Helper()
+	Register(name, d)
+	pool, err := Open(&Config{DriverName: name, PoolInitCapacity: 1, PoolMaxCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func equalCalls(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var errDeadlock = Errors{{Type: SqlError, Number: 1205, Message: "deadlock victim"}}
+
+func TestRunInTxRetryCommit(t *testing.T) {
+	d := &txDriver{commitErrs: []error{errDeadlock}}
+	pool := openTxPool(t, "tx_retry_commit", d)
+
+	runs := 0
+	err := RunInTx(context.Background(), pool, &TxOptions{MinBackoff: time.Millisecond}, func(tran *Transaction) error {
+		runs++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 2 {
+		t.Errorf("expected 2 runs, got %d", runs)
+	}
+	want := []string{"begin", "commit", "begin", "commit"}
+	if got := d.getCalls(); !equalCalls(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+}
+
+func TestRunInTxRetryFunc(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_retry_func", d)
+
+	runs := 0
+	err := RunInTx(context.Background(), pool, &TxOptions{MaxAttempts: 2, MinBackoff: time.Millisecond}, func(tran *Transaction) error {
+		runs++
+		return errDeadlock
+	})
+	if !errors.As(err, new(Errors)) {
+		t.Fatalf("expected deadlock error, got %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("expected 2 runs, got %d", runs)
+	}
+	want := []string{"begin", "rollback", "begin", "rollback"}
+	if got := d.getCalls(); !equalCalls(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+}
+
+func TestRunInTxNotRetryable(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_retry_permanent", d)
+
+	errPermanent := errors.New("permanent")
+	runs := 0
+	err := RunInTx(context.Background(), pool, nil, func(tran *Transaction) error {
+		runs++
+		return errPermanent
+	})
+	if err != errPermanent {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("expected 1 run, got %d", runs)
+	}
+}
+
+func TestRunInTxPanic(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_retry_panic", d)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic to be re-raised")
+			}
+		}()
+		RunInTx(context.Background(), pool, nil, func(tran *Transaction) error {
+			panic("boom")
+		})
+	}()
+	want := []string{"begin", "rollback"}
+	if got := d.getCalls(); !equalCalls(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+}
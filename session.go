@@ -0,0 +1,94 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// Session holds per request values applied to a connection each time it is
+// checked out of the pool and cleared before it is used again.
+// Common uses are the tenant or user identity for row-level security.
+type Session struct {
+	// Values set into the connection session, such as SESSION_CONTEXT.
+	Values map[string]any
+
+	// ContextInfo is a driver defined binary value, such as CONTEXT_INFO.
+	ContextInfo []byte
+}
+
+func (s *Session) empty() bool {
+	return s == nil || (len(s.Values) == 0 && len(s.ContextInfo) == 0)
+}
+
+// DriverSessionConn may be implemented by a DriverConn to apply a Session.
+// SetSession is called after the connection is checked out of the pool and
+// before any command is run. The driver must clear any session state
+// in Reset before the connection is used again.
+type DriverSessionConn interface {
+	SetSession(ctx context.Context, s *Session) error
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context that applies s to every connection checked
+// out of a ConnPool with the context, including for Query, Begin,
+// and Connection.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// WithSessionContext returns a context that sets the session values
+// on each connection checked out with the context.
+// Values set by a parent context are kept unless replaced.
+func WithSessionContext(ctx context.Context, values map[string]any) context.Context {
+	s := &Session{}
+	if parent := SessionFrom(ctx); parent != nil {
+		*s = *parent
+	}
+	merged := make(map[string]any, len(s.Values)+len(values))
+	for k, v := range s.Values {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	s.Values = merged
+	return WithSession(ctx, s)
+}
+
+// WithContextInfo returns a context that sets the driver context info value
+// on each connection checked out with the context.
+func WithContextInfo(ctx context.Context, info []byte) context.Context {
+	s := &Session{}
+	if parent := SessionFrom(ctx); parent != nil {
+		*s = *parent
+	}
+	s.ContextInfo = info
+	return WithSession(ctx, s)
+}
+
+// SessionFrom returns the Session set in ctx, or nil if none is set.
+func SessionFrom(ctx context.Context) *Session {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// applySession sets the session from ctx on a newly checked out connection.
+func (cp *ConnPool) applySession(ctx context.Context, conn DriverConn) error {
+	s := SessionFrom(ctx)
+	if s.empty() {
+		return nil
+	}
+	sc, ok := conn.(DriverSessionConn)
+	if !ok {
+		return fmt.Errorf("driver %s does not support session values: %w", cp.conf.DriverName, ErrNotImplemented)
+	}
+	return sc.SetSession(ctx, s)
+}
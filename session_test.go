@@ -0,0 +1,89 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sessionDriver struct {
+	mu       sync.Mutex
+	sessions []*Session
+}
+
+func (d *sessionDriver) DriverInfo() *DriverInfo { return &DriverInfo{} }
+func (d *sessionDriver) PingCommand() *Command   { return &Command{Arity: Zero} }
+func (d *sessionDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	return &sessionConn{dummyConn: dummyConn{opened: time.Now(), status: StatusReady}, d: d}, nil
+}
+
+type sessionConn struct {
+	dummyConn
+	d *sessionDriver
+}
+
+func (c *sessionConn) SetSession(ctx context.Context, s *Session) error {
+	c.d.mu.Lock()
+	c.d.sessions = append(c.d.sessions, s)
+	c.d.mu.Unlock()
+	return nil
+}
+
+func TestSessionContext(t *testing.T) {
+	d := &sessionDriver{}
+	Register("session_test", d)
+	pool, err := Open(&Config{DriverName: "session_test", PoolInitCapacity: 1, PoolMaxCapacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	res, err := pool.Query(ctx, &Command{Arity: Zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	if len(d.sessions) != 0 {
+		t.Fatalf("expected no session without values, got %d", len(d.sessions))
+	}
+
+	ctx = WithSessionContext(ctx, map[string]any{"tenant": 1})
+	ctx = WithSessionContext(ctx, map[string]any{"user": "a"})
+	ctx = WithContextInfo(ctx, []byte{1, 2})
+
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tran.Rollback()
+
+	if len(d.sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(d.sessions))
+	}
+	s := d.sessions[0]
+	if s.Values["tenant"] != 1 || s.Values["user"] != "a" || len(s.ContextInfo) != 2 {
+		t.Errorf("unexpected session %#v", s)
+	}
+}
+
+func TestSessionContextNotSupported(t *testing.T) {
+	Register("session_test_unsupported", &dummyDriver{})
+	pool, err := Open(&Config{DriverName: "session_test_unsupported", PoolInitCapacity: 1, PoolMaxCapacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := WithSessionContext(context.Background(), map[string]any{"tenant": 1})
+	_, err = pool.Query(ctx, &Command{Arity: Zero})
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("expected ErrNotImplemented, got %v", err)
+	}
+	capacity, available := pool.PoolAvailable()
+	if capacity != available {
+		t.Errorf("connection not returned to pool: capacity %d, available %d", capacity, available)
+	}
+}
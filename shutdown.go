@@ -0,0 +1,131 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPoolClosing is returned when a connection is requested from a pool
+// that is shutting down or closed.
+var ErrPoolClosing = errors.New("connection pool is closing")
+
+// DriverCanceler may be implemented by a DriverConn to cancel a running
+// command from another goroutine, such as by sending an attention signal.
+type DriverCanceler interface {
+	Cancel() error
+}
+
+const shutdownPoll = 5 * time.Millisecond
+
+// outConn is a connection handed out of the pool.
+type outConn struct {
+	p    Priority
+	kill bool // Close the connection when it is released.
+}
+
+// checkout records a connection handed out of the pool.
+func (cp *ConnPool) checkout(conn DriverConn, p Priority) {
+	cp.outMu.Lock()
+	if cp.out == nil {
+		cp.out = make(map[DriverConn]outConn)
+	}
+	cp.out[conn] = outConn{p: p}
+	cp.outMu.Unlock()
+}
+
+// checkin removes conn from the checked out connections.
+// Returns true if conn was marked to be closed on release.
+func (cp *ConnPool) checkin(conn DriverConn) (kill bool) {
+	cp.outMu.Lock()
+	out, ok := cp.out[conn]
+	delete(cp.out, conn)
+	cp.outMu.Unlock()
+	if ok {
+		cp.limit.release(out.p)
+	}
+	return out.kill
+}
+
+// killOnRelease marks every checked out connection to be closed
+// when it is released.
+func (cp *ConnPool) killOnRelease() {
+	cp.outMu.Lock()
+	for conn, out := range cp.out {
+		out.kill = true
+		cp.out[conn] = out
+	}
+	cp.outMu.Unlock()
+}
+
+func (cp *ConnPool) checkedOut() []DriverConn {
+	cp.outMu.Lock()
+	defer cp.outMu.Unlock()
+	list := make([]DriverConn, 0, len(cp.out))
+	for conn := range cp.out {
+		list = append(list, conn)
+	}
+	return list
+}
+
+// waitIdle waits until no connections are checked out.
+func (cp *ConnPool) waitIdle(ctx context.Context) error {
+	tick := time.NewTicker(shutdownPoll)
+	defer tick.Stop()
+	for cp.pool.InUse() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+	return nil
+}
+
+// Shutdown stops handing out connections, returning ErrPoolClosing, and waits
+// for running results, transactions, and connections to be released before
+// closing the pool.
+//
+// If ctx is done first, running commands are cancelled with DriverCanceler,
+// connections still checked out are closed when they are released instead
+// of being reused, and ctx.Err() is returned right away. The pool then
+// finishes closing in the background as the connections are released.
+func (cp *ConnPool) Shutdown(ctx context.Context) error {
+	cp.closing.Store(true)
+	err := cp.waitIdle(ctx)
+	if err == nil {
+		cp.Close()
+		return nil
+	}
+	for _, conn := range cp.checkedOut() {
+		if c, ok := conn.(DriverCanceler); ok {
+			c.Cancel()
+		}
+	}
+	cp.killOnRelease()
+	// A leaked transaction, result or connection may never be released,
+	// so do not wait for the pool to close.
+	go cp.Close()
+	return err
+}
+
+// Drain replaces every connection without stopping the pool, such as after
+// a credential rotation. Idle connections are closed and reopened now.
+// Checked out connections are closed when they are released.
+func (cp *ConnPool) Drain() {
+	cp.drainAt.Store(time.Now().UnixNano())
+	n := cp.pool.Keep(context.Background(), func(ctx context.Context, conn DriverConn) bool {
+		return !cp.drained(conn)
+	}, 0)
+	cp.stats.drained.Add(int64(n))
+}
+
+// drained returns true if conn was opened before the last Drain.
+func (cp *ConnPool) drained(conn DriverConn) bool {
+	at := cp.drainAt.Load()
+	return at != 0 && conn.Opened().UnixNano() < at
+}
@@ -0,0 +1,265 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// cancelDriver opens signal connections that implement DriverCanceler.
+type cancelDriver struct {
+	signalDriver
+}
+
+func (d *cancelDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	conn, err := d.signalDriver.Open(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &cancelConn{signalConn: conn.(*signalConn)}, nil
+}
+
+// cancelConn completes the running query when canceled.
+type cancelConn struct {
+	*signalConn
+}
+
+func (c *cancelConn) Cancel() error {
+	c.signal()
+	return nil
+}
+
+// waitQuery waits until a query has started on n connections.
+func waitQuery(t *testing.T, d *signalDriver, n int) []*signalConn {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var running []*signalConn
+		for _, c := range d.getConns() {
+			c.mu.Lock()
+			if c.status == StatusQuery {
+				running = append(running, c)
+			}
+			c.mu.Unlock()
+		}
+		if len(running) >= n {
+			return running
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d running queries", n)
+	return nil
+}
+
+func TestShutdownWaits(t *testing.T) {
+	d := &signalDriver{}
+	Register("shutdown_wait", d)
+	pool, err := Open(&Config{DriverName: "shutdown_wait", PoolInitCapacity: 2, PoolMaxCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	queryDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Query(ctx, &Command{Arity: Zero})
+		queryDone <- err
+	}()
+	running := waitQuery(t, d, 1)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- pool.Shutdown(shutdownCtx)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case err := <-shutdownDone:
+		t.Fatalf("shutdown returned before the query finished: %v", err)
+	default:
+	}
+	_, err = pool.Query(ctx, &Command{Arity: Zero})
+	if !errors.Is(err, ErrPoolClosing) {
+		t.Fatalf("query during shutdown: got %v, want ErrPoolClosing", err)
+	}
+
+	running[0].signal()
+	if err := <-queryDone; err != nil {
+		t.Fatalf("running query: %v", err)
+	}
+	if err := <-shutdownDone; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if _, err := pool.Query(ctx, &Command{Arity: Zero}); !errors.Is(err, ErrPoolClosing) {
+		t.Fatalf("query after shutdown: got %v, want ErrPoolClosing", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	d := &cancelDriver{}
+	Register("shutdown_deadline", d)
+	pool, err := Open(&Config{DriverName: "shutdown_deadline", PoolInitCapacity: 1, PoolMaxCapacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	queryDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Query(ctx, &Command{Arity: Zero})
+		queryDone <- err
+	}()
+	running := waitQuery(t, &d.signalDriver, 1)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err = pool.Shutdown(shutdownCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown: got %v, want deadline exceeded", err)
+	}
+	select {
+	case err := <-queryDone:
+		if err != nil {
+			t.Fatalf("canceled query: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("running query was not canceled")
+	}
+	if !running[0].isClosed() {
+		t.Fatal("canceled connection was not closed on release")
+	}
+}
+
+func TestShutdownDeadlineInUse(t *testing.T) {
+	d := &signalDriver{}
+	Register("shutdownrelease", d)
+	pool, err := Open(&Config{DriverName: "shutdownrelease", PoolInitCapacity: 1, PoolMaxCapacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	queryDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Query(ctx, &Command{Arity: Zero})
+		queryDone <- err
+	}()
+	running := waitQuery(t, d, 1)[0]
+
+	// Shutdown returns at the deadline without waiting for the release.
+	shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(shutdownCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown: got %v, want deadline exceeded", err)
+	}
+	if running.isClosed() {
+		t.Fatal("connection closed while in use")
+	}
+
+	running.signal()
+	if err := <-queryDone; err != nil {
+		t.Fatalf("running query: %v", err)
+	}
+	if !running.isClosed() {
+		t.Fatal("connection was not closed on release")
+	}
+	if n := len(d.getConns()); n != 1 {
+		t.Fatalf("got %d connections, want no replacement opened", n)
+	}
+}
+
+func TestShutdownDeadlineLeaked(t *testing.T) {
+	Register("shutdownleaked", &dummyDriver{})
+	pool, err := Open(&Config{DriverName: "shutdownleaked", PoolInitCapacity: 1, PoolMaxCapacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// An idle transaction that is never ended holds its connection.
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- pool.Shutdown(shutdownCtx)
+	}()
+	select {
+	case err := <-shutdownDone:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("shutdown: got %v, want deadline exceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("shutdown did not return after the deadline")
+	}
+	tran.Rollback()
+}
+
+func TestDrain(t *testing.T) {
+	d := &signalDriver{}
+	Register("drain", d)
+	pool, err := Open(&Config{DriverName: "drain", PoolInitCapacity: 2, PoolMaxCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	ctx := context.Background()
+
+	queryDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Query(ctx, &Command{Arity: Zero})
+		queryDone <- err
+	}()
+	held := waitQuery(t, d, 1)[0]
+
+	// Open a second connection that is idle during the drain.
+	secondDone := make(chan error, 1)
+	go func() {
+		_, err := pool.Query(ctx, &Command{Arity: Zero})
+		secondDone <- err
+	}()
+	for _, c := range waitQuery(t, d, 2) {
+		if c != held {
+			c.signal()
+		}
+	}
+	if err := <-secondDone; err != nil {
+		t.Fatal(err)
+	}
+	conns := d.getConns()
+	if len(conns) != 2 {
+		t.Fatalf("got %d connections, want 2", len(conns))
+	}
+	idle := conns[0]
+	if idle == held {
+		idle = conns[1]
+	}
+
+	pool.Drain()
+	if !idle.isClosed() {
+		t.Fatal("idle connection not closed by drain")
+	}
+	if held.isClosed() {
+		t.Fatal("checked out connection closed by drain")
+	}
+	if got := pool.Stats().Drained; got != 1 {
+		t.Fatalf("drained %d, want 1", got)
+	}
+
+	held.signal()
+	if err := <-queryDone; err != nil {
+		t.Fatal(err)
+	}
+	if !held.isClosed() {
+		t.Fatal("drained connection not closed on release")
+	}
+	if got := pool.Stats().Drained; got != 2 {
+		t.Fatalf("drained %d, want 2", got)
+	}
+}
@@ -10,6 +10,11 @@ package sql
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/kardianos/rdb"
 	"github.com/kardianos/rdb/sql/driver"
@@ -20,6 +25,11 @@ var ErrNoRows = errors.New("sql: no rows in result set")
 
 var ErrTxDone = errors.New("sql: Transaction has already been committed or rolled back")
 
+// ErrConnDone is returned by any operation that is performed on a connection that has already been returned to the connection pool.
+var ErrConnDone = errors.New("sql: connection is already closed")
+
+var errStmtClosed = errors.New("sql: statement is closed")
+
 // DB is a database handle representing a pool of zero or more underlying connections. It's safe for concurrent use by multiple goroutines.
 //
 // The sql package creates and frees connections automatically; it also maintains a free pool of idle connections. If the database has a concept of per-connection state, such state can only be reliably observed within a transaction. Once DB.Begin is called, the returned Tx is bound to a single connection. Once Commit or Rollback is called on the transaction, that transaction's connection is returned to DB's idle connection pool. The pool size can be controlled with SetMaxIdleConns.
@@ -52,9 +62,88 @@ func Open(config *rdb.Config) (*DB, error) {
 	return NewDB(pool), nil
 }
 
+// IsolationLevel is the transaction isolation level used in TxOptions.
+type IsolationLevel int
+
+// Various isolation levels that drivers may support in BeginTx. If a driver does not support a given isolation level an error may be returned.
+const (
+	LevelDefault IsolationLevel = iota
+	LevelReadUncommitted
+	LevelReadCommitted
+	LevelWriteCommitted
+	LevelRepeatableRead
+	LevelSnapshot
+	LevelSerializable
+	LevelLinearizable
+)
+
+var isolationNames = [...]string{
+	LevelDefault:         "Default",
+	LevelReadUncommitted: "Read Uncommitted",
+	LevelReadCommitted:   "Read Committed",
+	LevelWriteCommitted:  "Write Committed",
+	LevelRepeatableRead:  "Repeatable Read",
+	LevelSnapshot:        "Snapshot",
+	LevelSerializable:    "Serializable",
+	LevelLinearizable:    "Linearizable",
+}
+
+// String returns the name of the transaction isolation level.
+func (i IsolationLevel) String() string {
+	if i < 0 || int(i) >= len(isolationNames) {
+		return fmt.Sprintf("IsolationLevel(%d)", i)
+	}
+	return isolationNames[i]
+}
+
+var isolationLevels = map[IsolationLevel]rdb.IsolationLevel{
+	LevelDefault:         rdb.LevelDefault,
+	LevelReadUncommitted: rdb.LevelReadUncommitted,
+	LevelReadCommitted:   rdb.LevelReadCommitted,
+	LevelWriteCommitted:  rdb.LevelWriteCommitted,
+	LevelRepeatableRead:  rdb.LevelRepeatableRead,
+	LevelSnapshot:        rdb.LevelSnapshot,
+	LevelSerializable:    rdb.LevelSerializable,
+}
+
+// TxOptions holds the transaction options to be used in DB.BeginTx.
+type TxOptions struct {
+	// Isolation is the transaction isolation level.
+	// If zero, the driver or database's default level is used.
+	Isolation IsolationLevel
+	ReadOnly  bool
+}
+
+func (opts *TxOptions) level() (rdb.IsolationLevel, error) {
+	if opts == nil {
+		return rdb.LevelDefault, nil
+	}
+	if opts.ReadOnly {
+		return 0, errors.New("sql: read-only transactions are not supported")
+	}
+	level, ok := isolationLevels[opts.Isolation]
+	if !ok {
+		return 0, fmt.Errorf("sql: isolation level %v is not supported", opts.Isolation)
+	}
+	return level, nil
+}
+
 // Begin starts a transaction. The isolation level is dependent on the driver.
 func (db *DB) Begin() (*Tx, error) {
-	tran, err := db.pool.Begin(context.Background())
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction.
+//
+// The provided context is used until the transaction is committed or rolled back.
+//
+// The provided TxOptions is optional and may be nil if defaults should be used. If a non-default isolation level is used that the driver doesn't support, an error will be returned.
+func (db *DB) BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	level, err := opts.level()
+	if err != nil {
+		return nil, err
+	}
+	tran, err := db.pool.BeginLevel(ctx, level)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +160,17 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// Conn returns a single connection by either opening a new connection or returning an existing connection from the connection pool. Conn will block until either a connection is returned or ctx is canceled. Queries run on the same Conn will be run in the same database session.
+//
+// Every Conn must be returned to the database pool after use by calling Conn.Close.
+func (db *DB) Conn(ctx context.Context) (*Conn, error) {
+	c, err := db.pool.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn: c}, nil
+}
+
 // A Result summarizes an executed SQL command.
 type result struct {
 	res *rdb.Result
@@ -83,87 +183,210 @@ func (r result) RowsAffected() (int64, error) {
 	return int64(r.res.RowsAffected()), nil
 }
 
+// NamedArg is a named argument. NamedArg values may be used as arguments to Query or Exec and bind to the corresponding named parameter in the SQL statement.
+//
+// For a more concise way to create NamedArg values, see the Named function.
+type NamedArg struct {
+	_NamedFieldsRequired struct{}
+
+	// Name is the name of the parameter placeholder.
+	Name string
+
+	// Value is the value of the parameter.
+	Value interface{}
+}
+
+// Named provides a more concise way to create NamedArg values.
+//
+// Example usage:
+//
+//	db.ExecContext(ctx, `
+//	    delete from Invoice
+//	    where
+//	        TimeCreated < @end
+//	        and TimeCreated >= @start;`,
+//	    sql.Named("start", startTime),
+//	    sql.Named("end", endTime),
+//	)
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// prepParams converts the args to params. An arg may be a NamedArg or an
+// rdb.Param.
 func prepParams(args []interface{}) []rdb.Param {
 	params := make([]rdb.Param, len(args))
-	for i, value := range args {
-		params[i].Value = value
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case NamedArg:
+			params[i] = rdb.Param{Name: v.Name, Value: v.Value}
+		case rdb.Param:
+			params[i] = v
+		default:
+			params[i].Value = arg
+		}
 	}
 	return params
 }
 func prep(arity rdb.Arity, query string, args []interface{}) (*rdb.Command, []rdb.Param) {
-	params := make([]rdb.Param, len(args))
-	for i, value := range args {
-		params[i].Value = value
-	}
 	return &rdb.Command{
 		SQL:   query,
 		Arity: arity,
-	}, params
+	}, prepParams(args)
 }
 
-// Exec executes a query without returning any rows. The args are for any placeholder parameters in the query.
-func (db *DB) Exec(query string, args ...interface{}) (Result, error) {
-	ctx := context.Background()
-	cmd, params := prep(rdb.Zero, query, args)
-	res, err := db.pool.Query(ctx, cmd, params...)
+func exec(ctx context.Context, q rdb.Queryer, cmd *rdb.Command, params []rdb.Param) (Result, error) {
+	res, err := q.Query(ctx, cmd, params...)
 	if err != nil {
 		return nil, err
 	}
-	return result{res: res}, nil
+	return result{res: res}, res.Close()
+}
+
+func queryRows(ctx context.Context, q rdb.Queryer, cmd *rdb.Command, params []rdb.Param) (*Rows, error) {
+	res, err := q.Query(ctx, cmd, params...)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{res: res}, nil
+}
+
+func queryRow(ctx context.Context, q rdb.Queryer, cmd *rdb.Command, params []rdb.Param) *Row {
+	res, err := q.Query(ctx, cmd, params...)
+	row := &Row{res: res}
+	if err != nil {
+		if err == rdb.ErrArity {
+			err = ErrNoRows
+		}
+		row.err = err
+	}
+	return row
+}
+
+// Exec executes a query without returning any rows. The args are for any placeholder parameters in the query.
+func (db *DB) Exec(query string, args ...interface{}) (Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows. The args are for any placeholder parameters in the query.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	cmd, params := prep(rdb.Zero, query, args)
+	return exec(ctx, db.pool, cmd, params)
 }
 
 // Ping verifies a connection to the database is still alive, establishing a connection if necessary.
 func (db *DB) Ping() error {
-	ctx := context.Background()
+	return db.PingContext(context.Background())
+}
+
+// PingContext verifies a connection to the database is still alive, establishing a connection if necessary.
+func (db *DB) PingContext(ctx context.Context) error {
 	return db.pool.Ping(ctx)
 }
 
 // Prepare creates a prepared statement for later queries or executions. Multiple queries or executions may be run concurrently from the returned statement.
 func (db *DB) Prepare(query string) (*Stmt, error) {
-	return &Stmt{
-		q: db.pool,
-		cmd: &rdb.Command{
-			SQL: query,
-		},
-	}, nil
+	return db.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext creates a prepared statement for later queries or executions. Multiple queries or executions may be run concurrently from the returned statement. The statement is not prepared on the server; the query is sent each time the statement is run, so ctx is not used.
+func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	return newStmt(db.pool, query), nil
 }
 
 // Query executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query.
 func (db *DB) Query(query string, args ...interface{}) (*Rows, error) {
-	ctx := context.Background()
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
 	cmd, params := prep(rdb.Any, query, args)
-	res, err := db.pool.Query(ctx, cmd, params...)
-	if err != nil {
-		return nil, err
-	}
-	return &Rows{res: res}, nil
+	return queryRows(ctx, db.pool, cmd, params)
 }
 
 // QueryRow executes a query that is expected to return at most one row. QueryRow always return a non-nil value. Errors are deferred until Row's Scan method is called.
 func (db *DB) QueryRow(query string, args ...interface{}) *Row {
-	ctx := context.Background()
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row. QueryRowContext always returns a non-nil value. Errors are deferred until Row's Scan method is called.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
 	cmd, params := prep(rdb.One, query, args)
-	res, err := db.pool.Query(ctx, cmd, params...)
-	row := &Row{res: res}
-	if err != nil {
-		if err == rdb.ErrArity {
-			err = ErrNoRows
-		}
-		row.err = err
-	}
-	return row
+	return queryRow(ctx, db.pool, cmd, params)
 }
 
-// SetMaxIdleConns is not supported directly.
-// Set the PoolInitCapacity field in the rdb.Config.
+// SetMaxIdleConns sets the number of idle connections the health check keeps open, see the HealthCheckInterval field in the rdb.Config. The capacity of the connection pool is not changed. If n <= 0, no idle connections are kept.
 func (db *DB) SetMaxIdleConns(n int) {
-	panic("Set PoolInitCapacity in rdb.Config.")
+	db.pool.SetMinIdle(n)
 }
 
-// SetMaxOpenConns is not supported directly.
-// Set the PoolMaxCapacity field in the rdb.Config.
+// SetMaxOpenConns sets the maximum number of open connections to the database, up to the PoolMaxCapacity field in the rdb.Config. If n <= 0, the limit is PoolMaxCapacity.
 func (db *DB) SetMaxOpenConns(n int) {
-	panic("Set PoolMaxCapacity in rdb.Config.")
+	db.pool.SetMaxCapacity(n)
+}
+
+// SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
+//
+// Expired connections may be closed lazily before reuse.
+//
+// If d <= 0, connections are reused forever.
+func (db *DB) SetConnMaxLifetime(d time.Duration) {
+	db.pool.SetMaxLifetime(d)
+}
+
+// Conn represents a single database connection rather than a pool of database connections. Prefer running queries from DB unless there is a specific need for a continuous single database connection.
+//
+// A Conn must call Close to return the connection to the database pool and may do so concurrently with a running query.
+type Conn struct {
+	conn *rdb.Connection
+}
+
+func (c *Conn) Normal() *rdb.Connection {
+	return c.conn
+}
+
+// Close returns the connection to the connection pool. All operations after a Close will return with ErrConnDone. Close is safe to call concurrently with other operations and will block until all other operations finish.
+func (c *Conn) Close() error {
+	if !c.conn.Active() {
+		return ErrConnDone
+	}
+	return c.conn.Close()
+}
+
+// ExecContext executes a query without returning any rows. The args are for any placeholder parameters in the query.
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	if !c.conn.Active() {
+		return nil, ErrConnDone
+	}
+	cmd, params := prep(rdb.Zero, query, args)
+	return exec(ctx, c.conn, cmd, params)
+}
+
+// PrepareContext creates a prepared statement for later queries or executions on the connection.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	if !c.conn.Active() {
+		return nil, ErrConnDone
+	}
+	return newStmt(c.conn, query), nil
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query.
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if !c.conn.Active() {
+		return nil, ErrConnDone
+	}
+	cmd, params := prep(rdb.Any, query, args)
+	return queryRows(ctx, c.conn, cmd, params)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row. QueryRowContext always returns a non-nil value. Errors are deferred until Row's Scan method is called.
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	if !c.conn.Active() {
+		return &Row{err: ErrConnDone}
+	}
+	cmd, params := prep(rdb.One, query, args)
+	return queryRow(ctx, c.conn, cmd, params)
 }
 
 // NullBool represents a bool that may be null. NullBool implements the Scanner interface so it can be used as a scan destination, similar to NullString.
@@ -239,6 +462,60 @@ func (ns NullString) Value() (driver.Value, error) {
 	return nil, nil
 }
 
+// Null represents a value that may be null. Null implements rdb.ColumnScanner so it can be used as a scan destination, and rdb.ParamValuer so it can be used as a parameter:
+//
+//	var s Null[string]
+//	err := db.QueryRow("SELECT name FROM foo WHERE id=?", id).Scan(&s)
+//	...
+//	if s.Valid {
+//	   // use s.V
+//	} else {
+//	   // NULL value
+//	}
+type Null[T any] struct {
+	V     T
+	Valid bool
+}
+
+// ScanColumn implements the rdb.ColumnScanner interface.
+// The column may be nil when the driver assigns the value directly.
+func (n *Null[T]) ScanColumn(c *rdb.Column, value rdb.Nullable) error {
+	if value.Null || value.Value == nil {
+		*n = Null[T]{}
+		return nil
+	}
+	if c == nil {
+		c = &rdb.Column{}
+	}
+	err := rdb.AssignValue(c, value, &n.V, nil)
+	n.Valid = err == nil
+	return err
+}
+
+// Scan implements the Scanner interface.
+func (n *Null[T]) Scan(value interface{}) error {
+	return n.ScanColumn(&rdb.Column{}, rdb.Nullable{Value: value})
+}
+
+// ParamValue implements the rdb.ParamValuer interface.
+func (n Null[T]) ParamValue(p *rdb.Param) error {
+	if !n.Valid {
+		p.Value = nil
+		p.Null = true
+		return nil
+	}
+	p.Value = n.V
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n Null[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.V, nil
+}
+
 // RawBytes is a byte slice that holds a reference to memory owned by the database itself. After a Scan into a RawBytes, the slice is only valid until the next call to Next, Scan, or Close.
 type RawBytes []byte
 
@@ -268,9 +545,18 @@ func (r *Row) Scan(dest ...interface{}) error {
 	if r.err != nil {
 		return r.err
 	}
+	defer r.res.Close()
+	if !r.res.Next() {
+		return ErrNoRows
+	}
 	return r.res.Scan(dest...)
 }
 
+// Err provides a way for wrapping packages to check for query errors without calling Scan. Err returns the error, if any, that was encountered while running the query. If this error is not nil, this error will also be returned from Scan.
+func (r *Row) Err() error {
+	return r.err
+}
+
 // Rows is the result of a query. Its cursor starts before the first row of the result set. Use Next to advance through the rows:
 
 // rows, err := db.Query("SELECT ...")
@@ -287,6 +573,7 @@ func (r *Row) Scan(dest ...interface{}) error {
 //	...
 type Rows struct {
 	res *rdb.Result
+	err error
 }
 
 // Close closes the Rows, preventing further enumeration. If Next returns false, the Rows are closed automatically and it will suffice to check the result of Err. Close is idempotent and does not affect the result of Err.
@@ -304,9 +591,19 @@ func (rs *Rows) Columns() ([]string, error) {
 	return names, nil
 }
 
+// ColumnTypes returns column information such as column type, length, and nullable. Some information may not be available from some drivers.
+func (rs *Rows) ColumnTypes() ([]*ColumnType, error) {
+	schema := rs.res.Schema()
+	list := make([]*ColumnType, len(schema))
+	for i, col := range schema {
+		list[i] = &ColumnType{col: col}
+	}
+	return list, nil
+}
+
 // Err returns the error, if any, that was encountered during iteration. Err may be called after an explicit or implicit Close.
 func (rs *Rows) Err() error {
-	return nil
+	return rs.err
 }
 
 // Next prepares the next result row for reading with the Scan method. It returns true on success, or false if there is no next result row or an error happened while preparing it. Err should be consulted to distinguish between the two cases.
@@ -316,6 +613,18 @@ func (rs *Rows) Next() bool {
 	return rs.res.Next()
 }
 
+// NextResultSet prepares the next result set for reading. It reports whether there is further result sets, or false if there is no further result set or if there is an error advancing to it. The Err method should be consulted to distinguish between the two cases.
+//
+// After calling NextResultSet, the Next method should always be called before scanning.
+func (rs *Rows) NextResultSet() bool {
+	more, err := rs.res.NextResult()
+	if err != nil {
+		rs.err = err
+		return false
+	}
+	return more
+}
+
 // Scan copies the columns in the current row into the values pointed at by dest.
 //
 // If an argument has type *[]byte, Scan saves in that argument a copy of the corresponding data. The copy is owned by the caller and can be modified and held indefinitely. The copy can be avoided by using an argument of type *RawBytes instead; see the documentation for RawBytes for restrictions on its use.
@@ -325,6 +634,123 @@ func (rs *Rows) Scan(dest ...interface{}) error {
 	return rs.res.Scan(dest...)
 }
 
+// ColumnType contains the name and type of a column.
+type ColumnType struct {
+	col *rdb.Column
+}
+
+func (ci *ColumnType) Normal() *rdb.Column {
+	return ci.col
+}
+
+// Name returns the name or alias of the column.
+func (ci *ColumnType) Name() string {
+	return ci.col.Name
+}
+
+// Length returns the column type length for variable length column types such as text and binary field types. If the type length is unbounded the value will be math.MaxInt64. If the column type is not variable length, such as an int, ok is false.
+func (ci *ColumnType) Length() (length int64, ok bool) {
+	switch ci.col.Generic {
+	case rdb.Text, rdb.Binary:
+	default:
+		return 0, false
+	}
+	if ci.col.Unlimit {
+		return math.MaxInt64, true
+	}
+	return int64(ci.col.Length), true
+}
+
+// DecimalSize returns the scale and precision of a decimal type. If not applicable or if not supported ok is false.
+func (ci *ColumnType) DecimalSize() (precision, scale int64, ok bool) {
+	if ci.col.Generic != rdb.Numeric {
+		return 0, 0, false
+	}
+	return int64(ci.col.Precision), int64(ci.col.Scale), true
+}
+
+// ScanType returns a Go type suitable for scanning into using Rows.Scan. If a driver does not support this property ScanType will return the type of an empty interface.
+func (ci *ColumnType) ScanType() reflect.Type {
+	if t, ok := scanTypes[ci.col.Type]; ok {
+		return t
+	}
+	return reflect.TypeFor[interface{}]()
+}
+
+// Nullable reports whether the column may be null.
+func (ci *ColumnType) Nullable() (nullable, ok bool) {
+	return ci.col.Nullable, true
+}
+
+// DatabaseTypeName returns the database system name of the column type. If an empty string is returned, then the driver type name is not supported. Length specifiers are not included. Common type names include "VARCHAR", "TEXT", "NVARCHAR", "DECIMAL", "BOOL", "INT", and "BIGINT".
+func (ci *ColumnType) DatabaseTypeName() string {
+	return typeNames[ci.col.Type]
+}
+
+var scanTypes = map[rdb.Type]reflect.Type{
+	rdb.TypeText:        reflect.TypeFor[string](),
+	rdb.TypeAnsiText:    reflect.TypeFor[string](),
+	rdb.TypeVarChar:     reflect.TypeFor[string](),
+	rdb.TypeAnsiVarChar: reflect.TypeFor[string](),
+	rdb.TypeChar:        reflect.TypeFor[string](),
+	rdb.TypeAnsiChar:    reflect.TypeFor[string](),
+	rdb.TypeBinary:      reflect.TypeFor[[]byte](),
+	rdb.TypeBool:        reflect.TypeFor[bool](),
+	rdb.TypeUint8:       reflect.TypeFor[uint8](),
+	rdb.TypeUint16:      reflect.TypeFor[uint16](),
+	rdb.TypeUint32:      reflect.TypeFor[uint32](),
+	rdb.TypeUint64:      reflect.TypeFor[uint64](),
+	rdb.TypeInt8:        reflect.TypeFor[int8](),
+	rdb.TypeInt16:       reflect.TypeFor[int16](),
+	rdb.TypeInt32:       reflect.TypeFor[int32](),
+	rdb.TypeInt64:       reflect.TypeFor[int64](),
+	rdb.TypeSerial16:    reflect.TypeFor[int16](),
+	rdb.TypeSerial32:    reflect.TypeFor[int32](),
+	rdb.TypeSerial64:    reflect.TypeFor[int64](),
+	rdb.TypeFloat32:     reflect.TypeFor[float32](),
+	rdb.TypeFloat64:     reflect.TypeFor[float64](),
+	rdb.TypeDecimal:     reflect.TypeFor[rdb.Decimal](),
+	rdb.TypeMoney:       reflect.TypeFor[rdb.Decimal](),
+	rdb.TypeTimestampz:  reflect.TypeFor[time.Time](),
+	rdb.TypeDuration:    reflect.TypeFor[time.Duration](),
+	rdb.TypeTime:        reflect.TypeFor[time.Time](),
+	rdb.TypeDate:        reflect.TypeFor[time.Time](),
+	rdb.TypeTimestamp:   reflect.TypeFor[time.Time](),
+	rdb.TypeUUID:        reflect.TypeFor[[]byte](),
+	rdb.TypeJSON:        reflect.TypeFor[string](),
+	rdb.TypeXML:         reflect.TypeFor[string](),
+}
+
+var typeNames = map[rdb.Type]string{
+	rdb.TypeText:        "NTEXT",
+	rdb.TypeAnsiText:    "TEXT",
+	rdb.TypeVarChar:     "NVARCHAR",
+	rdb.TypeAnsiVarChar: "VARCHAR",
+	rdb.TypeChar:        "NCHAR",
+	rdb.TypeAnsiChar:    "CHAR",
+	rdb.TypeBinary:      "VARBINARY",
+	rdb.TypeBool:        "BIT",
+	rdb.TypeUint8:       "TINYINT",
+	rdb.TypeInt8:        "TINYINT",
+	rdb.TypeInt16:       "SMALLINT",
+	rdb.TypeInt32:       "INT",
+	rdb.TypeInt64:       "BIGINT",
+	rdb.TypeSerial16:    "SMALLINT",
+	rdb.TypeSerial32:    "INT",
+	rdb.TypeSerial64:    "BIGINT",
+	rdb.TypeFloat32:     "REAL",
+	rdb.TypeFloat64:     "FLOAT",
+	rdb.TypeDecimal:     "DECIMAL",
+	rdb.TypeMoney:       "MONEY",
+	rdb.TypeTimestampz:  "DATETIMEOFFSET",
+	rdb.TypeTime:        "TIME",
+	rdb.TypeDate:        "DATE",
+	rdb.TypeTimestamp:   "DATETIME2",
+	rdb.TypeUUID:        "UNIQUEIDENTIFIER",
+	rdb.TypeJSON:        "JSON",
+	rdb.TypeXML:         "XML",
+}
+
 // Scanner is an interface used by Scan.
 type Scanner interface {
 	// Scan assigns a value from a database driver.
@@ -349,32 +775,49 @@ type Scanner interface {
 type Stmt struct {
 	q   rdb.Queryer
 	cmd *rdb.Command
+
+	closed atomic.Bool
 }
 
-// Close closes the statement.
+func newStmt(q rdb.Queryer, query string) *Stmt {
+	return &Stmt{
+		q: q,
+		cmd: &rdb.Command{
+			SQL: query,
+		},
+	}
+}
+
+// Close closes the statement. Using the statement after it is closed returns an error.
 func (s *Stmt) Close() error {
-	// TODO: Unprepare any statement.
+	s.closed.Store(true)
 	return nil
 }
 
 // Exec executes a prepared statement with the given arguments and returns a Result summarizing the effect of the statement.
 func (s *Stmt) Exec(args ...interface{}) (Result, error) {
-	ctx := context.Background()
-	res, err := s.q.Query(ctx, s.cmd, prepParams(args)...)
-	if err != nil {
-		return nil, err
+	return s.ExecContext(context.Background(), args...)
+}
+
+// ExecContext executes a prepared statement with the given arguments and returns a Result summarizing the effect of the statement.
+func (s *Stmt) ExecContext(ctx context.Context, args ...interface{}) (Result, error) {
+	if s.closed.Load() {
+		return nil, errStmtClosed
 	}
-	return result{res: res}, res.Close()
+	return exec(ctx, s.q, s.cmd, prepParams(args))
 }
 
 // Query executes a prepared query statement with the given arguments and returns the query results as a *Rows.
 func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
-	ctx := context.Background()
-	res, err := s.q.Query(ctx, s.cmd, prepParams(args)...)
-	if err != nil {
-		return nil, err
+	return s.QueryContext(context.Background(), args...)
+}
+
+// QueryContext executes a prepared query statement with the given arguments and returns the query results as a *Rows.
+func (s *Stmt) QueryContext(ctx context.Context, args ...interface{}) (*Rows, error) {
+	if s.closed.Load() {
+		return nil, errStmtClosed
 	}
-	return &Rows{res: res}, nil
+	return queryRows(ctx, s.q, s.cmd, prepParams(args))
 }
 
 // QueryRow executes a prepared query statement with the given arguments. If an error occurs during the execution of the statement, that error will be returned by a call to Scan on the returned *Row, which is always non-nil. If the query selects no rows, the *Row's Scan will return ErrNoRows. Otherwise, the *Row's Scan scans the first selected row and discards the rest.
@@ -383,29 +826,23 @@ func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
 //
 //	var name string
 //	err := nameByUseridStmt.QueryRow(id).Scan(&name)
-//	type Tx
-//	type Tx struct {
-//	    // contains filtered or unexported fields
-//	}
-//
+func (s *Stmt) QueryRow(args ...interface{}) *Row {
+	return s.QueryRowContext(context.Background(), args...)
+}
+
+// QueryRowContext executes a prepared query statement with the given arguments. If an error occurs during the execution of the statement, that error will be returned by a call to Scan on the returned *Row, which is always non-nil. If the query selects no rows, the *Row's Scan will return ErrNoRows. Otherwise, the *Row's Scan scans the first selected row and discards the rest.
+func (s *Stmt) QueryRowContext(ctx context.Context, args ...interface{}) *Row {
+	if s.closed.Load() {
+		return &Row{err: errStmtClosed}
+	}
+	return queryRow(ctx, s.q, s.cmd, prepParams(args))
+}
+
 // Tx is an in-progress database transaction.
 //
 // A transaction must end with a call to Commit or Rollback.
 //
 // After a call to Commit or Rollback, all operations on the transaction fail with ErrTxDone.
-func (s *Stmt) QueryRow(args ...interface{}) *Row {
-	ctx := context.Background()
-	res, err := s.q.Query(ctx, s.cmd, prepParams(args)...)
-	row := &Row{res: res}
-	if err != nil {
-		if err == rdb.ErrArity {
-			err = ErrNoRows
-		}
-		row.err = err
-	}
-	return row
-}
-
 type Tx struct {
 	tran *rdb.Transaction
 }
@@ -416,18 +853,24 @@ func (tx *Tx) Normal() *rdb.Transaction {
 
 // Commit commits the transaction.
 func (tx *Tx) Commit() error {
+	if !tx.tran.Active() {
+		return ErrTxDone
+	}
 	return tx.tran.Commit()
 }
 
 // Exec executes a query that doesn't return rows. For example: an INSERT and UPDATE.
 func (tx *Tx) Exec(query string, args ...interface{}) (Result, error) {
-	ctx := context.Background()
-	cmd, params := prep(rdb.Zero, query, args)
-	res, err := tx.tran.Query(ctx, cmd, params...)
-	if err != nil {
-		return nil, err
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query that doesn't return rows. For example: an INSERT and UPDATE.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	if !tx.tran.Active() {
+		return nil, ErrTxDone
 	}
-	return result{res: res}, nil
+	cmd, params := prep(rdb.Zero, query, args)
+	return exec(ctx, tx.tran, cmd, params)
 }
 
 // Prepare creates a prepared statement for use within a transaction.
@@ -436,42 +879,52 @@ func (tx *Tx) Exec(query string, args ...interface{}) (Result, error) {
 //
 // To use an existing prepared statement on this transaction, see Tx.Stmt.
 func (tx *Tx) Prepare(query string) (*Stmt, error) {
-	return &Stmt{
-		q: tx.tran,
-		cmd: &rdb.Command{
-			SQL: query,
-		},
-	}, nil
+	return tx.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext creates a prepared statement for use within a transaction.
+//
+// The returned statement operates within the transaction and can no longer be used once the transaction has been committed or rolled back.
+func (tx *Tx) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	if !tx.tran.Active() {
+		return nil, ErrTxDone
+	}
+	return newStmt(tx.tran, query), nil
 }
 
 // Query executes a query that returns rows, typically a SELECT.
 func (tx *Tx) Query(query string, args ...interface{}) (*Rows, error) {
-	ctx := context.Background()
-	cmd, params := prep(rdb.Any, query, args)
-	res, err := tx.tran.Query(ctx, cmd, params...)
-	if err != nil {
-		return nil, err
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if !tx.tran.Active() {
+		return nil, ErrTxDone
 	}
-	return &Rows{res: res}, nil
+	cmd, params := prep(rdb.Any, query, args)
+	return queryRows(ctx, tx.tran, cmd, params)
 }
 
 // QueryRow executes a query that is expected to return at most one row. QueryRow always return a non-nil value. Errors are deferred until Row's Scan method is called.
 func (tx *Tx) QueryRow(query string, args ...interface{}) *Row {
-	ctx := context.Background()
-	cmd, params := prep(rdb.Any, query, args)
-	res, err := tx.tran.Query(ctx, cmd, params...)
-	row := &Row{res: res}
-	if err != nil {
-		if err == rdb.ErrArity {
-			err = ErrNoRows
-		}
-		row.err = err
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row. QueryRowContext always returns a non-nil value. Errors are deferred until Row's Scan method is called.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	if !tx.tran.Active() {
+		return &Row{err: ErrTxDone}
 	}
-	return row
+	cmd, params := prep(rdb.One, query, args)
+	return queryRow(ctx, tx.tran, cmd, params)
 }
 
 // Rollback aborts the transaction.
 func (tx *Tx) Rollback() error {
+	if !tx.tran.Active() {
+		return ErrTxDone
+	}
 	return tx.tran.Rollback()
 }
 
@@ -485,8 +938,15 @@ func (tx *Tx) Rollback() error {
 //	...
 //	res, err := tx.Stmt(updateMoney).Exec(123.45, 98293203)
 func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
-	ns := *stmt
-	s := &ns
-	s.q = tx.tran
+	return tx.StmtContext(context.Background(), stmt)
+}
+
+// StmtContext returns a transaction-specific prepared statement from an existing statement.
+func (tx *Tx) StmtContext(ctx context.Context, stmt *Stmt) *Stmt {
+	s := &Stmt{
+		q:   tx.tran,
+		cmd: stmt.cmd,
+	}
+	s.closed.Store(stmt.closed.Load())
 	return s
 }
@@ -0,0 +1,326 @@
+package sql
+
+import (
+	"context"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kardianos/rdb"
+)
+
+// fakeDriver returns the rows of each set for every query.
+type fakeDriver struct {
+	sets [][][]any
+	cols []*rdb.Column
+}
+
+func (d *fakeDriver) DriverInfo() *rdb.DriverInfo {
+	return &rdb.DriverInfo{DriverSupport: rdb.DriverSupport{NamedParameter: true}}
+}
+
+func (d *fakeDriver) Open(ctx context.Context, c *rdb.Config) (rdb.DriverConn, error) {
+	return &fakeConn{d: d, opened: time.Now()}, nil
+}
+
+func (d *fakeDriver) PingCommand() *rdb.Command {
+	return &rdb.Command{SQL: "ping", Arity: rdb.Zero}
+}
+
+type fakeConn struct {
+	d      *fakeDriver
+	opened time.Time
+	avail  bool
+	status rdb.DriverConnStatus
+
+	val      rdb.DriverValuer
+	set, row int
+	params   []rdb.Param
+}
+
+func (c *fakeConn) Close()                                           {}
+func (c *fakeConn) Available() bool                                  { return c.avail }
+func (c *fakeConn) SetAvailable(available bool)                      { c.avail = available }
+func (c *fakeConn) ConnectionInfo() *rdb.ConnectionInfo              { return nil }
+func (c *fakeConn) Opened() time.Time                                { return c.opened }
+func (c *fakeConn) Status() rdb.DriverConnStatus                     { return c.status }
+func (c *fakeConn) Prepare(*rdb.Command) (interface{}, error)        { return nil, nil }
+func (c *fakeConn) Unprepare(interface{}) error                      { return nil }
+func (c *fakeConn) Begin(context.Context, rdb.IsolationLevel) error  { return nil }
+func (c *fakeConn) Rollback(savepoint string) error                  { return nil }
+func (c *fakeConn) Commit(context.Context) error                     { return nil }
+func (c *fakeConn) SavePoint(ctx context.Context, name string) error { return nil }
+
+func (c *fakeConn) Reset(*rdb.Config) error {
+	c.status = rdb.StatusReady
+	return nil
+}
+
+func (c *fakeConn) Query(ctx context.Context, cmd *rdb.Command, params []rdb.Param, preparedToken interface{}, val rdb.DriverValuer) error {
+	c.val = val
+	c.params = params
+	c.set, c.row = 0, 0
+	if cmd.SQL == "ping" || len(c.d.sets) == 0 {
+		val.RowsAffected(2)
+		return val.Done()
+	}
+	c.status = rdb.StatusQuery
+	return val.Columns(c.d.cols)
+}
+
+func (c *fakeConn) Scan(ctx context.Context) error {
+	if c.status == rdb.StatusResultDone {
+		return io.EOF
+	}
+	if c.status != rdb.StatusQuery {
+		return nil
+	}
+	rows := c.d.sets[c.set]
+	if c.row < len(rows) {
+		for i, v := range rows[c.row] {
+			err := c.val.WriteField(c.d.cols[i], &rdb.DriverValue{Value: v, Null: v == nil}, nil)
+			if err != nil {
+				return err
+			}
+		}
+		c.val.RowScanned()
+		c.row++
+	}
+	if c.row < len(rows) {
+		return nil
+	}
+	if c.set+1 < len(c.d.sets) {
+		c.status = rdb.StatusResultDone
+		return nil
+	}
+	c.status = rdb.StatusReady
+	return c.val.Done()
+}
+
+func (c *fakeConn) NextResult(ctx context.Context) (bool, error) {
+	if c.status != rdb.StatusResultDone {
+		return false, nil
+	}
+	c.set++
+	c.row = 0
+	c.status = rdb.StatusQuery
+	return true, c.val.Columns(c.d.cols)
+}
+
+func (c *fakeConn) NextQuery(ctx context.Context) error {
+	for c.status == rdb.StatusQuery || c.status == rdb.StatusResultDone {
+		if c.status == rdb.StatusResultDone {
+			c.NextResult(ctx)
+		}
+		if err := c.Scan(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openFake(t *testing.T, name string, d *fakeDriver) *DB {
+	t.Helper()
+	rdb.Register(name, d)
+	db, err := Open(&rdb.Config{DriverName: name, PoolInitCapacity: 1, PoolMaxCapacity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestQuery(t *testing.T) {
+	d := &fakeDriver{
+		cols: []*rdb.Column{
+			{Name: "ID", Index: 0, Type: rdb.TypeInt64, Generic: rdb.Integer},
+			{Name: "Name", Index: 1, Type: rdb.TypeVarChar, Generic: rdb.Text, Length: 50, Nullable: true},
+		},
+		sets: [][][]any{
+			{{int64(1), "a"}, {int64(2), nil}},
+			{{int64(3), "c"}},
+		},
+	}
+	db := openFake(t, "sql_shim_query", d)
+	ctx := context.Background()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.QueryContext(ctx, "select ID, Name from T where ID > @id", Named("id", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := types[1].Length(); !ok || n != 50 {
+		t.Errorf("length %d, %t", n, ok)
+	}
+	if _, ok := types[0].Length(); ok {
+		t.Error("int column has a length")
+	}
+	if nullable, _ := types[1].Nullable(); !nullable {
+		t.Error("expected nullable column")
+	}
+	if got := types[0].ScanType(); got != reflect.TypeFor[int64]() {
+		t.Errorf("scan type %v", got)
+	}
+	if got := types[1].DatabaseTypeName(); got != "NVARCHAR" {
+		t.Errorf("type name %q", got)
+	}
+
+	var names []Null[string]
+	for rows.Next() {
+		var id int64
+		var name Null[string]
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	want := []Null[string]{{V: "a", Valid: true}, {}}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	if !rows.NextResultSet() {
+		t.Fatalf("expected second result: %v", rows.Err())
+	}
+	if !rows.Next() {
+		t.Fatal("expected row in second result")
+	}
+	var id int64
+	var name string
+	if err := rows.Scan(&id, &name); err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 || name != "c" {
+		t.Errorf("got %d %q", id, name)
+	}
+	if rows.NextResultSet() {
+		t.Error("unexpected third result")
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	if err := db.QueryRowContext(ctx, "select ID, Name from T").Scan(&id, &name); err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || name != "a" {
+		t.Errorf("got %d %q", id, name)
+	}
+
+	c, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.QueryRowContext(ctx, "select ID, Name from T").Scan(&id, &name); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != ErrConnDone {
+		t.Errorf("got %v, want ErrConnDone", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	db := openFake(t, "sql_shim_exec", &fakeDriver{})
+	ctx := context.Background()
+
+	db.SetMaxOpenConns(2)
+	before, _ := db.Normal().PoolAvailable()
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(time.Hour)
+	if c, _ := db.Normal().PoolAvailable(); c != before {
+		t.Errorf("capacity %d after SetMaxIdleConns, want %d", c, before)
+	}
+
+	res, err := db.ExecContext(ctx, "update T set A = ?", Null[int]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("rows affected %d", n)
+	}
+	if err := db.QueryRow("select 1").Scan(new(int)); err != ErrNoRows {
+		t.Errorf("got %v, want ErrNoRows", err)
+	}
+
+	stmt, err := db.PrepareContext(ctx, "delete from T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	if _, err := stmt.Exec(); err == nil {
+		t.Error("expected error from closed statement")
+	}
+
+	if _, err := db.BeginTx(ctx, &TxOptions{ReadOnly: true}); err == nil {
+		t.Error("expected error for read-only transaction")
+	}
+	if _, err := db.BeginTx(ctx, &TxOptions{Isolation: LevelLinearizable}); err == nil {
+		t.Error("expected error for linearizable transaction")
+	}
+	tx, err := db.BeginTx(ctx, &TxOptions{Isolation: LevelSnapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.QueryRowContext(ctx, "select 1").Scan(new(int)); err != ErrNoRows {
+		t.Errorf("got %v, want ErrNoRows", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "delete from T"); err != ErrTxDone {
+		t.Errorf("got %v, want ErrTxDone", err)
+	}
+}
+
+func TestNull(t *testing.T) {
+	var n Null[int64]
+	if err := n.Scan(int32(4)); err != nil {
+		t.Fatal(err)
+	}
+	if n != (Null[int64]{V: 4, Valid: true}) {
+		t.Errorf("got %v", n)
+	}
+	if err := n.Scan(nil); err != nil || n.Valid {
+		t.Errorf("got %v, %v", n, err)
+	}
+	if err := n.Scan("x"); err == nil || n.Valid {
+		t.Error("expected error scanning a string into an int64")
+	}
+
+	// A direct assignment passes no column.
+	var n32 Null[int32]
+	handled, err := rdb.DirectAssignString(&n32, "abc", false, nil)
+	if !handled || err == nil || n32.Valid {
+		t.Errorf("direct assign mismatch: handled %t, err %v, valid %t", handled, err, n32.Valid)
+	}
+
+	var p rdb.Param
+	if err := (Null[float64]{V: math.Pi, Valid: true}).ParamValue(&p); err != nil || p.Value != math.Pi {
+		t.Errorf("got %+v, %v", p, err)
+	}
+	p = rdb.Param{}
+	if err := (Null[float64]{}).ParamValue(&p); err != nil || !p.Null {
+		t.Errorf("got %+v, %v", p, err)
+	}
+	if v, _ := (Null[string]{V: "a", Valid: true}).Value(); v != "a" {
+		t.Errorf("value %v", v)
+	}
+	if LevelSnapshot.String() != "Snapshot" {
+		t.Errorf("level %s", LevelSnapshot)
+	}
+}
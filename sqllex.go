@@ -0,0 +1,151 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import "strings"
+
+type placeholderKind byte
+
+const (
+	placeholderAt       placeholderKind = iota // @name
+	placeholderColon                           // :name
+	placeholderQuestion                        // ?
+	placeholderDollar                          // $n
+)
+
+// sqlPlaceholder is a parameter placeholder in SQL text.
+type sqlPlaceholder struct {
+	Kind       placeholderKind
+	Start, End int    // Byte offsets in the SQL text, including the prefix.
+	Name       string // Name or number without the prefix.
+}
+
+// scanPlaceholders returns the "@name", ":name", "?" and "$n" placeholders
+// in sql. String literals, quoted and bracketed identifiers, and comments
+// are skipped. System functions such as @@ROWCOUNT and "::" are not
+// placeholders.
+func scanPlaceholders(sql string) []sqlPlaceholder {
+	var list []sqlPlaceholder
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'', c == '"':
+			i = skipQuoted(sql, i+1, c)
+		case c == '[':
+			i = skipQuoted(sql, i+1, ']')
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return list
+			}
+			i += end + 1
+		case strings.HasPrefix(sql[i:], "/*"):
+			i = skipComment(sql, i+2)
+		case c == '@':
+			end := i + 1
+			if end < len(sql) && sql[end] == '@' {
+				end++
+				for end < len(sql) && isIdentByte(sql[end]) {
+					end++
+				}
+				i = end
+				continue
+			}
+			for end < len(sql) && isIdentByte(sql[end]) {
+				end++
+			}
+			if end > i+1 {
+				list = append(list, sqlPlaceholder{Kind: placeholderAt, Start: i, End: end, Name: sql[i+1 : end]})
+			}
+			i = end
+		case c == ':':
+			if strings.HasPrefix(sql[i:], "::") {
+				i += 2
+				continue
+			}
+			end := i + 1
+			for end < len(sql) && isIdentByte(sql[end]) {
+				end++
+			}
+			if end > i+1 && !isDigit(sql[i+1]) {
+				list = append(list, sqlPlaceholder{Kind: placeholderColon, Start: i, End: end, Name: sql[i+1 : end]})
+			}
+			i = end
+		case c == '?':
+			list = append(list, sqlPlaceholder{Kind: placeholderQuestion, Start: i, End: i + 1})
+			i++
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && isDigit(sql[end]) {
+				end++
+			}
+			if end == len(sql) || !isIdentByte(sql[end]) {
+				list = append(list, sqlPlaceholder{Kind: placeholderDollar, Start: i, End: end, Name: sql[i+1 : end]})
+			}
+			i = end
+		case isIdentByte(c):
+			// Skip the whole word so "a@b" is not a placeholder.
+			for i < len(sql) && (isIdentByte(sql[i]) || sql[i] == '@') {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return list
+}
+
+// skipQuoted returns the offset after the closing quote, where a doubled
+// quote is part of the text.
+func skipQuoted(sql string, i int, quote byte) int {
+	for i < len(sql) {
+		if sql[i] != quote {
+			i++
+			continue
+		}
+		if i+1 < len(sql) && sql[i+1] == quote {
+			i += 2
+			continue
+		}
+		return i + 1
+	}
+	return i
+}
+
+// skipComment returns the offset after the end of a block comment,
+// which may be nested.
+func skipComment(sql string, i int) int {
+	depth := 1
+	for i < len(sql) {
+		switch {
+		case strings.HasPrefix(sql[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(sql[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	case c == '_', c == '$', c == '#', c >= 0x80:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats is a snapshot of connection pool statistics.
+// Counters are totals since the pool was opened.
+type PoolStats struct {
+	Capacity    int // Current capacity, grows up to MaxCapacity.
+	MaxCapacity int
+	Available   int // Connection slots not checked out.
+	Active      int // Open connections, in the pool or checked out.
+	InUse       int // Connections checked out.
+
+	WaitCount   int64         // Number of checkouts that had to wait.
+	WaitTime    time.Duration // Total time waited for a connection.
+	IdleTimeout time.Duration
+	IdleClosed  int64 // Connections closed after being idle.
+	Exhausted   int64 // Number of times the last available connection was checked out.
+	Invalid     int64 // Connections closed after failing validation or a health check.
+	Drained     int64 // Connections closed by Drain.
+
+	CircuitOpen     bool  // True while new connections are refused after repeated failures.
+	CircuitRejected int64 // Connection attempts refused with ErrCircuitOpen.
+	Waiting         int   // Requests waiting for a connection, if PoolMaxWaiters is set.
+	Queued          int   // Requests blocked in the pool for a free connection, at any priority.
+	Busy            int64 // Requests refused with ErrPoolBusy.
+
+	Opened          int64 // Physical connections opened.
+	OpenFailed      int64 // Physical connection attempts that failed.
+	KilledOnRelease int64 // Connections closed when released instead of being reused.
+	ResetFailed     int64 // Connections closed because the reset failed.
+	LifetimeClosed  int64 // Connections closed after exceeding ConnectionMaxLifetime.
+	Expansions      int64 // Times the pool capacity was expanded after SoftWait.
+}
+
+// poolCounters are the statistics ConnPool tracks on top of the resource pool.
+type poolCounters struct {
+	opened          atomic.Int64
+	openFailed      atomic.Int64
+	killedOnRelease atomic.Int64
+	resetFailed     atomic.Int64
+	lifetimeClosed  atomic.Int64
+	expansions      atomic.Int64
+	healthInvalid   atomic.Int64
+	drained         atomic.Int64
+	circuitRejected atomic.Int64
+	busy            atomic.Int64
+}
+
+// Stats returns a snapshot of the pool statistics.
+func (cp *ConnPool) Stats() PoolStats {
+	p := cp.pool
+	return PoolStats{
+		Capacity:    int(p.Capacity()),
+		MaxCapacity: int(cp.maxCapacity()),
+		Available:   int(p.Available()),
+		Active:      int(p.Active()),
+		InUse:       int(p.InUse()),
+
+		WaitCount:   p.WaitCount(),
+		WaitTime:    p.WaitTime(),
+		IdleTimeout: p.IdleTimeout(),
+		IdleClosed:  p.IdleClosed(),
+		Exhausted:   p.Exhausted(),
+		Invalid:     p.Invalid() + cp.stats.healthInvalid.Load(),
+		Drained:     cp.stats.drained.Load(),
+
+		CircuitOpen:     cp.breaker.isOpen(),
+		CircuitRejected: cp.stats.circuitRejected.Load(),
+		Waiting:         int(cp.waiters.Load()),
+		Queued:          int(p.Waiting()),
+		Busy:            cp.stats.busy.Load(),
+
+		Opened:          cp.stats.opened.Load(),
+		OpenFailed:      cp.stats.openFailed.Load(),
+		KilledOnRelease: cp.stats.killedOnRelease.Load(),
+		ResetFailed:     cp.stats.resetFailed.Load(),
+		LifetimeClosed:  cp.stats.lifetimeClosed.Load(),
+		Expansions:      cp.stats.expansions.Load(),
+	}
+}
+
+type promMetric struct {
+	name, kind, help string
+	value            func(s PoolStats) float64
+}
+
+var promMetrics = []promMetric{
+	{"rdb_pool_capacity", "gauge", "Current pool capacity.", func(s PoolStats) float64 { return float64(s.Capacity) }},
+	{"rdb_pool_max_capacity", "gauge", "Maximum pool capacity.", func(s PoolStats) float64 { return float64(s.MaxCapacity) }},
+	{"rdb_pool_available", "gauge", "Connection slots not checked out.", func(s PoolStats) float64 { return float64(s.Available) }},
+	{"rdb_pool_active", "gauge", "Open connections.", func(s PoolStats) float64 { return float64(s.Active) }},
+	{"rdb_pool_in_use", "gauge", "Connections checked out.", func(s PoolStats) float64 { return float64(s.InUse) }},
+	{"rdb_pool_wait_count_total", "counter", "Checkouts that had to wait.", func(s PoolStats) float64 { return float64(s.WaitCount) }},
+	{"rdb_pool_wait_seconds_total", "counter", "Total time waited for a connection.", func(s PoolStats) float64 { return s.WaitTime.Seconds() }},
+	{"rdb_pool_idle_closed_total", "counter", "Connections closed after being idle.", func(s PoolStats) float64 { return float64(s.IdleClosed) }},
+	{"rdb_pool_exhausted_total", "counter", "Times the last available connection was checked out.", func(s PoolStats) float64 { return float64(s.Exhausted) }},
+	{"rdb_pool_invalid_total", "counter", "Connections closed after failing validation.", func(s PoolStats) float64 { return float64(s.Invalid) }},
+	{"rdb_pool_drained_total", "counter", "Connections closed by a drain.", func(s PoolStats) float64 { return float64(s.Drained) }},
+	{"rdb_pool_circuit_open", "gauge", "1 while new connections are refused after repeated failures.", func(s PoolStats) float64 {
+		if s.CircuitOpen {
+			return 1
+		}
+		return 0
+	}},
+	{"rdb_pool_circuit_rejected_total", "counter", "Connection attempts refused by the circuit breaker.", func(s PoolStats) float64 { return float64(s.CircuitRejected) }},
+	{"rdb_pool_waiting", "gauge", "Requests waiting for a connection, if PoolMaxWaiters is set.", func(s PoolStats) float64 { return float64(s.Waiting) }},
+	{"rdb_pool_queued", "gauge", "Requests blocked in the pool for a free connection.", func(s PoolStats) float64 { return float64(s.Queued) }},
+	{"rdb_pool_busy_total", "counter", "Requests refused because too many were waiting.", func(s PoolStats) float64 { return float64(s.Busy) }},
+	{"rdb_pool_opened_total", "counter", "Physical connections opened.", func(s PoolStats) float64 { return float64(s.Opened) }},
+	{"rdb_pool_open_failed_total", "counter", "Physical connection attempts that failed.", func(s PoolStats) float64 { return float64(s.OpenFailed) }},
+	{"rdb_pool_killed_on_release_total", "counter", "Connections closed when released.", func(s PoolStats) float64 { return float64(s.KilledOnRelease) }},
+	{"rdb_pool_reset_failed_total", "counter", "Connections closed because the reset failed.", func(s PoolStats) float64 { return float64(s.ResetFailed) }},
+	{"rdb_pool_lifetime_closed_total", "counter", "Connections closed after exceeding the max lifetime.", func(s PoolStats) float64 { return float64(s.LifetimeClosed) }},
+	{"rdb_pool_expansions_total", "counter", "Times the pool capacity was expanded.", func(s PoolStats) float64 { return float64(s.Expansions) }},
+}
+
+// promLabel escapes a label value for the Prometheus text format.
+var promLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the statistics of each pool in the Prometheus
+// text exposition format. The map key is used as the "pool" label.
+// See the metrics package to serve it over HTTP.
+func WritePrometheus(w io.Writer, pools map[string]*ConnPool) error {
+	names := make([]string, 0, len(pools))
+	stats := make(map[string]PoolStats, len(pools))
+	for name, cp := range pools {
+		names = append(names, name)
+		stats[name] = cp.Stats()
+	}
+	sort.Strings(names)
+
+	bb := &strings.Builder{}
+	for _, m := range promMetrics {
+		fmt.Fprintf(bb, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, name := range names {
+			fmt.Fprintf(bb, "%s{pool=\"%s\"} %v\n", m.name, promLabel.Replace(name), m.value(stats[name]))
+		}
+	}
+	_, err := io.WriteString(w, bb.String())
+	return err
+}
@@ -0,0 +1,60 @@
+package rdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoolStats(t *testing.T) {
+	driver := &signalDriver{}
+	Register("stats_test", driver)
+	pool, err := Open(&Config{
+		DriverName:            "stats_test",
+		PoolInitCapacity:      1,
+		PoolMaxCapacity:       2,
+		ConnectionMaxLifetime: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	go func() {
+		time.Sleep(time.Millisecond)
+		for _, conn := range driver.getConns() {
+			conn.signal()
+		}
+	}()
+	res, err := pool.Query(ctx, &Command{Arity: Any})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats(); s.InUse != 1 || s.Opened != 1 {
+		t.Errorf("expected 1 in use and 1 opened, got %+v", s)
+	}
+	res.Close()
+
+	s := pool.Stats()
+	if s.InUse != 0 || s.Capacity != 1 || s.MaxCapacity != 2 || s.Available != 1 {
+		t.Errorf("unexpected stats after close: %+v", s)
+	}
+
+	bb := &strings.Builder{}
+	if err := WritePrometheus(bb, map[string]*ConnPool{"main": pool, "a\\b\"c\nd é": pool}); err != nil {
+		t.Fatal(err)
+	}
+	body := bb.String()
+	for _, line := range []string{
+		"# TYPE rdb_pool_in_use gauge",
+		`rdb_pool_capacity{pool="main"} 1`,
+		`rdb_pool_opened_total{pool="main"} 1`,
+		`rdb_pool_opened_total{pool="a\\b\"c\nd é"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
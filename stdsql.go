@@ -0,0 +1,530 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// registerStd registers dr with database/sql if name is free.
+func registerStd(name string, dr Driver) {
+	if slices.Contains(sql.Drivers(), name) {
+		return
+	}
+	sql.Register(name, newStdDriver(name, dr))
+}
+
+// NewConnector returns a database/sql connector for the driver and settings
+// in config, for use with sql.OpenDB. Each driver passed to Register is also
+// registered with database/sql under the same name, unless the name is
+// already used there, so it may be opened with sql.Open:
+//
+//	db, err := sql.Open("ms", "ms://user:pass@localhost/SqlExpress?db=master")
+//
+// The data source name is a URL as read by ParseConfigURL or a connection
+// string as read by ParseConnectionString. The database/sql package manages
+// the connections, so the pool settings of the Config are not used. New
+// connections still use the credential providers and failover partners.
+//
+// Named parameters are passed with sql.Named. Unnamed parameters may use the
+// "?" and "$n" placeholders. Any value the driver accepts may be passed,
+// including slices, Opt, and types with a ParamValuer. Pass a Param to set
+// the type of a parameter.
+func NewConnector(config *Config) (driver.Connector, error) {
+	dr, err := getDriver(config.DriverName)
+	if err != nil {
+		return nil, err
+	}
+	return newStdDriver(config.DriverName, dr).connector(config)
+}
+
+type stdDriver struct {
+	name string
+	dr   Driver
+	info *DriverInfo
+}
+
+func newStdDriver(name string, dr Driver) *stdDriver {
+	return &stdDriver{name: name, dr: dr, info: dr.DriverInfo()}
+}
+
+// Open implements driver.Driver.
+func (d *stdDriver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+// OpenConnector implements driver.DriverContext.
+func (d *stdDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	var config *Config
+	var err error
+	if strings.Contains(dsn, "://") {
+		config, err = ParseConfigURL(dsn)
+	} else {
+		config, err = ParseConnectionString(d.name, dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.DriverName != d.name {
+		return nil, fmt.Errorf("DSN is for driver %q, not %q", config.DriverName, d.name)
+	}
+	return d.connector(config)
+}
+
+func (d *stdDriver) connector(config *Config) (driver.Connector, error) {
+	if config.Secure && !d.info.SecureConnection {
+		return nil, fmt.Errorf("driver %s does not support secure connections", d.name)
+	}
+	// The pool is not opened, it only opens connections the same way an
+	// opened pool does, with the credential providers and failover partners.
+	cp := &ConnPool{dr: d.dr, info: d.info, conf: config}
+	var err error
+	cp.failover, err = newFailover(config)
+	if err != nil {
+		return nil, err
+	}
+	return &stdConnector{d: d, cp: cp, conf: config}, nil
+}
+
+type stdConnector struct {
+	d    *stdDriver
+	cp   *ConnPool
+	conf *Config
+}
+
+// Connect implements driver.Connector.
+func (c *stdConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.cp.open(ctx)
+	if conn == nil && err == nil {
+		err = fmt.Errorf("new connection is nil")
+	}
+	if err == nil {
+		err = conn.Reset(c.conf)
+	}
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, err
+	}
+	return &stdConn{d: c.d, conf: c.conf, conn: conn}, nil
+}
+
+// Driver implements driver.Connector.
+func (c *stdConnector) Driver() driver.Driver {
+	return c.d
+}
+
+type stdConn struct {
+	d    *stdDriver
+	conf *Config
+	conn DriverConn
+
+	// Set when the connection is not in a known state after an error.
+	bad bool
+}
+
+var (
+	_ driver.Conn              = &stdConn{}
+	_ driver.ConnBeginTx       = &stdConn{}
+	_ driver.QueryerContext    = &stdConn{}
+	_ driver.ExecerContext     = &stdConn{}
+	_ driver.NamedValueChecker = &stdConn{}
+	_ driver.Pinger            = &stdConn{}
+	_ driver.SessionResetter   = &stdConn{}
+	_ driver.Validator         = &stdConn{}
+	_ driver.RowsNextResultSet = &stdRows{}
+	_ driver.StmtQueryContext  = &stdStmt{}
+	_ driver.StmtExecContext   = &stdStmt{}
+	_ driver.DriverContext     = &stdDriver{}
+	_ driver.Connector         = &stdConnector{}
+	_ driver.Tx                = stdTx{}
+	_ driver.Result            = driver.RowsAffected(0)
+)
+
+// Close implements driver.Conn.
+func (c *stdConn) Close() error {
+	c.conn.Close()
+	return nil
+}
+
+// Prepare implements driver.Conn. The statement is not prepared on the
+// server; it sends the query each time it is used.
+func (c *stdConn) Prepare(query string) (driver.Stmt, error) {
+	return &stdStmt{c: c, query: query}, nil
+}
+
+// Begin implements driver.Conn.
+func (c *stdConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// stdIsolation maps database/sql isolation levels to an IsolationLevel.
+var stdIsolation = map[sql.IsolationLevel]IsolationLevel{
+	sql.LevelDefault:         LevelDefault,
+	sql.LevelReadUncommitted: LevelReadUncommitted,
+	sql.LevelReadCommitted:   LevelReadCommitted,
+	sql.LevelWriteCommitted:  LevelWriteCommitted,
+	sql.LevelRepeatableRead:  LevelRepeatableRead,
+	sql.LevelSnapshot:        LevelSnapshot,
+	sql.LevelSerializable:    LevelSerializable,
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *stdConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		return nil, errors.New("read-only transactions are not supported")
+	}
+	level, ok := stdIsolation[sql.IsolationLevel(opts.Isolation)]
+	if !ok {
+		return nil, fmt.Errorf("isolation level %v is not supported", sql.IsolationLevel(opts.Isolation))
+	}
+	err := c.conn.Begin(ctx, level)
+	if err != nil {
+		c.check()
+		return nil, err
+	}
+	return stdTx{c: c}, nil
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *stdConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := &stdRows{ctx: ctx, c: c}
+	err := c.query(ctx, query, args, &rows.val)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *stdConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	val := &valuer{}
+	err := c.query(ctx, query, args, val)
+	if err != nil {
+		return nil, err
+	}
+	err = c.conn.NextQuery(ctx)
+	if err == nil && len(val.errorList) != 0 {
+		err = val.errorList
+	}
+	if err != nil {
+		c.check()
+		return nil, err
+	}
+	return driver.RowsAffected(val.rowsAffected), nil
+}
+
+// query sends query to the driver, which reports the result to val.
+func (c *stdConn) query(ctx context.Context, query string, args []driver.NamedValue, val *valuer) error {
+	params := make([]Param, len(args))
+	for i, arg := range args {
+		p, ok := arg.Value.(Param)
+		if !ok {
+			p = Param{Value: arg.Value}
+		}
+		if len(arg.Name) > 0 {
+			p.Name = arg.Name
+		}
+		params[i] = p
+	}
+	cmd, params, err := prepareQuery(c.d.info, c.conf, &Command{SQL: query}, params)
+	if err != nil {
+		return err
+	}
+	val.cmd = cmd
+	err = c.conn.Query(ctx, cmd, params, nil, val)
+	if err == nil && len(val.errorList) != 0 {
+		err = val.errorList
+	}
+	if err != nil {
+		if c.conn.Status() != StatusReady {
+			c.conn.NextQuery(ctx)
+		}
+		c.check()
+		return err
+	}
+	return nil
+}
+
+// check marks the connection bad if it is not ready for the next query.
+func (c *stdConn) check() {
+	if c.conn.Status() != StatusReady {
+		c.bad = true
+	}
+}
+
+// CheckNamedValue implements driver.NamedValueChecker. Values are passed to
+// the driver as they are, except for driver.Valuer types that are not a
+// Param, ParamValuer, or type registered with RegisterType.
+func (c *stdConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case nil, Param, ParamValuer:
+		return nil
+	case sql.Out:
+		return errors.New("output parameters are not supported")
+	case driver.Valuer:
+		if _, ok := lookupType(reflect.TypeOf(v)); ok {
+			return nil
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			nv.Value = nil
+			return nil
+		}
+		value, err := v.Value()
+		if err != nil {
+			return err
+		}
+		nv.Value = value
+	}
+	return nil
+}
+
+// Ping implements driver.Pinger.
+func (c *stdConn) Ping(ctx context.Context) error {
+	cmd := c.d.dr.PingCommand()
+	if cmd == nil {
+		return nil
+	}
+	_, err := c.ExecContext(ctx, cmd.SQL, nil)
+	return err
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *stdConn) ResetSession(ctx context.Context) error {
+	if !c.IsValid() {
+		return driver.ErrBadConn
+	}
+	err := c.conn.Reset(c.conf)
+	if err != nil {
+		c.bad = true
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *stdConn) IsValid() bool {
+	return !c.bad && c.conn.Status() == StatusReady
+}
+
+type stdTx struct {
+	c *stdConn
+}
+
+func (tx stdTx) Commit() error {
+	err := tx.c.conn.Commit(context.Background())
+	if err != nil {
+		tx.c.check()
+	}
+	return err
+}
+
+func (tx stdTx) Rollback() error {
+	err := tx.c.conn.Rollback("")
+	if err != nil {
+		tx.c.check()
+	}
+	return err
+}
+
+type stdStmt struct {
+	c     *stdConn
+	query string
+}
+
+func (s *stdStmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1 as placeholders are not counted.
+func (s *stdStmt) NumInput() int {
+	return -1
+}
+
+func (s *stdStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stdStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stdStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *stdStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+func (s *stdStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	return s.c.CheckNamedValue(nv)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	list := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		list[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return list
+}
+
+type stdRows struct {
+	ctx    context.Context
+	c      *stdConn
+	val    valuer
+	closed bool
+}
+
+func (r *stdRows) Columns() []string {
+	names := make([]string, len(r.val.columns))
+	for i, col := range r.val.columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+func (r *stdRows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.c.conn.NextQuery(r.ctx)
+	if err != nil {
+		r.c.check()
+	}
+	return err
+}
+
+func (r *stdRows) Next(dest []driver.Value) error {
+	err := r.scan()
+	if err != nil {
+		return err
+	}
+	for i := range dest {
+		if i >= len(r.val.buffer) {
+			dest[i] = nil
+			continue
+		}
+		dest[i] = stdValue(r.val.columns[i], r.val.buffer[i])
+	}
+	return nil
+}
+
+// scan reads the next row into the buffer. It returns io.EOF if there are
+// no more rows in the result.
+func (r *stdRows) scan() error {
+	if r.val.eof || r.c.conn.Status() == StatusResultDone {
+		return io.EOF
+	}
+	n := r.val.rowCount
+	r.val.clearBuffer()
+	err := r.c.conn.Scan(r.ctx)
+	if err == nil && len(r.val.errorList) != 0 {
+		err = r.val.errorList
+	}
+	if err != nil {
+		if err != io.EOF {
+			r.c.check()
+		}
+		return err
+	}
+	if r.val.rowCount == n {
+		return io.EOF
+	}
+	return nil
+}
+
+// HasNextResultSet implements driver.RowsNextResultSet. It reports true
+// until the query is done.
+func (r *stdRows) HasNextResultSet() bool {
+	return !r.val.eof
+}
+
+// NextResultSet implements driver.RowsNextResultSet. Any rows left in the
+// current result are skipped.
+func (r *stdRows) NextResultSet() error {
+	for {
+		err := r.scan()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if r.val.eof {
+		return io.EOF
+	}
+	more, err := r.c.conn.NextResult(r.ctx)
+	if err != nil {
+		r.c.check()
+		return err
+	}
+	if !more || r.val.eof {
+		return io.EOF
+	}
+	return nil
+}
+
+// stdValue converts a column value to a driver.Value type where one fits.
+// Other values are returned as they are.
+func stdValue(col *Column, n Nullable) driver.Value {
+	if n.Null {
+		return nil
+	}
+	switch v := n.Value.(type) {
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return strconv.FormatUint(uint64(v), 10)
+		}
+		return int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10)
+		}
+		return int64(v)
+	case float32:
+		return float64(v)
+	case *big.Rat:
+		if v == nil {
+			return nil
+		}
+		return v.FloatString(col.Scale)
+	case Decimal:
+		return v.String()
+	}
+	return n.Value
+}
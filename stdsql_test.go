@@ -0,0 +1,287 @@
+package rdb
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stdSet struct {
+	cols []*Column
+	rows [][]any
+}
+
+type fakeStdDriver struct {
+	dummyDriver
+	sets    []stdSet
+	queries chan listQuery
+}
+
+func (d *fakeStdDriver) DriverInfo() *DriverInfo {
+	return &DriverInfo{DriverSupport: DriverSupport{NamedParameter: true}}
+}
+
+func (d *fakeStdDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	return &fakeStdConn{dummyConn: dummyConn{opened: time.Now()}, d: d}, nil
+}
+
+// fakeStdConn returns the driver sets for "select" queries and reports three
+// rows affected for other queries.
+type fakeStdConn struct {
+	dummyConn
+	d        *fakeStdDriver
+	val      DriverValuer
+	sets     []stdSet
+	set, row int
+}
+
+func (c *fakeStdConn) Reset(conf *Config) error {
+	c.status = StatusReady
+	return nil
+}
+
+func (c *fakeStdConn) Query(ctx context.Context, cmd *Command, params []Param, preparedToken interface{}, val DriverValuer) error {
+	c.d.queries <- listQuery{sql: cmd.SQL, params: params}
+	c.val = val
+	c.set, c.row = 0, 0
+	c.sets = nil
+	if strings.HasPrefix(cmd.SQL, "select") {
+		c.sets = c.d.sets
+	}
+	if len(c.sets) == 0 {
+		val.RowsAffected(3)
+		c.status = StatusReady
+		return val.Done()
+	}
+	c.status = StatusQuery
+	return val.Columns(c.sets[0].cols)
+}
+
+func (c *fakeStdConn) Scan(ctx context.Context) error {
+	if c.status == StatusResultDone {
+		return io.EOF
+	}
+	if c.status != StatusQuery {
+		return nil
+	}
+	set := c.sets[c.set]
+	if c.row < len(set.rows) {
+		for i, v := range set.rows[c.row] {
+			err := c.val.WriteField(set.cols[i], &DriverValue{Value: v, Null: v == nil}, nil)
+			if err != nil {
+				return err
+			}
+		}
+		c.val.RowScanned()
+		c.row++
+	}
+	if c.row < len(set.rows) {
+		return nil
+	}
+	if c.set+1 < len(c.sets) {
+		c.status = StatusResultDone
+		return nil
+	}
+	c.status = StatusReady
+	return c.val.Done()
+}
+
+func (c *fakeStdConn) NextResult(ctx context.Context) (bool, error) {
+	if c.status != StatusResultDone {
+		return false, nil
+	}
+	c.set++
+	c.row = 0
+	c.status = StatusQuery
+	return true, c.val.Columns(c.sets[c.set].cols)
+}
+
+func (c *fakeStdConn) NextQuery(ctx context.Context) error {
+	for c.status == StatusQuery || c.status == StatusResultDone {
+		if c.status == StatusResultDone {
+			c.NextResult(ctx)
+		}
+		if err := c.Scan(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStdSQL(t *testing.T) {
+	d := &fakeStdDriver{
+		sets: []stdSet{
+			{
+				cols: []*Column{{Name: "ID", Index: 0}, {Name: "Name", Index: 1}},
+				rows: [][]any{{int32(1), "a"}, {int32(2), nil}},
+			},
+			{
+				cols: []*Column{{Name: "Total", Index: 0, Scale: 2}},
+				rows: [][]any{{big.NewRat(5, 2)}},
+			},
+		},
+		queries: make(chan listQuery, 1),
+	}
+	Register("stdsql", d)
+	db, err := sql.Open("stdsql", "stdsql://localhost?db=app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-d.queries
+
+	rows, err := db.QueryContext(ctx, `select ID, Name from T where ID > @id and Name = $2; select Total`,
+		sql.Named("id", 0), sql.NullString{String: "a", Valid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := <-d.queries
+	if want := `select ID, Name from T where ID > @id and Name = @p2; select Total`; got.sql != want {
+		t.Errorf("got  %s\nwant %s", got.sql, want)
+	}
+	if len(got.params) != 2 || got.params[0].Name != "id" || got.params[1].Value != "a" {
+		t.Errorf("params: %+v", got.params)
+	}
+
+	var ids []any
+	var names []sql.NullString
+	for rows.Next() {
+		var id any
+		var name sql.NullString
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+	if len(ids) != 2 || ids[0] != int64(1) || ids[1] != int64(2) {
+		t.Errorf("ids: %v", ids)
+	}
+	if len(names) != 2 || names[0].String != "a" || names[1].Valid {
+		t.Errorf("names: %v", names)
+	}
+	if !rows.NextResultSet() {
+		t.Fatalf("expected second result: %v", rows.Err())
+	}
+	if cols, _ := rows.Columns(); len(cols) != 1 || cols[0] != "Total" {
+		t.Errorf("columns: %v", cols)
+	}
+	var total string
+	if !rows.Next() {
+		t.Fatal("expected row")
+	}
+	if err := rows.Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != "2.50" {
+		t.Errorf("total: %s", total)
+	}
+	if rows.NextResultSet() {
+		t.Error("unexpected third result")
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := db.ExecContext(ctx, `update T set A = 1 where ID in (@ids)`, sql.Named("ids", []int{4, 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Errorf("rows affected: %d", n)
+	}
+	if got := <-d.queries; got.sql != `update T set A = 1 where ID in (@ids_0, @ids_1)` {
+		t.Errorf("exec sql: %s", got.sql)
+	}
+
+	if _, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}); err == nil {
+		t.Error("expected error for read-only transaction")
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSnapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.ExecContext(ctx, `exec P @x`, sql.Named("x", sql.Out{})); err == nil {
+		t.Error("expected error for output parameter")
+	}
+
+	bad, err := sql.Open("stdsql", "ms://localhost")
+	if err == nil {
+		err = bad.PingContext(ctx)
+		bad.Close()
+	}
+	if err == nil {
+		t.Error("expected error for DSN of another driver")
+	}
+}
+
+func TestStdSQLConnector(t *testing.T) {
+	d := &fakeStdDriver{queries: make(chan listQuery, 1)}
+	Register("std_sql_connector", d)
+	c, err := NewConnector(&Config{DriverName: "std_sql_connector"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	res, err := db.Exec(`delete from T where ID = ?`, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Errorf("rows affected: %d", n)
+	}
+	if got := <-d.queries; got.sql != `delete from T where ID = @p1` {
+		t.Errorf("sql: %s", got.sql)
+	}
+
+	if _, err := NewConnector(&Config{DriverName: "std_sql_missing"}); err == nil {
+		t.Error("expected error for unknown driver")
+	}
+}
+
+func TestStdSQLCredentials(t *testing.T) {
+	d := &credDriver{password: "two"}
+	Register("stdsqlcred", d)
+
+	var refreshed []bool
+	c, err := NewConnector(&Config{
+		DriverName: "stdsqlcred",
+		Credentials: func(ctx context.Context) (string, string, error) {
+			refresh := CredentialsRefresh(ctx)
+			refreshed = append(refreshed, refresh)
+			if refresh {
+				return "app", "two", nil
+			}
+			return "app", "one", nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"app:one", "app:two"}
+	if !equalCalls(d.logins, want) {
+		t.Fatalf("logins %q, want %q", d.logins, want)
+	}
+	if len(refreshed) != 2 || refreshed[0] || !refreshed[1] {
+		t.Fatalf("refresh flags %v", refreshed)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DriverRowFinisher may be implemented by a DriverConn to stream large
+// column values, such as varbinary(max), into a *io.Reader Prep destination.
+// The driver reports the column with DriverValue.Stream set and returns from
+// Scan before reading the rest of the row. The reader pulls the value from the
+// connection as it is read.
+type DriverRowFinisher interface {
+	// FinishRow discards any unread part of the open stream and reports the
+	// remaining columns of the row. It may stop again at a later streamed
+	// column. Reading a stream after FinishRow must return ErrStreamClosed.
+	FinishRow(ctx context.Context) error
+}
+
+// Reader returns the value of column name as an io.Reader. Use after Scan.
+//
+// If the column was prepared with a *io.Reader and the driver supports it,
+// the value is streamed from the connection without buffering it. Columns must
+// then be read in order: reading a later column, calling Next, Scan, or Close
+// discards the rest of the stream, and reading it afterwards returns
+// ErrStreamClosed. A column prepared with a *io.Reader that is not streamed
+// returns the reader assigned to it. Other text and binary columns are read
+// from the row buffer. A NULL value returns a nil reader.
+func (r *Result) Reader(name string) (io.Reader, error) {
+	col, found := r.val.columnLookup[name]
+	if !found {
+		return nil, ErrorColumnNotFound{At: "Reader", Name: name}
+	}
+	index := col.Index
+	if err := r.readTo(index); err != nil {
+		return nil, err
+	}
+	if r.val.stream != nil && r.val.streamAt == index {
+		return r.val.stream, nil
+	}
+	if r.val.streamed[index] {
+		return nil, fmt.Errorf("column %q: %w", name, ErrStreamClosed)
+	}
+	if out := r.val.readers[index]; out != nil {
+		return *out, nil
+	}
+	bv := r.val.buffer[index]
+	if bv.Null {
+		return nil, nil
+	}
+	switch v := bv.Value.(type) {
+	case []byte:
+		return bytes.NewReader(v), nil
+	case string:
+		return strings.NewReader(v), nil
+	}
+	return nil, fmt.Errorf("column %q of type %T cannot be read as a stream", name, bv.Value)
+}
+
+// readTo finishes a row paused at a column stream before a column after
+// the stream at index is read. The error is kept for the next Scan or Close.
+func (r *Result) readTo(index int) error {
+	if r.val.stream == nil || index <= r.val.streamAt {
+		return nil
+	}
+	r.leak.lock()
+	defer r.leak.unlock()
+	if r.isClosed() || r.leak.forced() {
+		r.val.stream = nil
+		return ErrClosed
+	}
+	for r.val.stream != nil && index > r.val.streamAt {
+		if err := r.finishRow(); err != nil {
+			if r.streamErr == nil {
+				r.streamErr = err
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// finishRow closes the open stream and reads the rest of the row.
+func (r *Result) finishRow() error {
+	r.val.stream = nil
+	var err error
+	if f, ok := r.conn.(DriverRowFinisher); ok {
+		err = f.FinishRow(r.ctx)
+	}
+	if r.val.stream != nil {
+		// Paused again at a later column stream.
+		r.wrapStream()
+		return err
+	}
+	r.val.clearPrep()
+	return r.endRow(err)
+}
+
+// resultStream is the open column stream handed to the caller. Reading it
+// uses the result connection, the same as Scan.
+type resultStream struct {
+	r  *Result
+	rd io.Reader
+}
+
+func (s *resultStream) Read(p []byte) (int, error) {
+	s.r.leak.lock()
+	defer s.r.leak.unlock()
+	if s.r.leak.forced() {
+		return 0, ErrStreamClosed
+	}
+	s.r.updateHit()
+	return s.rd.Read(p)
+}
+
+// wrapStream wraps the open column stream, and the *io.Reader Prep
+// destination it was assigned to, in a resultStream.
+func (r *Result) wrapStream() {
+	s := &resultStream{r: r, rd: r.val.stream}
+	r.val.stream = s
+	if out, ok := r.val.prep[r.val.streamAt].(*io.Reader); ok {
+		*out = s
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+type fakeStreamDriver struct {
+	dummyDriver
+	cols []*Column
+	rows [][]any
+}
+
+func (d *fakeStreamDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	return &fakeStreamConn{dummyConn: dummyConn{opened: time.Now()}, d: d}, nil
+}
+
+// fakeStreamConn streams []byte values prepared with *io.Reader and pauses
+// the row until FinishRow or the next Scan.
+type fakeStreamConn struct {
+	dummyConn
+	d      *fakeStreamDriver
+	val    DriverValuer
+	row    int
+	col    int
+	stream *fakeStream
+}
+
+type fakeStream struct {
+	r      *bytes.Reader
+	closed bool
+}
+
+func (s *fakeStream) Read(p []byte) (int, error) {
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+	return s.r.Read(p)
+}
+
+func (c *fakeStreamConn) Reset(conf *Config) error {
+	c.status = StatusReady
+	return nil
+}
+
+func (c *fakeStreamConn) Query(ctx context.Context, cmd *Command, params []Param, preparedToken interface{}, val DriverValuer) error {
+	c.val = val
+	c.row = 0
+	if len(c.d.rows) == 0 {
+		c.status = StatusReady
+		return val.Done()
+	}
+	c.status = StatusQuery
+	return val.Columns(c.d.cols)
+}
+
+func (c *fakeStreamConn) Scan(ctx context.Context) error {
+	if c.status != StatusQuery {
+		return nil
+	}
+	if c.stream != nil {
+		c.stream.closed = true
+		c.stream = nil
+		c.row++
+	}
+	c.col = 0
+	c.val.RowScanned()
+	return c.writeRow()
+}
+
+func (c *fakeStreamConn) FinishRow(ctx context.Context) error {
+	if c.stream == nil {
+		return nil
+	}
+	c.stream.closed = true
+	c.stream = nil
+	return c.writeRow()
+}
+
+func (c *fakeStreamConn) writeRow() error {
+	row := c.d.rows[c.row]
+	for c.col < len(row) {
+		i := c.col
+		c.col++
+		v := row[i]
+		if bb, ok := v.([]byte); ok {
+			if _, ok := c.val.(DriverValuerPrep).PrepAt(i).(*io.Reader); ok {
+				c.stream = &fakeStream{r: bytes.NewReader(bb)}
+				return c.val.WriteField(c.d.cols[i], &DriverValue{Value: c.stream, Stream: true}, nil)
+			}
+		}
+		err := c.val.WriteField(c.d.cols[i], &DriverValue{Value: v, Null: v == nil}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	c.row++
+	if c.row < len(c.d.rows) {
+		return nil
+	}
+	c.status = StatusReady
+	return c.val.Done()
+}
+
+func (c *fakeStreamConn) NextQuery(ctx context.Context) error {
+	if c.stream != nil {
+		c.stream.closed = true
+		c.stream = nil
+	}
+	c.status = StatusReady
+	return nil
+}
+
+func openStreamPool(t *testing.T, name string) *ConnPool {
+	t.Helper()
+	Register(name, &fakeStreamDriver{
+		cols: []*Column{{Name: "ID", Index: 0}, {Name: "Data", Index: 1}, {Name: "Name", Index: 2}},
+		rows: [][]any{
+			{int64(1), []byte("first blob"), "a"},
+			{int64(2), []byte("second blob"), nil},
+			{int64(3), nil, "c"},
+		},
+	})
+	pool, err := Open(&Config{DriverName: name, PoolInitCapacity: 1, PoolMaxCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestResultStream(t *testing.T) {
+	pool := openStreamPool(t, "stream")
+	ctx := context.Background()
+
+	res, err := pool.Query(ctx, &Command{SQL: "select"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	var got []string
+	var names []any
+	for res.Next() {
+		var data io.Reader
+		res.Prep("Data", &data)
+		if err := res.Scan(); err != nil {
+			t.Fatal(err)
+		}
+		if id := res.Get("ID"); id == nil {
+			t.Fatal("missing ID before the stream")
+		}
+		if rd, err := res.Reader("Data"); err != nil || (rd != data) {
+			t.Fatalf("reader %v, %v", rd, err)
+		}
+		if data == nil {
+			got = append(got, "<nil>")
+		} else {
+			bb, err := io.ReadAll(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, string(bb))
+		}
+		names = append(names, res.Get("Name"))
+	}
+	if want := []string{"first blob", "second blob", "<nil>"}; len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(names) != 3 || names[0] != "a" || names[1] != nil || names[2] != "c" {
+		t.Errorf("names: %v", names)
+	}
+}
+
+func TestResultStreamOrder(t *testing.T) {
+	pool := openStreamPool(t, "streamorder")
+	ctx := context.Background()
+
+	res, err := pool.Query(ctx, &Command{SQL: "select"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	var data io.Reader
+	if err := res.Prep("Data", &data).Scan(); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if n, err := data.Read(buf); err != nil || string(buf[:n]) != "first" {
+		t.Fatalf("read %q, %v", buf[:n], err)
+	}
+	// Reading a later column discards the rest of the stream.
+	if name := res.Get("Name"); name != "a" {
+		t.Errorf("name %v", name)
+	}
+	if _, err := data.Read(buf); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("got %v, want ErrStreamClosed", err)
+	}
+	if _, err := res.Reader("Data"); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("got %v, want ErrStreamClosed", err)
+	}
+
+	// A value that was not prepared is read from the row buffer.
+	if err := res.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	rd, err := res.Reader("Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bb, _ := io.ReadAll(rd); string(bb) != "second blob" {
+		t.Errorf("buffered %q", bb)
+	}
+	if rd, err := res.Reader("Name"); rd != nil || err != nil {
+		t.Errorf("null: %v, %v", rd, err)
+	}
+	if _, err := res.Reader("ID"); err == nil {
+		t.Error("expected error for an int column")
+	}
+	if _, err := res.Reader("Missing"); err == nil {
+		t.Error("expected error for unknown column")
+	}
+
+	// Moving to the next row closes an unread stream.
+	res.Close()
+	res, err = pool.Query(ctx, &Command{SQL: "select"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if err := res.Prep("Data", &data).Scan(); err != nil {
+		t.Fatal(err)
+	}
+	first := data
+	if err := res.Prep("Data", &data).Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Read(buf); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("got %v, want ErrStreamClosed", err)
+	}
+	if bb, _ := io.ReadAll(data); string(bb) != "second blob" {
+		t.Errorf("second %q", bb)
+	}
+}
+
+func TestResultStreamArityOne(t *testing.T) {
+	pool := openStreamPool(t, "streamone")
+	ctx := context.Background()
+
+	_, idle := pool.PoolAvailable()
+	res, err := pool.Query(ctx, &Command{SQL: "select", Arity: One})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data io.Reader
+	if err := res.Prep("Data", &data).Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if bb, err := io.ReadAll(data); err != nil || string(bb) != "first blob" {
+		t.Errorf("got %q, %v", bb, err)
+	}
+	if res.Next() {
+		t.Error("expected a single row")
+	}
+	if err := res.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, avail := pool.PoolAvailable(); avail != idle {
+		t.Errorf("available %d, want %d", avail, idle)
+	}
+}
+
+func TestResultReaderPrepNotStreamed(t *testing.T) {
+	pool := openStreamPool(t, "streamprepreader")
+	res, err := pool.Query(context.Background(), &Command{Arity: Any})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+
+	var name io.Reader
+	if err := res.Prep("Name", &name).Scan(); err != nil {
+		t.Fatal(err)
+	}
+	rd, err := res.Reader("Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rd == nil {
+		t.Fatal("got nil reader for a column prepared with *io.Reader")
+	}
+	got, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a" {
+		t.Fatalf("got %q, want a", got)
+	}
+}
+
+func TestResultStreamLeakTouch(t *testing.T) {
+	Register("streamleaktouch", &fakeStreamDriver{
+		cols: []*Column{{Name: "Data", Index: 0}},
+		rows: [][]any{{[]byte("0123456789")}},
+	})
+	leaks := make(chan *Leak, 1)
+	pool, err := Open(&Config{
+		DriverName:       "streamleaktouch",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		LeakTimeout:      20 * time.Millisecond,
+		LeakClose:        true,
+		LeakReport: func(leak *Leak) {
+			leaks <- leak
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	res, err := pool.Query(context.Background(), &Command{Arity: Any})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	var data io.Reader
+	if err := res.Prep("Data", &data).Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the stream slower than LeakTimeout overall is not a leak.
+	var got []byte
+	b := make([]byte, 1)
+	for {
+		time.Sleep(5 * time.Millisecond)
+		n, err := data.Read(b)
+		got = append(got, b[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(got) != "0123456789" {
+		t.Fatalf("got %q", got)
+	}
+	select {
+	case leak := <-leaks:
+		t.Fatalf("stream in use reported as a leak: %+v", leak)
+	default:
+	}
+}
@@ -35,6 +35,8 @@ import (
 //   - plain field only on a nullable column: zero value for NULL (Nullable scratch)
 //
 // io.Writer fields receive bytes via Write without the API retaining a []byte.
+// Fields whose pointer implements rdb.ColumnScanner, or whose type is
+// registered with rdb.RegisterType, are passed the column value, including NULL.
 //
 // Field offsets and typed prep/apply funcs are built once per result set; the
 // row loop does not walk the struct with reflect.Value.
@@ -323,6 +325,14 @@ func newStructPlan[T any](schema []*rdb.Column, tagName string) (*structPlan, er
 				return nil, err
 			}
 			b.applyJSON = fn
+		case rdb.IsScanType(f.Type):
+			// ColumnScanner or registered type: Prep address of field, handles NULL.
+			b.mode = modeDirect
+			fn, err := makeDirectPrep(f.Type, f.Offset)
+			if err != nil {
+				return nil, fmt.Errorf("table: field %s: %w", f.Name, err)
+			}
+			b.prep = fn
 		case isOptType(f.Type):
 			// Opt[T] by value only: Prep address of field (*Opt[T]) for DirectAssign.
 			b.mode = modeDirect
@@ -429,6 +439,12 @@ func (p *structPlan) scan(res *rdb.Result, dest unsafe.Pointer) error {
 var ioWriterType = reflect.TypeOf((*io.Writer)(nil)).Elem()
 
 func makeDirectPrep(ft reflect.Type, off uintptr) (func(unsafe.Pointer) any, error) {
+	// ColumnScanner or registered type: keep the named type.
+	if rdb.IsScanType(ft) {
+		return func(base unsafe.Pointer) any {
+			return reflect.NewAt(ft, unsafe.Add(base, off)).Interface()
+		}, nil
+	}
 	// Opt[T] value field: return *Opt[T] pointing at the inlined struct.
 	if isOptType(ft) {
 		return func(base unsafe.Pointer) any {
@@ -487,6 +503,8 @@ func makeDirectPrep(ft reflect.Type, off uintptr) (func(unsafe.Pointer) any, err
 			return func(base unsafe.Pointer) any { return (*time.Time)(unsafe.Add(base, off)) }, nil
 		case reflect.TypeOf(big.Rat{}):
 			return func(base unsafe.Pointer) any { return (*big.Rat)(unsafe.Add(base, off)) }, nil
+		case reflect.TypeOf(rdb.Decimal{}):
+			return func(base unsafe.Pointer) any { return (*rdb.Decimal)(unsafe.Add(base, off)) }, nil
 		}
 	}
 	// Fallback: reflect.NewAt still avoids Field-by-name walks.
@@ -519,6 +537,12 @@ func makeNullApply(ft reflect.Type, off uintptr) (func(unsafe.Pointer, rdb.Nulla
 		}, nil
 	}
 
+	if rdb.IsScanType(ft) {
+		return func(base unsafe.Pointer, n rdb.Nullable) error {
+			return rdb.AssignValue(nil, n, reflect.NewAt(ft, unsafe.Add(base, off)).Interface(), nil)
+		}, nil
+	}
+
 	switch ft.Kind() {
 	case reflect.Bool:
 		return func(base unsafe.Pointer, n rdb.Nullable) error {
@@ -612,6 +636,21 @@ func makeNullApply(ft reflect.Type, off uintptr) (func(unsafe.Pointer, rdb.Nulla
 				return nil
 			}, nil
 		}
+		if ft == reflect.TypeOf(rdb.Decimal{}) {
+			return func(base unsafe.Pointer, n rdb.Nullable) error {
+				p := (*rdb.Decimal)(unsafe.Add(base, off))
+				if n.Null || n.Value == nil {
+					*p = rdb.Decimal{}
+					return nil
+				}
+				d, err := asDecimal(n.Value)
+				if err != nil {
+					return err
+				}
+				*p = d
+				return nil
+			}, nil
+		}
 	}
 
 	// Reflect fallback for uncommon types.
@@ -797,6 +836,8 @@ func asFloat64(v any) (float64, error) {
 		return float64(x), nil
 	case int64:
 		return float64(x), nil
+	case rdb.Decimal:
+		return x.Float64(), nil
 	default:
 		return 0, fmt.Errorf("cannot convert %T to float", v)
 	}
@@ -833,6 +874,18 @@ func asBytes(v any) ([]byte, error) {
 	}
 }
 
+func asDecimal(v any) (rdb.Decimal, error) {
+	switch x := v.(type) {
+	case rdb.Decimal:
+		return x, nil
+	case *big.Rat:
+		digits, _ := x.FloatPrec()
+		return rdb.DecimalFromRat(x, min(digits, rdb.MaxDecimalScale), rdb.RoundHalfEven)
+	default:
+		return rdb.Decimal{}, fmt.Errorf("cannot convert %T to rdb.Decimal", v)
+	}
+}
+
 func asTime(v any) (time.Time, error) {
 	switch x := v.(type) {
 	case time.Time:
@@ -9,6 +9,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"math/big"
 	"strings"
 	"testing"
 	"unsafe"
@@ -289,6 +290,43 @@ func TestDirectPrepWritesField(t *testing.T) {
 	}
 }
 
+func TestDecimalField(t *testing.T) {
+	type Row struct {
+		Total rdb.Decimal  `db:"total"`
+		Fee   rdb.Decimal  `db:"fee"`
+		Tax   *rdb.Decimal `db:"tax"`
+	}
+	schema := []*rdb.Column{
+		{Name: "total", Index: 0, Nullable: false},
+		{Name: "fee", Index: 1, Nullable: true},
+		{Name: "tax", Index: 2, Nullable: true},
+	}
+	plan, err := newStructPlan[Row](schema, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row Row
+	base := unsafe.Pointer(&row)
+	want := rdb.NewDecimal(1250, 2)
+
+	total := plan.fields[0]
+	if total.mode != modeDirect {
+		t.Fatalf("total mode=%v", total.mode)
+	}
+	if handled, err := rdb.DirectAssignDecimal(total.prep(base), want, false, nil); !handled || err != nil {
+		t.Fatalf("handled=%v err=%v", handled, err)
+	}
+	if err := plan.fields[1].applyNull(base, rdb.Nullable{Value: big.NewRat(1, 4)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.fields[2].applyNull(base, rdb.Nullable{Value: want}); err != nil {
+		t.Fatal(err)
+	}
+	if row.Total != want || row.Fee.String() != "0.25" || row.Tax == nil || *row.Tax != want {
+		t.Fatalf("row=%v %v %v", row.Total, row.Fee, row.Tax)
+	}
+}
+
 func TestNullApply(t *testing.T) {
 	type Row struct {
 		Name string `db:"name"`
@@ -397,3 +435,47 @@ func TestStreamPropagatesQueryError(t *testing.T) {
 		t.Fatalf("err=%v want %v", got, want)
 	}
 }
+
+// code implements rdb.ColumnScanner.
+type code string
+
+func (c *code) ScanColumn(column *rdb.Column, value rdb.Nullable) error {
+	if value.Null {
+		*c = "none"
+		return nil
+	}
+	*c = code("c-" + value.Value.(string))
+	return nil
+}
+
+func TestPlanColumnScanner(t *testing.T) {
+	type Row struct {
+		Code code  `db:"code"`
+		Ptr  *code `db:"ptr"`
+	}
+	schema := []*rdb.Column{
+		{Name: "code", Index: 0, Nullable: true},
+		{Name: "ptr", Index: 1, Nullable: true},
+	}
+	plan, err := newStructPlan[Row](schema, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.fields[0].mode != modeDirect {
+		t.Fatalf("code mode=%v, want modeDirect", plan.fields[0].mode)
+	}
+	var row Row
+	base := unsafe.Pointer(&row)
+	if err := rdb.AssignValue(nil, rdb.Nullable{Null: true}, plan.fields[0].prep(base), nil); err != nil {
+		t.Fatal(err)
+	}
+	if row.Code != "none" {
+		t.Fatalf("code=%q", row.Code)
+	}
+	if err := plan.fields[1].applyNull(base, rdb.Nullable{Value: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if row.Ptr == nil || *row.Ptr != "c-x" {
+		t.Fatalf("ptr=%v", row.Ptr)
+	}
+}
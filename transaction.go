@@ -7,70 +7,315 @@ package rdb
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 )
 
-// Although nested transactions are unsupported, savepoints are supported.
+// Transaction runs queries on a single connection. Nested transactions
+// are emulated with savepoints, see Begin.
 // A transaction should end with either a Commit() or Rollback() call.
 type Transaction struct {
 	ctx  context.Context
 	cp   *ConnPool
 	conn DriverConn
 
-	done  bool
+	done  atomic.Bool
 	level IsolationLevel
+
+	// Set for a nested transaction.
+	parent    *Transaction
+	savepoint string
+
+	// Active nested transaction, if any.
+	child *Transaction
+
+	// Number of savepoints created for nested transactions.
+	// Only used on the outermost transaction.
+	nestCount int
+
+	beforeCommit []func(ctx context.Context) error
+	onCommit     []func()
+	onRollback   []func(err error)
+
+	// Set if leak detection is enabled.
+	leak *leakEntry
 }
 
 var errTransactionClosed = errors.New("transaction already closed")
 
+// ErrNestedActive is returned when a transaction is used, ended or nested
+// again while a nested transaction started from it is still active.
+// End the nested transaction first.
+var ErrNestedActive = errors.New("nested transaction still active")
+
+// Query runs cmd on the transaction connection.
+// Returns ErrNestedActive if a nested transaction is still active.
 func (tran *Transaction) Query(ctx context.Context, cmd *Command, params ...Param) (*Result, error) {
-	if tran.done {
+	tran.leak.lock()
+	defer tran.leak.unlock()
+	if tran.done.Load() {
 		return nil, errTransactionClosed
 	}
+	if tran.child != nil {
+		return nil, ErrNestedActive
+	}
 	return tran.cp.query(ctx, true, tran.conn, cmd, nil, params...)
 }
 
+// Begin starts a nested transaction backed by an automatically named savepoint.
+// Committing the nested transaction ends the scope and leaves the work to
+// the outer transaction. Rolling it back rolls back to the savepoint.
+// Only the innermost active transaction may be nested again.
+func (tran *Transaction) Begin() (*Transaction, error) {
+	tran.leak.lock()
+	defer tran.leak.unlock()
+	if tran.done.Load() {
+		return nil, errTransactionClosed
+	}
+	if tran.child != nil {
+		return nil, ErrNestedActive
+	}
+	root := tran
+	for root.parent != nil {
+		root = root.parent
+	}
+	root.nestCount++
+	name := fmt.Sprintf("rdb_nest_%d", root.nestCount)
+	err := tran.conn.SavePoint(tran.ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	nested := &Transaction{
+		ctx:       tran.ctx,
+		cp:        tran.cp,
+		conn:      tran.conn,
+		level:     tran.level,
+		parent:    tran,
+		savepoint: name,
+		leak:      tran.leak,
+	}
+	tran.child = nested
+	return nested, nil
+}
+
+// Nested returns true if the transaction was started from another transaction.
+func (tran *Transaction) Nested() bool {
+	return tran.parent != nil
+}
+
 // Commit commits a one or more queries. If no queries have been run this
 // just returns the connection without any action being taken.
+// A nested transaction is ended and its work is kept in the outer transaction.
+// Returns ErrNestedActive and does nothing if a nested transaction is still active.
 func (tran *Transaction) Commit() error {
-	if tran.done {
-		return errTransactionClosed
+	tran.leak.lock()
+	if err := tran.usable(); err != nil {
+		tran.leak.unlock()
+		return err
 	}
-	tran.done = true
+	if tran.parent != nil {
+		tran.done.Store(true)
+		p := tran.parent
+		p.child = nil
+		// Hooks run when the outermost transaction ends.
+		p.beforeCommit = append(p.beforeCommit, tran.beforeCommit...)
+		p.onCommit = append(p.onCommit, tran.onCommit...)
+		p.onRollback = append(p.onRollback, tran.onRollback...)
+		tran.leak.unlock()
+		return nil
+	}
+	tran.leak.unlock()
+
+	// Hooks may use the transaction, so they run without the leak entry locked.
+	for _, f := range tran.beforeCommit {
+		err := f(tran.ctx)
+		if err != nil {
+			tran.rollback(err)
+			return err
+		}
+	}
+	tran.leak.lock()
+	if err := tran.usable(); err != nil {
+		tran.leak.unlock()
+		return err
+	}
+	tran.done.Store(true)
 	err := tran.conn.Commit(tran.ctx)
 	tran.cp.releaseConn(tran.ctx, tran.conn, tran.conn.Status() != StatusReady)
-	return err
+	tran.leak.unlock()
+	if err != nil {
+		tran.runRollback(err)
+		return err
+	}
+	for _, f := range tran.onCommit {
+		f()
+	}
+	return nil
 }
 
 // Rollback rolls back one or more queries. If no queries have been run this
 // just returns the connection without any action being taken.
+// A nested transaction is rolled back to the savepoint it started at.
+// If a nested transaction is still active it is rolled back as well and
+// ErrNestedActive is returned after the transaction has ended.
 func (tran *Transaction) Rollback() error {
-	return tran.RollbackTo("")
+	tran.leak.lock()
+	if tran.done.Load() {
+		tran.leak.unlock()
+		return errTransactionClosed
+	}
+	nested := tran.child != nil
+	err := tran.end()
+	tran.leak.unlock()
+	tran.runRollback(nil)
+	if err == nil && nested {
+		err = ErrNestedActive
+	}
+	return err
 }
 
-// Rollback to an existing savepoint. Commit or Rollback should still
-// be called after calling RollbackTo.
-func (tran *Transaction) RollbackTo(savepoint string) error {
-	if tran.done {
+// usable returns an error if the transaction is done or a nested
+// transaction is active. Call with the leak entry locked.
+func (tran *Transaction) usable() error {
+	if tran.done.Load() {
 		return errTransactionClosed
 	}
+	if tran.child != nil {
+		return ErrNestedActive
+	}
+	return nil
+}
 
-	err := tran.conn.Rollback(savepoint)
-	if len(savepoint) == 0 {
-		tran.done = true
+// rollback ends the transaction and any nested transaction.
+// The cause is reported to the OnRollback hooks.
+func (tran *Transaction) rollback(cause error) error {
+	tran.leak.lock()
+	if tran.done.Load() {
+		tran.leak.unlock()
+		return errTransactionClosed
+	}
+	err := tran.end()
+	tran.leak.unlock()
+	tran.runRollback(cause)
+	return err
+}
+
+// end rolls back the transaction and any nested transaction.
+// Call with the leak entry locked.
+func (tran *Transaction) end() error {
+	tran.endChildren()
+
+	var err error
+	tran.done.Store(true)
+	if tran.parent != nil {
+		err = tran.conn.Rollback(tran.savepoint)
+		tran.parent.child = nil
+	} else {
+		err = tran.conn.Rollback("")
 		tran.cp.releaseConn(tran.ctx, tran.conn, tran.conn.Status() != StatusReady)
 	}
 	return err
 }
 
+// forceClose rolls back a leaked transaction and releases the connection
+// to the connection pool, the same as Rollback. The leak entry is locked by
+// the leak detector, so the OnRollback hooks are returned to run after.
+func (tran *Transaction) forceClose() func() {
+	if tran.done.Load() {
+		return nil
+	}
+	tran.end()
+	return func() {
+		tran.runRollback(ErrLeaked)
+	}
+}
+
+func (tran *Transaction) runRollback(cause error) {
+	for _, f := range tran.onRollback {
+		f(cause)
+	}
+}
+
+// BeforeCommit registers f to run before the outermost transaction is committed.
+// If f returns an error the transaction is rolled back and Commit returns
+// the error. Hooks run in registration order.
+func (tran *Transaction) BeforeCommit(f func(ctx context.Context) error) {
+	tran.beforeCommit = append(tran.beforeCommit, f)
+}
+
+// OnCommit registers f to run after the outermost transaction is
+// successfully committed. Hooks run in registration order.
+func (tran *Transaction) OnCommit(f func()) {
+	tran.onCommit = append(tran.onCommit, f)
+}
+
+// OnRollback registers f to run after the transaction is rolled back
+// or fails to commit. The error is the reason the commit failed, or nil
+// if Rollback was called. If registered on a nested transaction that
+// is committed, f runs if the outer transaction is rolled back.
+// Hooks run in registration order.
+func (tran *Transaction) OnRollback(f func(err error)) {
+	tran.onRollback = append(tran.onRollback, f)
+}
+
+// endChildren marks all nested transactions as done and
+// collects their rollback hooks.
+func (tran *Transaction) endChildren() {
+	for child := tran.child; child != nil; child = child.child {
+		child.done.Store(true)
+		tran.onRollback = append(tran.onRollback, child.onRollback...)
+	}
+	tran.child = nil
+}
+
+// Rollback to an existing savepoint. Commit or Rollback should still
+// be called after calling RollbackTo. An empty savepoint is the same as
+// calling Rollback.
+func (tran *Transaction) RollbackTo(savepoint string) error {
+	if len(savepoint) == 0 {
+		return tran.Rollback()
+	}
+	tran.leak.lock()
+	defer tran.leak.unlock()
+	if tran.done.Load() {
+		return errTransactionClosed
+	}
+	if tran.child != nil {
+		return ErrNestedActive
+	}
+	return tran.conn.Rollback(savepoint)
+}
+
 // Create a save point in the transaction.
 func (tran *Transaction) SavePoint(name string) error {
-	if tran.done {
+	tran.leak.lock()
+	defer tran.leak.unlock()
+	if tran.done.Load() {
 		return errTransactionClosed
 	}
+	if tran.child != nil {
+		return ErrNestedActive
+	}
 	return tran.conn.SavePoint(tran.ctx, name)
 }
 
 // Return true if the transaction has not been either commited or entirely rolled back.
 func (tran *Transaction) Active() bool {
-	return !tran.done
+	return !tran.done.Load()
+}
+
+// BeginScope starts a transaction scope on q. If q is a Transaction a nested
+// transaction is started, if q is a ConnPool or Cluster a new transaction is
+// started, on the primary for a Cluster as with Cluster.Begin.
+// This allows functions that accept a Queryer to group their own work.
+func BeginScope(ctx context.Context, q Queryer) (*Transaction, error) {
+	switch q := q.(type) {
+	case *Transaction:
+		return q.Begin()
+	case *ConnPool:
+		return q.Begin(ctx)
+	case *Cluster:
+		return q.Begin(ctx)
+	}
+	return nil, fmt.Errorf("cannot begin a transaction scope on %T", q)
 }
@@ -0,0 +1,229 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNestedTransaction(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_nested", d)
+	ctx := context.Background()
+
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := BeginScope(ctx, tran)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inner.Nested() {
+		t.Fatal("expected nested transaction")
+	}
+	if _, err := tran.Begin(); err != ErrNestedActive {
+		t.Fatalf("expected ErrNestedActive when nesting twice, got %v", err)
+	}
+	if err := tran.Commit(); err != ErrNestedActive {
+		t.Fatalf("expected ErrNestedActive on outer commit, got %v", err)
+	}
+	if err := inner.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	inner, err = tran.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tran.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"begin", "saverdb_nest_1", "rollbackrdb_nest_1", "saverdb_nest_2", "commit"}
+	if got := d.getCalls(); !equalCalls(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+}
+
+func TestNestedTransactionOuterRollback(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_nested_rollback", d)
+	ctx := context.Background()
+
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := tran.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The outer rollback reports the open nested transaction but still
+	// rolls back both and returns the connection.
+	if err := tran.Rollback(); err != ErrNestedActive {
+		t.Fatalf("expected ErrNestedActive on outer rollback, got %v", err)
+	}
+	if inner.Active() || tran.Active() {
+		t.Fatal("expected both transactions to be rolled back")
+	}
+	if s := pool.Stats(); s.InUse != 0 {
+		t.Fatalf("expected connection returned to pool, got %d in use", s.InUse)
+	}
+	if err := inner.Rollback(); err != errTransactionClosed {
+		t.Fatalf("expected closed inner transaction, got %v", err)
+	}
+	want := []string{"begin", "saverdb_nest_1", "rollback"}
+	if got := d.getCalls(); !equalCalls(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+}
+
+func TestNestedTransactionParentBlocked(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_nested_blocked", d)
+	ctx := context.Background()
+
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := tran.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tran.Query(ctx, &Command{SQL: "select 1", Arity: Zero}); err != ErrNestedActive {
+		t.Fatalf("expected ErrNestedActive on outer query, got %v", err)
+	}
+	if err := tran.SavePoint("sp"); err != ErrNestedActive {
+		t.Fatalf("expected ErrNestedActive on outer savepoint, got %v", err)
+	}
+	if err := tran.RollbackTo("sp"); err != ErrNestedActive {
+		t.Fatalf("expected ErrNestedActive on outer rollback to, got %v", err)
+	}
+	if err := inner.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	res, err := tran.Query(ctx, &Command{SQL: "select 1", Arity: Zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	if err := tran.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunInTxNestedActive(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_nested_leftopen", d)
+
+	err := RunInTx(context.Background(), pool, nil, func(tran *Transaction) error {
+		_, err := tran.Begin()
+		return err
+	})
+	if err != ErrNestedActive {
+		t.Fatalf("expected ErrNestedActive, got %v", err)
+	}
+	want := []string{"begin", "saverdb_nest_1", "rollback"}
+	if got := d.getCalls(); !equalCalls(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+}
+
+func TestTransactionHooks(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_hooks", d)
+	ctx := context.Background()
+
+	var order []string
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tran.OnCommit(func() { order = append(order, "commit1") })
+	tran.BeforeCommit(func(ctx context.Context) error {
+		order = append(order, "before")
+		return nil
+	})
+	inner, err := tran.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.OnCommit(func() { order = append(order, "commit2") })
+	if err := inner.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 0 {
+		t.Fatalf("hooks ran before the outer commit: %v", order)
+	}
+	if err := tran.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"before", "commit1", "commit2"}
+	if !equalCalls(order, want) {
+		t.Errorf("got hooks %v, want %v", order, want)
+	}
+}
+
+func TestTransactionBeforeCommitError(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_hooks_abort", d)
+	ctx := context.Background()
+
+	errAbort := errors.New("abort")
+	var committed bool
+	var rollbackErr error
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tran.BeforeCommit(func(ctx context.Context) error { return errAbort })
+	tran.OnCommit(func() { committed = true })
+	tran.OnRollback(func(err error) { rollbackErr = err })
+
+	if err := tran.Commit(); err != errAbort {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+	if committed {
+		t.Error("OnCommit ran after an aborted commit")
+	}
+	if rollbackErr != errAbort {
+		t.Errorf("expected OnRollback with abort error, got %v", rollbackErr)
+	}
+	if tran.Active() {
+		t.Error("expected transaction to be done")
+	}
+	want := []string{"begin", "rollback"}
+	if got := d.getCalls(); !equalCalls(got, want) {
+		t.Errorf("got calls %v, want %v", got, want)
+	}
+}
+
+func TestTransactionNestedRollbackHook(t *testing.T) {
+	d := &txDriver{}
+	pool := openTxPool(t, "tx_hooks_nested", d)
+	ctx := context.Background()
+
+	var rolled, committed int
+	tran, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := tran.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.OnRollback(func(err error) { rolled++ })
+	inner.OnCommit(func() { committed++ })
+	if err := inner.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tran.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if rolled != 1 || committed != 0 {
+		t.Errorf("got rolled=%d committed=%d, want 1 and 0", rolled, committed)
+	}
+}
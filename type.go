@@ -40,11 +40,18 @@ const (
 	Bool
 	Integer
 	Float
-	Decimal
+	Numeric // Named Decimal before the Decimal value type; see GenericDecimal.
 	Time
 	Other
 )
 
+// GenericDecimal is the generic decimal type. It was named Decimal until that
+// name was taken by the Decimal value type, which breaks code that used
+// rdb.Decimal as a Type.
+//
+// Deprecated: Use Numeric.
+const GenericDecimal = Numeric
+
 // Type constants are not represented in all database systems.
 // Additional sql types may be recognized per driver, but such types
 // must have a vlaue greater then TypeDriverThresh.
@@ -0,0 +1,106 @@
+// Copyright 2014 Daniel Theophanes.
+// Use of this source code is governed by a zlib-style
+// license that can be found in the LICENSE file.
+
+package rdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kardianos/rdb/internal/pools/timer"
+)
+
+// ValidateMode sets when a pooled connection is checked before it is handed out.
+type ValidateMode byte
+
+const (
+	ValidateNever  ValidateMode = iota // Never check connections on checkout.
+	ValidateAlways                     // Check every connection on checkout.
+	ValidateIdle                       // Check connections idle longer than Config.ValidateAfter.
+)
+
+func (vm ValidateMode) String() string {
+	switch vm {
+	default:
+		return "?"
+	case ValidateNever:
+		return "never"
+	case ValidateAlways:
+		return "always"
+	case ValidateIdle:
+		return "idle"
+	}
+}
+
+func parseValidateMode(s string) (ValidateMode, error) {
+	switch s {
+	case "never":
+		return ValidateNever, nil
+	case "always":
+		return ValidateAlways, nil
+	case "idle":
+		return ValidateIdle, nil
+	}
+	return ValidateNever, fmt.Errorf("unknown validate mode %q, must be never, always, or idle", s)
+}
+
+// DriverPinger may be implemented by a DriverConn to provide a cheaper
+// liveness check then running the Driver.PingCommand.
+type DriverPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// pingConn checks that an idle connection is still usable.
+func (cp *ConnPool) pingConn(ctx context.Context, conn DriverConn) error {
+	if p, ok := conn.(DriverPinger); ok {
+		return p.Ping(ctx)
+	}
+	cmd := cp.dr.PingCommand()
+	val := &valuer{cmd: cmd}
+	err := conn.Query(ctx, cmd, nil, nil, val)
+	if err == nil {
+		err = conn.NextQuery(ctx)
+	}
+	if err == nil && len(val.errorList) != 0 {
+		err = val.errorList
+	}
+	return err
+}
+
+// validateCheckout is called by the resource pool before an idle connection
+// is handed out.
+func (cp *ConnPool) validateCheckout(ctx context.Context, conn DriverConn, idle time.Duration) bool {
+	if cp.conf.Validate == ValidateIdle {
+		after := cp.conf.ValidateAfter
+		if after <= 0 {
+			after = time.Second
+		}
+		if idle < after {
+			return true
+		}
+	}
+	return cp.pingConn(ctx, conn) == nil
+}
+
+func (cp *ConnPool) startHealthCheck() {
+	interval := cp.conf.HealthCheckInterval
+	if interval <= 0 {
+		return
+	}
+	cp.health = timer.NewTimer(interval)
+	cp.health.Start(func(ctx context.Context) {
+		n := cp.pool.Keep(ctx, func(ctx context.Context, conn DriverConn) bool {
+			return cp.pingConn(ctx, conn) == nil
+		}, int(cp.minIdle.Load()))
+		cp.stats.healthInvalid.Add(int64(n))
+	}, interval)
+}
+
+func (cp *ConnPool) stopHealthCheck() {
+	if cp.health == nil {
+		return
+	}
+	cp.health.Stop()
+}
@@ -0,0 +1,151 @@
+package rdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pingDriver opens connections that can be marked broken.
+type pingDriver struct {
+	mu    sync.Mutex
+	conns []*pingConn
+}
+
+func (d *pingDriver) DriverInfo() *DriverInfo { return &DriverInfo{} }
+func (d *pingDriver) PingCommand() *Command   { return &Command{Arity: Zero} }
+func (d *pingDriver) Open(ctx context.Context, c *Config) (DriverConn, error) {
+	conn := &pingConn{dummyConn: dummyConn{opened: time.Now(), status: StatusReady}}
+	d.mu.Lock()
+	d.conns = append(d.conns, conn)
+	d.mu.Unlock()
+	return conn, nil
+}
+
+func (d *pingDriver) getConns() []*pingConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*pingConn(nil), d.conns...)
+}
+
+type pingConn struct {
+	dummyConn
+	broken atomic.Bool
+	pings  atomic.Int64
+}
+
+func (c *pingConn) Ping(ctx context.Context) error {
+	c.pings.Add(1)
+	if c.broken.Load() {
+		return errors.New("broken connection")
+	}
+	return nil
+}
+
+func TestValidateOnCheckout(t *testing.T) {
+	d := &pingDriver{}
+	Register("validate_checkout", d)
+	pool, err := Open(&Config{
+		DriverName:       "validate_checkout",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		Validate:         ValidateAlways,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		conn, err := pool.Connection(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	conns := d.getConns()
+	if len(conns) != 1 {
+		t.Fatalf("expected connection reuse, got %d connections", len(conns))
+	}
+	if conns[0].pings.Load() != 1 {
+		t.Errorf("expected 1 ping, got %d", conns[0].pings.Load())
+	}
+
+	conns[0].broken.Store(true)
+	conn, err := pool.Connection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if n := len(d.getConns()); n != 2 {
+		t.Errorf("expected broken connection to be replaced, got %d connections", n)
+	}
+	if s := pool.Stats(); s.Invalid != 1 {
+		t.Errorf("expected 1 invalid connection, got %d", s.Invalid)
+	}
+}
+
+func TestValidateIdle(t *testing.T) {
+	d := &pingDriver{}
+	Register("validate_idle", d)
+	pool, err := Open(&Config{
+		DriverName:       "validate_idle",
+		PoolInitCapacity: 1,
+		PoolMaxCapacity:  1,
+		Validate:         ValidateIdle,
+		ValidateAfter:    time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Connection(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if n := d.getConns()[0].pings.Load(); n != 0 {
+		t.Errorf("expected no pings for recently used connection, got %d", n)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	d := &pingDriver{}
+	Register("validate_health", d)
+	pool, err := Open(&Config{
+		DriverName:          "validate_health",
+		PoolInitCapacity:    2,
+		PoolMaxCapacity:     2,
+		HealthCheckInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	waitFor := func(what string, f func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for !f() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor("min idle connections", func() bool { return pool.Stats().Active == 2 })
+
+	d.getConns()[0].broken.Store(true)
+	waitFor("broken connection replaced", func() bool { return pool.Stats().Invalid >= 1 && len(d.getConns()) == 3 })
+	if s := pool.Stats(); s.Active != 2 {
+		t.Errorf("expected 2 active connections, got %d", s.Active)
+	}
+}
@@ -40,6 +40,16 @@ type valuer struct {
 
 	convert []ColumnConverter
 
+	// stream is the open stream of the column at streamAt while the row
+	// is paused. streamed marks columns of the row that were streamed.
+	stream   io.Reader
+	streamAt int
+	streamed []bool
+
+	// readers are the *io.Reader Prep destinations of the row that were
+	// assigned a value read from the row instead of a stream.
+	readers []*io.Reader
+
 	rowCount     uint64
 	rowsAffected uint64
 }
@@ -51,6 +61,12 @@ func (v *valuer) clearBuffer() {
 		}
 
 	}
+	for i := range v.streamed {
+		v.streamed[i] = false
+	}
+	for i := range v.readers {
+		v.readers[i] = nil
+	}
 }
 func (v *valuer) clearPrep() {
 	for i := range v.prep {
@@ -87,6 +103,9 @@ func (v *valuer) Columns(cc []*Column) error {
 	}
 	v.buffer = make([]Nullable, len(cc))
 	v.prep = make([]interface{}, len(cc))
+	v.streamed = make([]bool, len(cc))
+	v.readers = make([]*io.Reader, len(cc))
+	v.stream = nil
 
 	// Prepare fields.
 	v.fields = make([]*Field, len(cc))
@@ -176,6 +195,22 @@ func (v *valuer) WriteField(c *Column, value *DriverValue, assign Assigner) erro
 
 	prep := v.prep[c.Index]
 
+	if value.Stream {
+		out, ok := prep.(*io.Reader)
+		if !ok {
+			return fmt.Errorf("column %q streamed without a *io.Reader destination", c.Name)
+		}
+		rd, _ := value.Value.(io.Reader)
+		*out = rd
+		v.stream = rd
+		v.streamAt = c.Index
+		v.streamed[c.Index] = true
+		return nil
+	}
+	if out, ok := prep.(*io.Reader); ok {
+		v.readers[c.Index] = out
+	}
+
 	// Fast path: assign buffer views straight into Prep without an intermediate copy.
 	// Skip when a converter or custom assigner needs the boxed DriverValue.
 	if value.MustCopy && prep != nil && convert == nil && assign == nil && !value.Chunked {
@@ -257,6 +292,9 @@ func AssignValue(c *Column, outValue Nullable, prep interface{}, assign Assigner
 		*nullable = outValue
 		return nil
 	}
+	if handled, err := assignCustom(c, outValue, prep); handled {
+		return err
+	}
 
 	// NULL: Opt[T], NullFlagPrep, and *Nullable accept it without ErrScanNull.
 	if outValue.Null || outValue.Value == nil {
@@ -522,13 +560,24 @@ func AssignValue(c *Column, outValue Nullable, prep interface{}, assign Assigner
 		default:
 			return errorTypeNotSupported(in, out, c)
 		}
+	case Decimal:
+		// Supported destinations are handled by DirectAssignDecimal.
+		return errorTypeNotSupported(in, prep, c)
 	default:
 		return errorTypeNotSupported(nil, nil, c)
 	}
 	return err
 }
 
+// errorTypeNotSupported reports a value that cannot be assigned to the prep.
+// The column is nil when the value was assigned directly.
 func errorTypeNotSupported(in, out interface{}, c *Column) error {
+	if c == nil {
+		if out == nil && in == nil {
+			return fmt.Errorf("Unsupported column type")
+		}
+		return fmt.Errorf("Prep type (%T) cannot fit data type (%T)", out, in)
+	}
 	if out == nil && in == nil {
 		return fmt.Errorf("Unsupported column type: %s", c.Name)
 	}